@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
 	"os"
@@ -11,13 +11,19 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/DisasterWoman/wallet-service/internal/cache"
 	"github.com/DisasterWoman/wallet-service/internal/config"
+	"github.com/DisasterWoman/wallet-service/internal/database"
 	"github.com/DisasterWoman/wallet-service/internal/handler"
+	"github.com/DisasterWoman/wallet-service/internal/health"
+	"github.com/DisasterWoman/wallet-service/internal/migrate"
+	"github.com/DisasterWoman/wallet-service/internal/ratelimit"
 	"github.com/DisasterWoman/wallet-service/internal/repository"
 	"github.com/DisasterWoman/wallet-service/internal/service"
 	_ "github.com/DisasterWoman/wallet-service/docs" 
+	"github.com/DisasterWoman/wallet-service/migrations"
 	"github.com/gorilla/mux"
-	_"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
@@ -33,18 +39,10 @@ import (
 
 // @host localhost:8080
 
-// healthHandler обрабатывает запросы проверки здоровья
-// @Summary Проверка здоровья сервиса
-// @Description Возвращает статус работы сервиса
-// @Tags health
-// @Produce json
-// @Success 200 {object} map[string]string "Сервис работает"
-// @Router /health [get]
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, `{"status": "ok", "message": "Wallet service is running"}`)
-}
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+// @description Токен администратора: "Bearer <ADMIN_TOKEN>"
 
 func main() {
 	cfg, err := config.Load()
@@ -52,41 +50,197 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	db, err := sql.Open("postgres", cfg.GetDBConnectionString())
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		db, migrator := openPostgres(cfg)
+		defer db.Close()
+		if err := migrator.RunCommand(context.Background(), os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
 	}
-	defer db.Close()
 
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(25)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	checker := health.NewChecker(cfg.HealthCheckTimeout)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	var repo repository.Repository
+	var limiterDB *sql.DB
+	switch cfg.StorageDriver {
+	case config.StorageMemory:
+		log.Println("Using in-memory storage: wallets are lost on restart")
+		repo = repository.NewMemoryRepository()
+	default:
+		db, migrator := openPostgres(cfg)
+		defer db.Close()
+
+		if cfg.AutoMigrate {
+			applied, err := migrator.Up(context.Background())
+			if err != nil {
+				log.Fatalf("Failed to apply migrations: %v", err)
+			}
+			log.Printf("Schema is up to date (applied %d migration(s), version %d)", applied, migrator.Latest())
+		}
+
+		checker.Register("migrations", health.MigrationCheck(migrator))
+		limiterDB = db
+
+		if cfg.CheckpointInterval > 0 {
+			log.Printf("Balance checkpoints: interval=%s, delay=%s", cfg.CheckpointInterval, cfg.CheckpointDelay)
+			checkpointCtx, stopCheckpoints := context.WithCancel(context.Background())
+			defer stopCheckpoints()
+			go repository.RunCheckpoints(checkpointCtx, db, cfg.CheckpointInterval, cfg.CheckpointDelay)
+		}
+
+		if cfg.ReconcileInterval > 0 {
+			log.Printf("Ledger reconciliation: interval=%s", cfg.ReconcileInterval)
+			reconcileCtx, stopReconciliation := context.WithCancel(context.Background())
+			defer stopReconciliation()
+			go repository.RunReconciliation(reconcileCtx, db, cfg.ReconcileInterval)
+		}
+
+		if cfg.DBDriver == config.DriverPgx {
+			pool, err := database.OpenPgx(context.Background(), cfg, repository.PreparePgxStatements)
+			if err != nil {
+				log.Fatalf("Failed to open pgx pool: %v", err)
+			}
+			defer pool.Close()
+
+			log.Printf("Using pgx driver: max conns=%d", cfg.DBMaxOpenConns)
+			checker.Register("database", health.PgxDatabaseCheck(pool))
+			checker.Register("pool", health.PgxPoolCheck(pool, cfg.PoolSaturationPercent))
+			repo = repository.NewPgxRepository(
+				pool,
+				repository.WithPgxIsolation(cfg.GetTxIsolation()),
+				repository.WithPgxRetryPolicy(repository.RetryPolicy{
+					MaxAttempts: cfg.TxMaxAttempts,
+					BaseDelay:   cfg.TxRetryBaseDelay,
+					MaxDelay:    cfg.TxRetryMaxDelay,
+				}),
+				repository.WithPgxTimeouts(repository.Timeouts{
+					Lock:      cfg.DBLockTimeout,
+					Statement: cfg.DBStatementTimeout,
+				}),
+			)
+			break
+		}
 
-	if err = db.PingContext(ctx); err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
+		checker.Register("database", health.DatabaseCheck(db))
+		checker.Register("pool", health.PoolCheck(db, cfg.PoolSaturationPercent))
+		opts := []repository.Option{
+			repository.WithIsolation(cfg.GetTxIsolation()),
+			repository.WithRetryPolicy(repository.RetryPolicy{
+				MaxAttempts: cfg.TxMaxAttempts,
+				BaseDelay:   cfg.TxRetryBaseDelay,
+				MaxDelay:    cfg.TxRetryMaxDelay,
+			}),
+			repository.WithTimeouts(repository.Timeouts{
+				Lock:      cfg.DBLockTimeout,
+				Statement: cfg.DBStatementTimeout,
+			}),
+		}
+		if cfg.WalletSharding {
+			opts = append(opts, repository.WithSharding())
+		}
+		if cfg.UpdateStrategy == config.UpdateConditional {
+			opts = append(opts, repository.WithConditionalUpdate())
+		}
+		replicas := openReplicas(cfg)
+		for _, replica := range replicas {
+			defer replica.Close()
+		}
+		if len(replicas) > 0 {
+			opts = append(opts, repository.WithReplicas(cfg.ReplicaMaxLag, replicas...))
+		}
+		postgres := repository.NewPostgresRepository(db, opts...)
+		if len(replicas) > 0 {
+			monitorCtx, stopMonitor := context.WithCancel(context.Background())
+			defer stopMonitor()
+			go postgres.MonitorReplicas(monitorCtx, cfg.ReplicaCheckInterval)
+		}
+		repo = postgres
 	}
 
-	log.Printf("Successfully connected to database: %s", cfg.DBName)
+	// Административные операции идут в хранилище напрямую, мимо кэша и
+	// пакетной записи.
+	admin, _ := repo.(repository.WalletAdmin)
+	bulkRepo, _ := repo.(repository.WalletBulk)
+
+	if cfg.DBMaxInFlight > 0 || cfg.DBMaxStatementStreams > 0 {
+		log.Printf("Limiting storage operations: max in flight=%d, max statement streams=%d, max wait=%s",
+			cfg.DBMaxInFlight, cfg.DBMaxStatementStreams, cfg.DBInFlightWait)
+		repo = repository.NewLimitingRepository(repo, cfg.DBMaxInFlight, cfg.DBMaxStatementStreams, cfg.DBInFlightWait)
+	}
+
+	if cfg.BatchEnabled {
+		applier, ok := repo.(repository.BatchApplier)
+		if !ok {
+			log.Fatalf("Storage driver %q does not support batching", cfg.StorageDriver)
+		}
+		log.Printf("Batching wallet updates: window=%s, max size=%d", cfg.BatchWindow, cfg.BatchMaxSize)
+		repo = repository.NewBatchingRepository(repo, applier, cfg.BatchWindow, cfg.BatchMaxSize)
+	}
+
+	if cfg.CacheEnabled {
+		log.Printf("Caching wallet reads: size=%d, max staleness=%s", cfg.CacheSize, cfg.CacheMaxStaleness)
+		caching := repository.NewCachingRepository(repo, cache.NewLRU(cfg.CacheSize, cfg.CacheMaxStaleness))
+		if cfg.StorageDriver == config.StoragePostgres {
+			listenCtx, stopListening := context.WithCancel(context.Background())
+			defer stopListening()
+			go func() {
+				err := repository.ListenInvalidations(listenCtx, cfg.GetDBConnectionString(), caching)
+				if err != nil && !errors.Is(err, context.Canceled) {
+					log.Printf("Cache invalidation listener stopped: %v", err)
+				}
+			}()
+		}
+		repo = caching
+	}
 
-	repo := repository.NewPostgresRepository(db)
 	walletService := service.NewWalletService(repo)
 	walletHandler := handler.NewWalletHandler(walletService)
+	healthHandler := handler.NewHealthHandler(checker)
 
 	r := mux.NewRouter()
 	
-	r.HandleFunc("/health", healthHandler).Methods(http.MethodGet)                           
-	r.HandleFunc("/api/v1/wallet", walletHandler.UpdateWalletBalance).Methods(http.MethodPost)  
-	r.HandleFunc("/api/v1/wallets/{walletId}", walletHandler.GetWalletBalance).Methods(http.MethodGet)
+	r.HandleFunc("/health", healthHandler.Liveness).Methods(http.MethodGet)
+	r.HandleFunc("/livez", healthHandler.Liveness).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", healthHandler.Readiness).Methods(http.MethodGet)
+	r.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
+
+	api := r.PathPrefix("/api/v1").Subrouter()
+	writeTimeout, readTimeout := handler.Timeout(cfg.RequestWriteTimeout), handler.Timeout(cfg.RequestReadTimeout)
+	api.Handle("/wallet", writeTimeout(http.HandlerFunc(walletHandler.UpdateWalletBalance))).Methods(http.MethodPost).Name(handler.OperationRoute)
+	api.Handle("/wallets/{walletId}", readTimeout(http.HandlerFunc(walletHandler.GetWalletBalance))).Methods(http.MethodGet)
+	api.Handle("/wallets/{walletId}/balance", readTimeout(http.HandlerFunc(walletHandler.GetWalletBalanceAsOf))).Methods(http.MethodGet)
+	api.Handle("/wallets/{walletId}/statement", handler.Timeout(cfg.StatementTimeout)(http.HandlerFunc(walletHandler.GetStatement))).Methods(http.MethodGet)
+	api.Handle("/owners/{ownerId}/wallets", readTimeout(http.HandlerFunc(walletHandler.GetOwnerWallets))).Methods(http.MethodGet)
+	if cfg.AdminToken != "" && admin != nil {
+		adminHandler := handler.NewAdminHandler(service.NewAdminService(admin))
+		adminAPI := api.PathPrefix("/admin").Subrouter()
+		adminAPI.Use(handler.AdminAuth(cfg.AdminToken))
+		adminAPI.Handle("/wallets", readTimeout(http.HandlerFunc(adminHandler.SearchWallets))).Methods(http.MethodGet)
+		adminAPI.Handle("/wallets/{walletId}/metadata", writeTimeout(http.HandlerFunc(adminHandler.SetWalletMetadata))).Methods(http.MethodPut)
+		if bulkRepo != nil {
+			bulkHandler := handler.NewBulkHandler(service.NewBulkService(bulkRepo), cfg.BulkMaxBytes)
+			bulkTimeout := handler.Timeout(cfg.BulkTimeout)
+			adminAPI.Handle("/wallets/import", bulkTimeout(http.HandlerFunc(bulkHandler.ImportWallets))).Methods(http.MethodPost)
+			adminAPI.Handle("/wallets/export", bulkTimeout(http.HandlerFunc(bulkHandler.ExportWallets))).Methods(http.MethodGet)
+		}
+	}
+	if cfg.RateLimitEnabled {
+		limitCtx, stopLimits := context.WithCancel(context.Background())
+		defer stopLimits()
+		api.Use(handler.RateLimit(rateLimitScopes(limitCtx, cfg, limiterDB)...))
+	}
 	
 	// Swagger documentation
 	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 
 	server := &http.Server{
-		Addr:    cfg.GetServerAddress(),
-		Handler: r,
+		Addr:              cfg.GetServerAddress(),
+		Handler:           r,
+		ReadHeaderTimeout: cfg.ServerReadHeaderTimeout,
+		ReadTimeout:       cfg.ServerReadTimeout,
+		WriteTimeout:      cfg.ServerWriteTimeout,
+		IdleTimeout:       cfg.ServerIdleTimeout,
 	}
 
 	go func() {
@@ -101,9 +255,13 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
+	log.Printf("Draining: readiness reports not ready for %s", cfg.ShutdownDrainDelay)
+	checker.SetDraining()
+	time.Sleep(cfg.ShutdownDrainDelay)
+
 	log.Println("Shutting down server...")
 
-	ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
@@ -111,4 +269,71 @@ func main() {
 	}
 
 	log.Println("Server stopped")
-}
\ No newline at end of file
+}
+
+func openPostgres(cfg *config.Config) (*sql.DB, *migrate.Migrator) {
+	db, err := database.Open(context.Background(), cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	log.Printf("Successfully connected to database: %s", cfg.DBName)
+
+	migrator, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+	return db, migrator
+}
+
+// rateLimitScopes собирает включённые области ограничения частоты. Область
+// с нулевой частотой пропускается. Для хранилища postgres запускается
+// периодическая очистка наполнившихся вёдер.
+func rateLimitScopes(ctx context.Context, cfg *config.Config, db *sql.DB) []handler.RateLimitScope {
+	newLimiter := func(scope string, rate ratelimit.Rate) ratelimit.Limiter {
+		if cfg.RateLimitBackend == config.RateLimitPostgres {
+			limiter := ratelimit.NewPostgresLimiter(db, scope, rate)
+			go limiter.CleanupLoop(ctx, time.Minute)
+			return limiter
+		}
+		return ratelimit.NewMemoryLimiter(rate)
+	}
+
+	candidates := []struct {
+		name string
+		rate ratelimit.Rate
+		key  func(*http.Request) string
+	}{
+		{"api_key", ratelimit.Rate{Rate: cfg.RateLimitAPIKeyRPS, Burst: float64(cfg.RateLimitAPIKeyBurst)}, handler.APIKey},
+		{"ip", ratelimit.Rate{Rate: cfg.RateLimitIPRPS, Burst: float64(cfg.RateLimitIPBurst)}, handler.ClientIP(cfg.RateLimitTrustProxy)},
+		{"wallet", ratelimit.Rate{Rate: cfg.RateLimitWalletRPS, Burst: float64(cfg.RateLimitWalletBurst)}, handler.WalletKey},
+	}
+
+	var scopes []handler.RateLimitScope
+	for _, c := range candidates {
+		if !c.rate.Enabled() {
+			continue
+		}
+		log.Printf("Rate limiting by %s: %g req/s, burst %g (%s)", c.name, c.rate.Rate, c.rate.Burst, cfg.RateLimitBackend)
+		scopes = append(scopes, handler.RateLimitScope{Name: c.name, Limiter: newLimiter(c.name, c.rate), Key: c.key})
+	}
+	return scopes
+}
+
+// openReplicas создаёт пулы реплик из DB_REPLICA_DSNS. Доступность реплик
+// не проверяется: пока MonitorReplicas не измерит отставание, чтения идут на
+// основную базу.
+func openReplicas(cfg *config.Config) []*sql.DB {
+	var replicas []*sql.DB
+	for i, dsn := range cfg.ReplicaDSNs {
+		replica, err := database.NewPool(cfg, dsn)
+		if err != nil {
+			log.Fatalf("Invalid read replica %d: %v", i, err)
+		}
+		replicas = append(replicas, replica)
+	}
+	if len(replicas) > 0 {
+		log.Printf("Routing reads to %d replica(s), max lag %s", len(replicas), cfg.ReplicaMaxLag)
+	}
+	return replicas
+}
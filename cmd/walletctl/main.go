@@ -0,0 +1,673 @@
+// Command walletctl — административная утилита для операций с кошельками.
+//
+// Работает через тот же сервисный слой, что и HTTP API, поэтому все проверки
+// (недостаточно средств, заморозка, блокировки строк) соблюдаются так же,
+// как при обычных запросах.
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/bulk"
+	"github.com/DisasterWoman/wallet-service/internal/config"
+	"github.com/DisasterWoman/wallet-service/internal/database"
+	"github.com/DisasterWoman/wallet-service/internal/migrate"
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/DisasterWoman/wallet-service/internal/repository"
+	"github.com/DisasterWoman/wallet-service/internal/service"
+	"github.com/DisasterWoman/wallet-service/internal/statement"
+	"github.com/DisasterWoman/wallet-service/migrations"
+	"github.com/google/uuid"
+)
+
+const usage = `Usage: walletctl [-o table|json] <command> [arguments]
+
+Commands:
+  create-owner [-id UUID] [-name NAME]
+                                    create a wallet owner
+  create-wallet -owner UUID [-id UUID] [-currency CODE]
+                                    create a wallet with zero balance (RUB by default)
+  owner-wallets <ownerId>           list owner wallets and totals per currency
+  balance [-at TIME] <walletId>     show wallet balance and status, or the balance at TIME (RFC 3339)
+  deposit <walletId> <amount>       deposit amount to the wallet
+  withdraw <walletId> <amount>      withdraw amount from the wallet
+  transfer <fromId> <toId> <amount> transfer amount between wallets
+                                    (amounts are integers in minor units of the wallet currency)
+  freeze <walletId>                 block all operations on the wallet
+  unfreeze <walletId>               allow operations on the wallet again
+  history [-limit N] <walletId>     show latest wallet operations
+  statement -from TIME [-to TIME] [-format csv|jsonl] <walletId>
+                                    write the wallet statement for the period to stdout
+  shard <walletId> <N>              split wallet balance across N shard rows (0 merges them back)
+  limit <walletId> <maxBalance>     set the wallet balance ceiling (at most 10^18)
+  search [QUERY]                    find wallets, e.g. 'label=team:payments&status=ACTIVE&minBalance=100'
+                                    (label, status, minBalance, maxBalance, createdFrom, createdTo, limit, cursor)
+  checkpoint [-at TIME]             write balance checkpoints as of TIME, at least CHECKPOINT_DELAY ago
+                                    (by default CHECKPOINT_DELAY ago, aligned to CHECKPOINT_INTERVAL)
+  reconcile                         check wallet balances against the journal and transfers against each other;
+                                    exits with an error if discrepancies are found
+  import [-format csv|jsonl] [-dry-run] [-legacy-owner UUID] FILE
+                                    load wallets with opening balances from FILE ("-" for stdin) and print the reconciliation report;
+                                    records with an empty owner ID get the legacy owner
+  export [-format csv|jsonl] [FILE] write all wallets to FILE (stdout by default) and print totals per currency
+  migrate [up|down [N]|version]     manage database schema
+`
+
+var errUsage = errors.New("invalid usage")
+
+type app struct {
+	service  service.WalletService
+	repo     *repository.PostgresRepository
+	db       *sql.DB
+	migrator *migrate.Migrator
+	out      *printer
+
+	// checkpointInterval и checkpointDelay выравнивают и ограничивают
+	// момент контрольной точки так же, как у сервиса.
+	checkpointInterval time.Duration
+	checkpointDelay    time.Duration
+}
+
+func main() {
+	log.SetFlags(0)
+
+	flags := flag.NewFlagSet("walletctl", flag.ExitOnError)
+	output := flags.String("o", "table", "output format: table or json")
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	out, err := newPrinter(os.Stdout, *output)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	ctx := context.Background()
+	db, err := database.Open(ctx, cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	opts := []repository.Option{
+		repository.WithIsolation(cfg.GetTxIsolation()),
+		repository.WithRetryPolicy(repository.RetryPolicy{
+			MaxAttempts: cfg.TxMaxAttempts,
+			BaseDelay:   cfg.TxRetryBaseDelay,
+			MaxDelay:    cfg.TxRetryMaxDelay,
+		}),
+	}
+	if cfg.WalletSharding {
+		opts = append(opts, repository.WithSharding())
+	}
+	if cfg.UpdateStrategy == config.UpdateConditional {
+		opts = append(opts, repository.WithConditionalUpdate())
+	}
+	repo := repository.NewPostgresRepository(db, opts...)
+	a := &app{
+		service:  service.NewWalletService(repo),
+		repo:     repo,
+		db:       db,
+		migrator: migrator,
+		out:      out,
+
+		checkpointInterval: cfg.CheckpointInterval,
+		checkpointDelay:    cfg.CheckpointDelay,
+	}
+
+	if err := a.run(ctx, flags.Arg(0), flags.Args()[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		db.Close()
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+func (a *app) run(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "create-owner":
+		return a.createOwner(ctx, args)
+	case "create-wallet":
+		return a.createWallet(ctx, args)
+	case "owner-wallets":
+		return a.ownerWallets(ctx, args)
+	case "balance":
+		return a.balance(ctx, args)
+	case "deposit":
+		return a.updateBalance(ctx, models.Deposit, args)
+	case "withdraw":
+		return a.updateBalance(ctx, models.Withdraw, args)
+	case "transfer":
+		return a.transfer(ctx, args)
+	case "freeze":
+		return a.setFrozen(ctx, args, true)
+	case "unfreeze":
+		return a.setFrozen(ctx, args, false)
+	case "history":
+		return a.history(ctx, args)
+	case "statement":
+		return a.statement(ctx, args)
+	case "shard":
+		return a.shard(ctx, args)
+	case "limit":
+		return a.limit(ctx, args)
+	case "checkpoint":
+		return a.checkpoint(ctx, args)
+	case "reconcile":
+		return a.reconcile(ctx, args)
+	case "search":
+		return a.search(ctx, args)
+	case "import":
+		return a.importWallets(ctx, args)
+	case "export":
+		return a.exportWallets(ctx, args)
+	case "migrate":
+		return a.migrator.RunCommand(ctx, args, a.out.w)
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, command)
+	}
+}
+
+func (a *app) createOwner(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("create-owner", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	id := flags.String("id", "", "owner UUID, generated when empty")
+	name := flags.String("name", "", "owner display name")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	ownerID := uuid.Nil
+	if *id != "" {
+		parsed, err := uuid.Parse(*id)
+		if err != nil {
+			return fmt.Errorf("invalid owner ID: %w", err)
+		}
+		ownerID = parsed
+	}
+
+	owner, err := a.service.CreateOwner(ctx, ownerID, *name)
+	if err != nil {
+		return err
+	}
+	return a.out.owner(owner)
+}
+
+func (a *app) createWallet(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("create-wallet", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	id := flags.String("id", "", "wallet UUID, generated when empty")
+	owner := flags.String("owner", "", "owner UUID")
+	currency := flags.String("currency", string(models.DefaultCurrency), "ISO 4217 currency code")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	walletID := uuid.New()
+	if *id != "" {
+		parsed, err := uuid.Parse(*id)
+		if err != nil {
+			return fmt.Errorf("invalid wallet ID: %w", err)
+		}
+		walletID = parsed
+	}
+
+	if *owner == "" {
+		return errUsage
+	}
+	ownerID, err := uuid.Parse(*owner)
+	if err != nil {
+		return fmt.Errorf("invalid owner ID: %w", err)
+	}
+	if ownerID == uuid.Nil {
+		return models.ErrOwnerIDRequired
+	}
+
+	// Кошелёк создаётся сразу в нужной валюте одной вставкой: отдельный
+	// SetCurrency после CreateWallet при ошибке оставил бы кошелёк в RUB.
+	wallet, err := a.repo.CreateWalletInCurrency(ctx, walletID, ownerID, models.Currency(*currency))
+	if err != nil {
+		return err
+	}
+	return a.out.wallet(wallet)
+}
+
+func (a *app) ownerWallets(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	ownerID, err := uuid.Parse(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid owner ID: %w", err)
+	}
+
+	wallets, err := a.service.OwnerWallets(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+	return a.out.ownerWallets(wallets)
+}
+
+func (a *app) balance(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("balance", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	at := flags.String("at", "", "show the balance at this moment (RFC 3339)")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return errUsage
+	}
+	walletID, err := parseWalletID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *at != "" {
+		asOf, err := time.Parse(time.RFC3339Nano, *at)
+		if err != nil {
+			return fmt.Errorf("invalid time %q", *at)
+		}
+		balance, err := a.service.BalanceAsOf(ctx, walletID, asOf)
+		if err != nil {
+			return err
+		}
+		return a.out.balance(balance)
+	}
+
+	wallet, err := a.service.GetWallet(ctx, walletID)
+	if err != nil {
+		return err
+	}
+	return a.out.wallet(wallet)
+}
+
+func (a *app) updateBalance(ctx context.Context, operationType models.OperationType, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	walletID, err := parseWalletID(args[0])
+	if err != nil {
+		return err
+	}
+	amount, err := parseAmount(args[1])
+	if err != nil {
+		return err
+	}
+
+	req := &models.OperationRequest{
+		WalletID:      walletID,
+		OperationType: operationType,
+		Amount:        amount,
+	}
+	if _, err := a.service.UpdateBalance(ctx, req); err != nil {
+		return err
+	}
+
+	wallet, err := a.service.GetWallet(ctx, walletID)
+	if err != nil {
+		return err
+	}
+	return a.out.wallet(wallet)
+}
+
+func (a *app) transfer(ctx context.Context, args []string) error {
+	if len(args) != 3 {
+		return errUsage
+	}
+	fromID, err := parseWalletID(args[0])
+	if err != nil {
+		return err
+	}
+	toID, err := parseWalletID(args[1])
+	if err != nil {
+		return err
+	}
+	amount, err := parseAmount(args[2])
+	if err != nil {
+		return err
+	}
+
+	transferID, err := a.service.Transfer(ctx, &models.TransferRequest{
+		FromWalletID: fromID,
+		ToWalletID:   toID,
+		Amount:       amount,
+	})
+	if err != nil {
+		return err
+	}
+	return a.out.transfer(transferID, fromID, toID, amount)
+}
+
+func (a *app) setFrozen(ctx context.Context, args []string, frozen bool) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	walletID, err := parseWalletID(args[0])
+	if err != nil {
+		return err
+	}
+
+	if frozen {
+		err = a.service.Freeze(ctx, walletID)
+	} else {
+		err = a.service.Unfreeze(ctx, walletID)
+	}
+	if err != nil {
+		return err
+	}
+
+	wallet, err := a.service.GetWallet(ctx, walletID)
+	if err != nil {
+		return err
+	}
+	return a.out.wallet(wallet)
+}
+
+func (a *app) history(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	limit := flags.Int("limit", service.DefaultHistoryLimit, "number of operations to show")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return errUsage
+	}
+	walletID, err := parseWalletID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	operations, err := a.service.History(ctx, walletID, *limit)
+	if err != nil {
+		return err
+	}
+	return a.out.operations(operations)
+}
+
+// statement пишет выписку прямо в stdout, минуя printer: у неё свои
+// форматы, и флаг -o на неё не влияет.
+func (a *app) statement(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("statement", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	query := url.Values{}
+	for _, name := range []string{"from", "to", "format"} {
+		flags.Func(name, "", func(value string) error {
+			query.Set(name, value)
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return errUsage
+	}
+	walletID, err := parseWalletID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	req, err := models.ParseStatementQuery(walletID, query)
+	if err != nil {
+		return err
+	}
+	out := bufio.NewWriter(a.out.w)
+	writer, err := statement.NewWriter(out, &req)
+	if err != nil {
+		return err
+	}
+	if err := a.service.Statement(ctx, &req, writer); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+func (a *app) shard(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	walletID, err := parseWalletID(args[0])
+	if err != nil {
+		return err
+	}
+	shards, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid shard count %q", args[1])
+	}
+
+	if err := a.repo.SetShardCount(ctx, walletID, shards); err != nil {
+		return err
+	}
+
+	wallet, err := a.service.GetWallet(ctx, walletID)
+	if err != nil {
+		return err
+	}
+	return a.out.wallet(wallet)
+}
+
+func (a *app) limit(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	walletID, err := parseWalletID(args[0])
+	if err != nil {
+		return err
+	}
+	limit, err := parseAmount(args[1])
+	if err != nil {
+		return err
+	}
+
+	if err := a.repo.SetMaxBalance(ctx, walletID, limit); err != nil {
+		return err
+	}
+
+	wallet, err := a.service.GetWallet(ctx, walletID)
+	if err != nil {
+		return err
+	}
+	return a.out.wallet(wallet)
+}
+
+func (a *app) checkpoint(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("checkpoint", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	at := flags.String("at", "", "checkpoint moment (RFC 3339)")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	// Точка позже now - CHECKPOINT_DELAY могла бы пропустить операции ещё
+	// не зафиксированных транзакций.
+	latest := time.Now().Add(-a.checkpointDelay)
+	asOf := latest
+	if a.checkpointInterval > 0 {
+		asOf = asOf.Truncate(a.checkpointInterval)
+	}
+	if *at != "" {
+		var err error
+		if asOf, err = time.Parse(time.RFC3339Nano, *at); err != nil {
+			return fmt.Errorf("invalid time %q", *at)
+		}
+		if asOf.After(latest) {
+			return fmt.Errorf("checkpoint time %s is later than CHECKPOINT_DELAY (%s) ago", *at, a.checkpointDelay)
+		}
+	}
+
+	created, err := repository.CreateCheckpoints(ctx, a.db, asOf)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out.w, "created %d checkpoint(s) as of %s\n", created, asOf.UTC().Format(time.RFC3339))
+	return nil
+}
+
+// reconcile печатает отчёт сверки и завершается ошибкой, если найдены
+// расхождения, чтобы её можно было запускать из cron и CI.
+func (a *app) reconcile(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	report, err := repository.Reconcile(ctx, a.db)
+	if err != nil {
+		return err
+	}
+	if err := a.out.reconciliation(report); err != nil {
+		return err
+	}
+	if total := report.Total(); total > 0 {
+		return fmt.Errorf("%d discrepancies found, see reconciliation run %d", total, report.ID)
+	}
+	return nil
+}
+
+// search принимает фильтр в том же виде, что и GET /api/v1/admin/wallets.
+func (a *app) search(ctx context.Context, args []string) error {
+	if len(args) > 1 {
+		return errUsage
+	}
+	query := url.Values{}
+	if len(args) == 1 {
+		var err error
+		if query, err = url.ParseQuery(args[0]); err != nil {
+			return fmt.Errorf("invalid search query: %w", err)
+		}
+	}
+	filter, err := models.ParseWalletFilter(query)
+	if err != nil {
+		return err
+	}
+
+	page, err := service.NewAdminService(a.repo).SearchWallets(ctx, filter)
+	if err != nil {
+		return err
+	}
+	return a.out.walletPage(page)
+}
+
+// importWallets печатает отчёт импорта и в том случае, когда импорт
+// отклонён: по нему видно, какие записи исправить.
+func (a *app) importWallets(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	format := flags.String("format", string(models.BulkCSV), "file format: csv or jsonl")
+	dryRun := flags.Bool("dry-run", false, "validate and roll back")
+	legacyOwner := flags.String("legacy-owner", "", "owner for records with an empty owner ID")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return errUsage
+	}
+	bulkFormat, err := models.ParseBulkFormat(*format)
+	if err != nil {
+		return err
+	}
+	var opts []bulk.Option
+	if *legacyOwner != "" {
+		ownerID, err := uuid.Parse(*legacyOwner)
+		if err != nil {
+			return fmt.Errorf("invalid owner ID: %w", err)
+		}
+		opts = append(opts, bulk.WithLegacyOwner(ownerID))
+	}
+
+	in := io.Reader(os.Stdin)
+	if name := flags.Arg(0); name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+	reader, err := bulk.NewReader(bufio.NewReader(in), bulkFormat, opts...)
+	if err != nil {
+		return err
+	}
+
+	report, err := service.NewBulkService(a.repo).ImportWallets(ctx, reader, *dryRun)
+	if report != nil {
+		if err := a.out.importReport(report); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// exportWallets печатает итоги по валютам в stdout, а если файл пишется в
+// stdout — в stderr.
+func (a *app) exportWallets(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	format := flags.String("format", string(models.BulkCSV), "file format: csv or jsonl")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 1 {
+		return errUsage
+	}
+	bulkFormat, err := models.ParseBulkFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	out, report := io.Writer(a.out.w), &printer{w: os.Stderr, json: a.out.json}
+	if flags.NArg() == 1 {
+		file, err := os.Create(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out, report = file, a.out
+	}
+	buffered := bufio.NewWriter(out)
+	writer, err := bulk.NewWriter(buffered, bulkFormat)
+	if err != nil {
+		return err
+	}
+	if err := service.NewBulkService(a.repo).ExportWallets(ctx, writer); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	return report.totals(writer.Totals())
+}
+
+func parseWalletID(value string) (uuid.UUID, error) {
+	walletID, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid wallet ID %q", value)
+	}
+	return walletID, nil
+}
+
+func parseAmount(value string) (int64, error) {
+	amount, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	return amount, nil
+}
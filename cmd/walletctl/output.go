@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+)
+
+type printer struct {
+	w    io.Writer
+	json bool
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case "table":
+		return &printer{w: w}, nil
+	case "json":
+		return &printer{w: w, json: true}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q, expected table or json", format)
+	}
+}
+
+func (p *printer) wallet(wallet *models.Wallet) error {
+	if p.json {
+		return p.encode(wallet)
+	}
+	return p.table(
+		[]string{"WALLET", "BALANCE", "CURRENCY", "STATUS", "VERSION", "CREATED"},
+		[]string{wallet.ID.String(), fmt.Sprint(wallet.Balance), string(wallet.Currency), string(wallet.Status), fmt.Sprint(wallet.Version), wallet.CreatedAt.Format(time.RFC3339)},
+	)
+}
+
+func (p *printer) balance(balance *models.BalanceResponse) error {
+	if p.json {
+		return p.encode(balance)
+	}
+	asOf := ""
+	if balance.AsOf != nil {
+		asOf = balance.AsOf.Format(time.RFC3339Nano)
+	}
+	return p.table(
+		[]string{"BALANCE", "DECIMAL", "CURRENCY", "AS OF"},
+		[]string{fmt.Sprint(balance.Balance), balance.BalanceDecimal, string(balance.Currency), asOf},
+	)
+}
+
+func (p *printer) owner(owner *models.Owner) error {
+	if p.json {
+		return p.encode(owner)
+	}
+	return p.table(
+		[]string{"OWNER", "NAME", "CREATED"},
+		[]string{owner.ID.String(), owner.Name, owner.CreatedAt.Format(time.RFC3339)},
+	)
+}
+
+// ownerWallets печатает кошельки владельца, а под ними — суммы по валютам.
+func (p *printer) ownerWallets(wallets *models.OwnerWallets) error {
+	if p.json {
+		return p.encode(wallets)
+	}
+
+	rows := make([][]string, 0, len(wallets.Wallets))
+	for _, wallet := range wallets.Wallets {
+		rows = append(rows, []string{
+			wallet.ID.String(), fmt.Sprint(wallet.Balance), string(wallet.Currency), string(wallet.Status), wallet.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	if err := p.table([]string{"WALLET", "BALANCE", "CURRENCY", "STATUS", "CREATED"}, rows...); err != nil {
+		return err
+	}
+	fmt.Fprintln(p.w)
+	return p.totalsTable(wallets.Totals)
+}
+
+// totals печатает число кошельков и сумму балансов по валютам.
+func (p *printer) totals(totals []models.CurrencyTotal) error {
+	if p.json {
+		return p.encode(totals)
+	}
+	return p.totalsTable(totals)
+}
+
+func (p *printer) totalsTable(totals []models.CurrencyTotal) error {
+	rows := make([][]string, 0, len(totals))
+	for _, total := range totals {
+		rows = append(rows, []string{string(total.Currency), fmt.Sprint(total.Wallets), total.Balance.String(), total.BalanceDecimal})
+	}
+	return p.table([]string{"CURRENCY", "WALLETS", "BALANCE", "DECIMAL"}, rows...)
+}
+
+// importReport печатает итог импорта, сверку итогов файла и базы по
+// валютам и ошибки записей.
+func (p *printer) importReport(report *models.ImportReport) error {
+	if p.json {
+		return p.encode(report)
+	}
+
+	state := "committed"
+	switch {
+	case report.ErrorCount > 0:
+		state = "rejected"
+	case report.DryRun:
+		state = "dry run, rolled back"
+	}
+	fmt.Fprintf(p.w, "%s: %d record(s), %d wallet(s), %d new owner(s), balanced: %t\n\n",
+		state, report.Records, report.Wallets, report.OwnersCreated, report.Balanced)
+
+	loaded := make(map[models.Currency]models.CurrencyTotal, len(report.Loaded))
+	for _, total := range report.Loaded {
+		loaded[total.Currency] = total
+	}
+	rows := make([][]string, 0, len(report.Input))
+	for _, total := range report.Input {
+		row := []string{string(total.Currency), fmt.Sprint(total.Wallets), total.BalanceDecimal, "", ""}
+		if l, ok := loaded[total.Currency]; ok {
+			row[3], row[4] = fmt.Sprint(l.Wallets), l.BalanceDecimal
+		}
+		rows = append(rows, row)
+	}
+	if err := p.table([]string{"CURRENCY", "FILE WALLETS", "FILE BALANCE", "LOADED WALLETS", "LOADED BALANCE"}, rows...); err != nil {
+		return err
+	}
+	if report.ErrorCount == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(p.w)
+	rows = make([][]string, 0, len(report.Errors))
+	for _, importErr := range report.Errors {
+		walletID := ""
+		if importErr.WalletID != nil {
+			walletID = importErr.WalletID.String()
+		}
+		rows = append(rows, []string{fmt.Sprint(importErr.Line), walletID, string(importErr.Code), importErr.Message})
+	}
+	if err := p.table([]string{"LINE", "WALLET", "CODE", "MESSAGE"}, rows...); err != nil {
+		return err
+	}
+	if more := report.ErrorCount - int64(len(report.Errors)); more > 0 {
+		fmt.Fprintf(p.w, "... and %d more error(s)\n", more)
+	}
+	return nil
+}
+
+// reconciliation печатает итоги сверки по видам расхождений и первые из
+// них.
+func (p *printer) reconciliation(report *models.ReconciliationReport) error {
+	if p.json {
+		return p.encode(report)
+	}
+
+	fmt.Fprintf(p.w, "run %d: %d wallet(s), %d transfer(s) checked in %s\n\n",
+		report.ID, report.Wallets, report.Transfers, report.FinishedAt.Sub(report.StartedAt).Round(time.Millisecond))
+	rows := make([][]string, 0, len(models.DiscrepancyKinds))
+	for _, kind := range models.DiscrepancyKinds {
+		rows = append(rows, []string{string(kind), fmt.Sprint(report.Counts[kind])})
+	}
+	if err := p.table([]string{"KIND", "DISCREPANCIES"}, rows...); err != nil {
+		return err
+	}
+	if len(report.Discrepancies) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(p.w)
+	rows = make([][]string, 0, len(report.Discrepancies))
+	for _, d := range report.Discrepancies {
+		walletID, transferID := "", ""
+		if d.WalletID != nil {
+			walletID = d.WalletID.String()
+		}
+		if d.TransferID != nil {
+			transferID = d.TransferID.String()
+		}
+		rows = append(rows, []string{string(d.Kind), walletID, transferID, fmt.Sprint(d.Expected), fmt.Sprint(d.Actual)})
+	}
+	if err := p.table([]string{"KIND", "WALLET", "TRANSFER", "EXPECTED", "ACTUAL"}, rows...); err != nil {
+		return err
+	}
+	if more := report.Total() - int64(len(report.Discrepancies)); more > 0 {
+		fmt.Fprintf(p.w, "... and %d more discrepancy(ies)\n", more)
+	}
+	return nil
+}
+
+// walletPage печатает найденные кошельки и курсор следующей страницы.
+func (p *printer) walletPage(page *models.WalletPage) error {
+	if p.json {
+		return p.encode(page)
+	}
+
+	rows := make([][]string, 0, len(page.Wallets))
+	for _, wallet := range page.Wallets {
+		labels := make([]string, 0, len(wallet.Labels))
+		for key, value := range wallet.Labels {
+			labels = append(labels, key+":"+value)
+		}
+		sort.Strings(labels)
+		rows = append(rows, []string{
+			wallet.ID.String(), fmt.Sprint(wallet.Balance), string(wallet.Currency), string(wallet.Status), strings.Join(labels, ","), wallet.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	if err := p.table([]string{"WALLET", "BALANCE", "CURRENCY", "STATUS", "LABELS", "CREATED"}, rows...); err != nil {
+		return err
+	}
+	if page.NextCursor != "" {
+		fmt.Fprintf(p.w, "\nnext page: cursor=%s\n", page.NextCursor)
+	}
+	return nil
+}
+
+func (p *printer) transfer(transferID, fromID, toID uuid.UUID, amount int64) error {
+	if p.json {
+		return p.encode(map[string]interface{}{
+			"transferId":   transferID,
+			"fromWalletId": fromID,
+			"toWalletId":   toID,
+			"amount":       amount,
+		})
+	}
+	return p.table(
+		[]string{"TRANSFER", "FROM", "TO", "AMOUNT"},
+		[]string{transferID.String(), fromID.String(), toID.String(), fmt.Sprint(amount)},
+	)
+}
+
+func (p *printer) operations(operations []models.Operation) error {
+	if p.json {
+		return p.encode(operations)
+	}
+
+	rows := make([][]string, 0, len(operations))
+	for _, op := range operations {
+		transferID := ""
+		if op.TransferID != nil {
+			transferID = op.TransferID.String()
+		}
+		rows = append(rows, []string{
+			fmt.Sprint(op.ID), op.CreatedAt.Format(time.RFC3339), string(op.Type), fmt.Sprint(op.Amount), transferID,
+		})
+	}
+	return p.table([]string{"ID", "TIME", "TYPE", "AMOUNT", "TRANSFER"}, rows...)
+}
+
+func (p *printer) encode(v interface{}) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (p *printer) table(header []string, rows ...[]string) error {
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			if i > 0 {
+				fmt.Fprint(tw, "\t")
+			}
+			fmt.Fprint(tw, cell)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
@@ -23,9 +23,446 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/api/v1/admin/wallets": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Ищет кошельки по меткам, статусу, диапазону баланса и дате создания. Кошельки упорядочены по времени создания; следующую страницу запрашивают с cursor из nextCursor.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Поиск кошельков",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Метка key:value, можно повторять",
+                        "name": "label",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "ACTIVE",
+                            "FROZEN"
+                        ],
+                        "type": "string",
+                        "description": "Статус кошелька",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Баланс не меньше, в минимальных единицах",
+                        "name": "minBalance",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Баланс не больше, в минимальных единицах",
+                        "name": "maxBalance",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Создан не раньше (RFC 3339)",
+                        "name": "createdFrom",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Создан раньше (RFC 3339)",
+                        "name": "createdTo",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Размер страницы, до 500",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Страница кошельков с метками и метаданными",
+                        "schema": {
+                            "$ref": "#/definitions/models.WalletPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный фильтр: VALIDATION_FAILED или код ошибки параметра (INVALID_LABEL, INVALID_FILTER, INVALID_LIMIT); ошибки параметров — в errors",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет или неверный токен администратора: UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера: INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервис перегружен: SERVICE_OVERLOADED",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Поиск не уложился в дедлайн: TIMEOUT",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/wallets/export": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Выгружает все кошельки в порядке создания в формате импорта вместе с лимитами, метками и метаданными, из одного снимка базы. У кошельков, созданных до появления владельцев, ownerId пуст: загрузить их обратно можно с legacyOwner. Файл пишется потоком; ошибка после начала передачи обрывает соединение, поэтому неполный файл не выглядит полным.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Экспорт кошельков",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "jsonl"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "Формат файла",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Файл экспорта",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Content-Disposition": {
+                                "type": "string",
+                                "description": "attachment; filename=wallets-\u003ctime\u003e.\u003cformat\u003e"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неизвестный формат: INVALID_FORMAT",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет или неверный токен администратора: UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера: INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Экспорт не уложился в дедлайн: TIMEOUT",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/wallets/import": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Загружает кошельки и их начальные балансы из CSV (колонки wallet_id, owner_id, currency, balance и необязательные status, max_balance, labels, metadata) или JSON Lines (поля walletId, ownerId, currency, balance, status, maxBalance, labels, metadata) командой COPY одной транзакцией. Баланс и лимит — десятичные строки в основных единицах валюты, баланс записывается в журнал операцией OPENING; метки и метаданные — JSON-объекты; недостающие владельцы создаются. Пустой ownerId экспорт пишет для кошельков, созданных до появления владельцев: такие записи принимаются, только если задан legacyOwner, иначе INVALID_OWNER_ID. Если есть неверные записи или дубликаты (повтор в файле — DUPLICATE_WALLET, кошелёк уже есть — WALLET_EXISTS), ничего не записывается. Отчёт сверяет итоги по валютам из файла с итогами записанных кошельков; с dryRun=true импорт проверяется и откатывается.",
+                "consumes": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Импорт кошельков",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "jsonl"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "Формат файла",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Проверить и откатить импорт",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Владелец для записей с пустым ownerId",
+                        "name": "legacyOwner",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Файл импорта",
+                        "name": "file",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Отчёт импорта",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос: INVALID_REQUEST, INVALID_FORMAT или INVALID_OWNER_ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет или неверный токен администратора: UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Файл больше BULK_MAX_BYTES: REQUEST_TOO_LARGE",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "В файле есть ошибки, ничего не записано; ошибки записей — в errors",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImportReport"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера: INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Импорт не уложился в дедлайн: TIMEOUT",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/wallets/{walletId}/metadata": {
+            "put": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Заменяет метки и метаданные кошелька целиком. Метки — до 64 пар ключ-значение для поиска, метаданные — JSON-объект до 16 КБ. Версия кошелька не меняется.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Задать метки и метаданные кошелька",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID кошелька",
+                        "name": "walletId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Метки и метаданные",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.WalletMetadataRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Кошелёк с новыми метками и метаданными",
+                        "schema": {
+                            "$ref": "#/definitions/models.Wallet"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос: INVALID_REQUEST, VALIDATION_FAILED, INVALID_LABEL или INVALID_METADATA; ошибки полей — в errors",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет или неверный токен администратора: UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Кошелёк не найден: WALLET_NOT_FOUND",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Тело запроса больше 64 КБ: REQUEST_TOO_LARGE",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера: INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервис перегружен: SERVICE_OVERLOADED",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Операция не уложилась в дедлайн: TIMEOUT",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/owners/{ownerId}/wallets": {
+            "get": {
+                "description": "Возвращает кошельки владельца в порядке создания и их суммарный баланс по каждой валюте",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "owner"
+                ],
+                "summary": "Кошельки владельца",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID владельца",
+                        "name": "ownerId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Кошельки и суммы по валютам",
+                        "schema": {
+                            "$ref": "#/definitions/models.OwnerWallets"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный UUID: INVALID_REQUEST",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Владелец не найден: OWNER_NOT_FOUND",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит частоты запросов: RATE_LIMITED",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера: INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервис перегружен: SERVICE_OVERLOADED",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Чтение не уложилось в дедлайн: TIMEOUT",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    }
+                }
+            }
+        },
         "/api/v1/wallet": {
             "post": {
-                "description": "Выполняет операцию пополнения или списания средств",
+                "description": "Выполняет операцию пополнения или списания средств. Сумма — целое число минимальных единиц (1250) или десятичная строка в основных единицах валюты кошелька (\"12.50\"); во втором случае ответ содержит и balanceDecimal. Лишние знаки после запятой отклоняются (AMOUNT_PRECISION).",
                 "consumes": [
                     "application/json"
                 ],
@@ -35,68 +472,261 @@ const docTemplate = `{
                 "tags": [
                     "wallet"
                 ],
-                "summary": "Изменить баланс кошелька",
+                "summary": "Изменить баланс кошелька",
+                "parameters": [
+                    {
+                        "description": "Данные операции",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.OperationRequestDoc"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag кошелька: операция выполнится, только если кошелёк не менялся",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешное выполнение операции и новый баланс",
+                        "schema": {
+                            "$ref": "#/definitions/models.OperationResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия кошелька после операции (только при If-Match)"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос: INVALID_REQUEST, VALIDATION_FAILED или код ошибки поля (INVALID_WALLET_ID, INVALID_OPERATION_TYPE, INVALID_AMOUNT, AMOUNT_TOO_LARGE, AMOUNT_PRECISION); ошибки полей — в errors",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Конфликт: INSUFFICIENT_FUNDS, BALANCE_LIMIT_EXCEEDED, WALLET_NOT_FOUND, WALLET_FROZEN",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Кошелёк изменился после чтения: VERSION_MISMATCH",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Тело запроса больше 64 КБ: REQUEST_TOO_LARGE",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит частоты запросов: RATE_LIMITED",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера: INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Кошелёк занят (WALLET_BUSY) или сервис перегружен (SERVICE_OVERLOADED)",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Операция не уложилась в дедлайн: TIMEOUT",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{walletId}": {
+            "get": {
+                "description": "Возвращает текущий баланс указанного кошелька в минимальных единицах и десятичной строкой в основных единицах его валюты",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Получить баланс кошелька",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID кошелька",
+                        "name": "walletId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "eventual",
+                            "strong"
+                        ],
+                        "type": "string",
+                        "description": "strong — читать с основной базы в обход реплик и кэша",
+                        "name": "consistency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Баланс кошелька",
+                        "schema": {
+                            "$ref": "#/definitions/models.BalanceResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия кошелька для If-Match"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный UUID или consistency: INVALID_REQUEST",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Кошелек не найден: WALLET_NOT_FOUND",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит частоты запросов: RATE_LIMITED",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера: INTERNAL_ERROR",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервис перегружен: SERVICE_OVERLOADED, WALLET_BUSY",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Чтение не уложилось в дедлайн: TIMEOUT",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{walletId}/balance": {
+            "get": {
+                "description": "Без asOf возвращает текущий баланс, как GET /api/v1/wallets/{walletId}. С asOf — баланс по журналу операций на этот момент: сумма операций не позже asOf, считаемая от ближайшей предыдущей контрольной точки. До создания кошелька баланс нулевой.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Баланс кошелька на момент времени",
                 "parameters": [
                     {
-                        "description": "Данные операции",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.OperationRequest"
-                        }
+                        "type": "string",
+                        "description": "UUID кошелька",
+                        "name": "walletId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Момент времени (RFC 3339), не в будущем",
+                        "name": "asOf",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "eventual",
+                            "strong"
+                        ],
+                        "type": "string",
+                        "description": "strong — читать текущий баланс с основной базы в обход реплик и кэша",
+                        "name": "consistency",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Успешное выполнение операции",
+                        "description": "Баланс кошелька",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.BalanceResponse"
                         }
                     },
                     "400": {
-                        "description": "Неверный запрос",
+                        "description": "Неверный UUID или consistency (INVALID_REQUEST), неверный asOf (INVALID_AS_OF)",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.Problem"
                         }
                     },
-                    "409": {
-                        "description": "Конфликт (недостаточно средств или кошелек не найден)",
+                    "404": {
+                        "description": "Кошелек не найден: WALLET_NOT_FOUND",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит частоты запросов: RATE_LIMITED",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
                         }
                     },
                     "500": {
-                        "description": "Внутренняя ошибка сервера",
+                        "description": "Внутренняя ошибка сервера: INTERNAL_ERROR",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервис перегружен: SERVICE_OVERLOADED",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Чтение не уложилось в дедлайн: TIMEOUT",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/v1/wallets/{walletId}": {
+        "/api/v1/wallets/{walletId}/statement": {
             "get": {
-                "description": "Возвращает текущий баланс указанного кошелька",
+                "description": "Выписка за период (from, to]: строка OPENING с остатком на момент from, строка на каждую операцию с остатком после неё и строка CLOSING с остатком на момент to. Пишется потоком по мере чтения журнала, в CSV (по умолчанию) или JSON Lines. Ошибка после начала передачи обрывает соединение, поэтому неполная выписка не выглядит полной.",
                 "produces": [
-                    "application/json"
+                    "text/csv",
+                    "application/x-ndjson"
                 ],
                 "tags": [
                     "wallet"
                 ],
-                "summary": "Получить баланс кошелька",
+                "summary": "Выписка по кошельку",
                 "parameters": [
                     {
                         "type": "string",
@@ -104,43 +734,79 @@ const docTemplate = `{
                         "name": "walletId",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Начало периода (RFC 3339), не включается",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец периода (RFC 3339), включается; по умолчанию — текущий момент",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "jsonl"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "Формат выписки",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Баланс кошелька",
+                        "description": "Выписка",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "integer"
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Content-Disposition": {
+                                "type": "string",
+                                "description": "attachment; filename=statement-\u003cwalletId\u003e-\u003cfrom\u003e-\u003cto\u003e.\u003cformat\u003e"
                             }
                         }
                     },
                     "400": {
-                        "description": "Неверный UUID",
+                        "description": "Неверный запрос: INVALID_REQUEST, VALIDATION_FAILED или код ошибки параметра (INVALID_PERIOD, INVALID_FORMAT); ошибки параметров — в errors",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.Problem"
                         }
                     },
                     "404": {
-                        "description": "Кошелек не найден",
+                        "description": "Кошелек не найден: WALLET_NOT_FOUND",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит частоты запросов: RATE_LIMITED",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
                         }
                     },
                     "500": {
-                        "description": "Внутренняя ошибка сервера",
+                        "description": "Внутренняя ошибка сервера: INTERNAL_ERROR",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервис перегружен: SERVICE_OVERLOADED",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Выписка не уложилась в дедлайн: TIMEOUT",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
                         }
                     }
                 }
@@ -148,22 +814,65 @@ const docTemplate = `{
         },
         "/health": {
             "get": {
-                "description": "Возвращает статус работы сервиса",
+                "description": "Возвращает 200, пока процесс способен обслуживать запросы. Зависимости не проверяются",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "health"
                 ],
-                "summary": "Проверка здоровья сервиса",
+                "summary": "Проверка жизнеспособности",
                 "responses": {
                     "200": {
-                        "description": "Сервис работает",
+                        "description": "Процесс жив",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/livez": {
+            "get": {
+                "description": "Возвращает 200, пока процесс способен обслуживать запросы. Зависимости не проверяются",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Проверка жизнеспособности",
+                "responses": {
+                    "200": {
+                        "description": "Процесс жив",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Проверяет доступность базы данных, заполненность пула соединений и версию схемы. Во время остановки сервиса возвращает 503",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Проверка готовности",
+                "responses": {
+                    "200": {
+                        "description": "Сервис готов",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервис не готов",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
                         }
                     }
                 }
@@ -171,16 +880,290 @@ const docTemplate = `{
         }
     },
     "definitions": {
-        "models.OperationRequest": {
+        "health.CheckResult": {
             "type": "object",
             "properties": {
-                "amount": {
+                "details": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "duration": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.Report": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/health.CheckResult"
+                    }
+                },
+                "draining": {
+                    "type": "boolean"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BalanceResponse": {
+            "type": "object",
+            "properties": {
+                "asOf": {
+                    "description": "AsOf задан, если баланс исторический.",
+                    "type": "string",
+                    "example": "2026-09-30T23:59:59Z"
+                },
+                "balance": {
+                    "type": "integer",
+                    "example": 1250
+                },
+                "balanceDecimal": {
+                    "type": "string",
+                    "example": "12.50"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "RUB"
+                }
+            }
+        },
+        "models.Code": {
+            "type": "string",
+            "enum": [
+                "WALLET_NOT_FOUND",
+                "INSUFFICIENT_FUNDS",
+                "BALANCE_LIMIT_EXCEEDED",
+                "INVALID_BALANCE_LIMIT",
+                "INVALID_OPERATION_TYPE",
+                "INVALID_AMOUNT",
+                "WALLET_FROZEN",
+                "SAME_WALLET",
+                "INVALID_LIMIT",
+                "VERSION_MISMATCH",
+                "INVALID_WALLET_ID",
+                "AMOUNT_TOO_LARGE",
+                "VALIDATION_FAILED",
+                "AMOUNT_PRECISION",
+                "INVALID_CURRENCY",
+                "WALLET_NOT_EMPTY",
+                "OWNER_NOT_FOUND",
+                "INVALID_OWNER_ID",
+                "INVALID_LABEL",
+                "INVALID_METADATA",
+                "INVALID_FILTER",
+                "INVALID_AS_OF",
+                "INVALID_PERIOD",
+                "INVALID_FORMAT",
+                "INVALID_RECORD",
+                "DUPLICATE_WALLET",
+                "WALLET_EXISTS",
+                "IMPORT_REJECTED",
+                "INVALID_REQUEST",
+                "UNAUTHORIZED",
+                "REQUEST_TOO_LARGE",
+                "RATE_LIMITED",
+                "WALLET_BUSY",
+                "SERVICE_OVERLOADED",
+                "TIMEOUT",
+                "INTERNAL_ERROR"
+            ],
+            "x-enum-varnames": [
+                "CodeWalletNotFound",
+                "CodeInsufficientFunds",
+                "CodeBalanceLimitExceeded",
+                "CodeInvalidBalanceLimit",
+                "CodeInvalidOperationType",
+                "CodeInvalidAmount",
+                "CodeWalletFrozen",
+                "CodeSameWallet",
+                "CodeInvalidLimit",
+                "CodeVersionMismatch",
+                "CodeInvalidWalletID",
+                "CodeAmountTooLarge",
+                "CodeValidationFailed",
+                "CodeAmountPrecision",
+                "CodeInvalidCurrency",
+                "CodeWalletNotEmpty",
+                "CodeOwnerNotFound",
+                "CodeInvalidOwnerID",
+                "CodeInvalidLabel",
+                "CodeInvalidMetadata",
+                "CodeInvalidFilter",
+                "CodeInvalidAsOf",
+                "CodeInvalidPeriod",
+                "CodeInvalidFormat",
+                "CodeInvalidRecord",
+                "CodeDuplicateWallet",
+                "CodeWalletExists",
+                "CodeImportRejected",
+                "CodeInvalidRequest",
+                "CodeUnauthorized",
+                "CodeRequestTooLarge",
+                "CodeRateLimited",
+                "CodeWalletBusy",
+                "CodeOverloaded",
+                "CodeTimeout",
+                "CodeInternal"
+            ]
+        },
+        "models.CurrencyTotal": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "integer",
+                    "example": 1250
+                },
+                "balanceDecimal": {
+                    "type": "string",
+                    "example": "12.50"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "wallets": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "models.FieldError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Code"
+                        }
+                    ],
+                    "example": "INVALID_AMOUNT"
+                },
+                "field": {
+                    "type": "string",
+                    "example": "amount"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "amount must be positive"
+                }
+            }
+        },
+        "models.ImportError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Code"
+                        }
+                    ],
+                    "example": "DUPLICATE_WALLET"
+                },
+                "line": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "message": {
+                    "type": "string",
+                    "example": "wallet ID repeats an earlier record"
+                },
+                "walletId": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ImportReport": {
+            "type": "object",
+            "properties": {
+                "balanced": {
+                    "type": "boolean"
+                },
+                "committed": {
+                    "type": "boolean"
+                },
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "errorCount": {
                     "type": "integer"
                 },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ImportError"
+                    }
+                },
+                "input": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CurrencyTotal"
+                    }
+                },
+                "loaded": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CurrencyTotal"
+                    }
+                },
+                "ownersCreated": {
+                    "type": "integer",
+                    "example": 250
+                },
+                "records": {
+                    "type": "integer",
+                    "example": 1000
+                },
+                "wallets": {
+                    "type": "integer",
+                    "example": 1000
+                }
+            }
+        },
+        "models.OperationRequestDoc": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Сумма: целое число минимальных единиц валюты кошелька (1250) или\nдесятичная строка в основных единицах (\"12.50\"). Дробное число\n(12.5) не принимается."
+                },
                 "operationType": {
-                    "$ref": "#/definitions/models.OperationType"
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.OperationType"
+                        }
+                    ],
+                    "example": "DEPOSIT"
                 },
                 "walletId": {
+                    "type": "string",
+                    "example": "4f1c6a9e-0000-4000-8000-000000000001"
+                }
+            }
+        },
+        "models.OperationResponse": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "integer"
+                },
+                "balanceDecimal": {
+                    "type": "string",
+                    "example": "12.50"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "status": {
                     "type": "string"
                 }
             }
@@ -189,13 +1172,168 @@ const docTemplate = `{
             "type": "string",
             "enum": [
                 "DEPOSIT",
-                "WITHDRAW"
+                "WITHDRAW",
+                "OPENING",
+                "TRANSFER_IN",
+                "TRANSFER_OUT"
             ],
             "x-enum-varnames": [
                 "Deposit",
-                "Withdraw"
+                "Withdraw",
+                "Opening",
+                "TransferIn",
+                "TransferOut"
+            ]
+        },
+        "models.OwnerWallets": {
+            "type": "object",
+            "properties": {
+                "ownerId": {
+                    "type": "string"
+                },
+                "totals": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CurrencyTotal"
+                    }
+                },
+                "wallets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Wallet"
+                    }
+                }
+            }
+        },
+        "models.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Code"
+                        }
+                    ],
+                    "example": "INSUFFICIENT_FUNDS"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "insufficient funds"
+                },
+                "errors": {
+                    "description": "Errors — ошибки отдельных полей, только для ошибок валидации.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/api/v1/wallet"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 409
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Conflict"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "about:blank"
+                }
+            }
+        },
+        "models.Wallet": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "labels": {
+                    "description": "Labels и Metadata заполняются только в административных ответах.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "metadata": {
+                    "type": "object"
+                },
+                "ownerId": {
+                    "description": "OwnerID пуст только у кошельков, созданных до появления владельцев.",
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/models.WalletStatus"
+                },
+                "version": {
+                    "type": "integer"
+                },
+                "walletId": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WalletMetadataRequest": {
+            "type": "object",
+            "properties": {
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "team": "payments"
+                    }
+                },
+                "metadata": {
+                    "type": "object"
+                }
+            }
+        },
+        "models.WalletPage": {
+            "type": "object",
+            "properties": {
+                "nextCursor": {
+                    "type": "string",
+                    "example": "MjAyNi0xMC0xOFQxMjowMDowMFosNWY..."
+                },
+                "wallets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Wallet"
+                    }
+                }
+            }
+        },
+        "models.WalletStatus": {
+            "type": "string",
+            "enum": [
+                "ACTIVE",
+                "FROZEN"
+            ],
+            "x-enum-varnames": [
+                "StatusActive",
+                "StatusFrozen"
             ]
         }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "description": "Токен администратора: \"Bearer \u003cADMIN_TOKEN\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
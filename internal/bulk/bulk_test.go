@@ -0,0 +1,229 @@
+package bulk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	walletA = uuid.MustParse("0b6f2c1e-2f6a-4d52-9b8e-1f2d3c4b5a69")
+	walletB = uuid.MustParse("7c1d9e4f-3a2b-4c5d-8e9f-0a1b2c3d4e5f")
+	ownerID = uuid.MustParse("5f1c6f0e-9a43-4d7a-9d1e-3a0c9a8b7c6d")
+)
+
+// readAll читает все записи, разделяя проверенные и ошибки.
+func readAll(t *testing.T, input string, format models.BulkFormat, opts ...Option) ([]models.ImportedWallet, []models.ImportError) {
+	t.Helper()
+	reader, err := NewReader(strings.NewReader(input), format, opts...)
+	require.NoError(t, err)
+
+	var (
+		wallets []models.ImportedWallet
+		errs    []models.ImportError
+	)
+	for {
+		wallet, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return wallets, errs
+		}
+		var importErr *models.ImportError
+		require.True(t, errors.As(err, &importErr) || err == nil, "unexpected error %v", err)
+		if importErr != nil {
+			errs = append(errs, *importErr)
+			continue
+		}
+		wallets = append(wallets, *wallet)
+	}
+}
+
+func TestReader_CSV(t *testing.T) {
+	input := "balance,currency,wallet_id,owner_id\n" +
+		"12.50,RUB," + walletA.String() + "," + ownerID.String() + "\n" +
+		"\"1000\",JPY," + walletB.String() + "," + ownerID.String() + "\n"
+
+	wallets, errs := readAll(t, input, models.BulkCSV)
+	assert.Empty(t, errs)
+	assert.Equal(t, []models.ImportedWallet{
+		{Line: 2, ID: walletA, OwnerID: ownerID, Currency: "RUB", Balance: 1250, Status: models.StatusActive},
+		{Line: 3, ID: walletB, OwnerID: ownerID, Currency: "JPY", Balance: 1000, Status: models.StatusActive},
+	}, wallets)
+}
+
+func TestReader_JSONL(t *testing.T) {
+	input := `{"walletId": "` + walletA.String() + `", "ownerId": "` + ownerID.String() + `", "currency": "USD", "balance": "0.05", "status": "FROZEN"}` + "\n" +
+		"\n" +
+		`{"walletId": "` + walletB.String() + `", "ownerId": "` + ownerID.String() + `", "currency": "KWD", "balance": 1.125}` + "\n"
+
+	wallets, errs := readAll(t, input, models.BulkJSONL)
+	assert.Empty(t, errs)
+	assert.Equal(t, []models.ImportedWallet{
+		{Line: 1, ID: walletA, OwnerID: ownerID, Currency: "USD", Balance: 5, Status: models.StatusFrozen},
+		{Line: 3, ID: walletB, OwnerID: ownerID, Currency: "KWD", Balance: 1125, Status: models.StatusActive},
+	}, wallets)
+}
+
+func TestReader_InvalidRecords(t *testing.T) {
+	owner := ownerID.String()
+	input := "wallet_id,owner_id,currency,balance,status,max_balance,labels,metadata\n" +
+		"not-a-uuid," + owner + ",RUB,1,,,,\n" +
+		walletA.String() + ",," + "RUB,1,,,,\n" +
+		walletA.String() + "," + owner + ",XXX,1,,,,\n" +
+		walletA.String() + "," + owner + ",RUB,1.005,,,,\n" +
+		walletA.String() + "," + owner + ",RUB,-1,,,,\n" +
+		walletA.String() + "," + owner + ",RUB,1,CLOSED,,,\n" +
+		walletA.String() + "," + owner + ",RUB\n" +
+		walletA.String() + "," + owner + ",RUB,1,,0.5,,\n" +
+		walletA.String() + "," + owner + ",RUB,1,,-1,,\n" +
+		walletA.String() + "," + owner + ",RUB,1,,,\"{\"\"Team\"\": \"\"x\"\"}\",\n" +
+		walletA.String() + "," + owner + ",RUB,1,,,,[1]\n" +
+		walletB.String() + "," + owner + ",RUB,1,ACTIVE,,,\n"
+
+	wallets, errs := readAll(t, input, models.BulkCSV)
+	require.Len(t, wallets, 1)
+	assert.Equal(t, walletB, wallets[0].ID)
+
+	codes := make(map[int64]models.Code)
+	for _, importErr := range errs {
+		codes[importErr.Line] = importErr.Code
+	}
+	assert.Equal(t, map[int64]models.Code{
+		2:  models.CodeInvalidWalletID,
+		3:  models.CodeInvalidOwnerID,
+		4:  models.CodeInvalidCurrency,
+		5:  models.CodeAmountPrecision,
+		6:  models.CodeInvalidAmount,
+		7:  models.CodeInvalidRecord,
+		8:  models.CodeInvalidRecord,
+		9:  models.CodeBalanceLimitExceeded,
+		10: models.CodeInvalidBalanceLimit,
+		11: models.CodeInvalidLabel,
+		12: models.CodeInvalidMetadata,
+	}, codes)
+	assert.Equal(t, &walletA, errs[1].WalletID)
+}
+
+// TestReader_BalanceAtLimit проверяет, что баланс и лимит, равные
+// models.MaxBalance, загружаются, а больший баланс — нет.
+func TestReader_BalanceAtLimit(t *testing.T) {
+	owner := ownerID.String()
+	input := "wallet_id,owner_id,currency,balance,max_balance\n" +
+		walletA.String() + "," + owner + ",RUB,10000000000000000.00,10000000000000000\n" +
+		walletB.String() + "," + owner + ",RUB,10000000000000000.01,\n"
+
+	wallets, errs := readAll(t, input, models.BulkCSV)
+	require.Len(t, wallets, 1)
+	assert.Equal(t, models.MaxBalance, wallets[0].Balance)
+	assert.Equal(t, models.MaxBalance, *wallets[0].MaxBalance)
+	require.Len(t, errs, 1)
+	assert.Equal(t, models.CodeAmountTooLarge, errs[0].Code)
+}
+
+func TestReader_Fatal(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		format models.BulkFormat
+		line   int64
+	}{
+		{"empty CSV", "", models.BulkCSV, 1},
+		{"unknown column", "wallet_id,owner_id,currency,balance,note\n", models.BulkCSV, 1},
+		{"missing column", "wallet_id,owner_id,currency\n", models.BulkCSV, 1},
+		{"bare quote", "wallet_id,owner_id,currency,balance\na,b\"c,RUB,1\n", models.BulkCSV, 2},
+		{"long JSON line", "\n" + strings.Repeat(" ", MaxRecordBytes+1) + "\n", models.BulkJSONL, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wallets, errs := readAll(t, tt.input, tt.format)
+			assert.Empty(t, wallets)
+			require.Len(t, errs, 1)
+			assert.Equal(t, models.CodeInvalidFormat, errs[0].Code)
+			assert.Equal(t, tt.line, errs[0].Line)
+		})
+	}
+}
+
+func TestReader_InvalidJSON(t *testing.T) {
+	input := `{"walletId": "` + walletA.String() + `", "extra": 1}` + "\n" +
+		`{"walletId": "` + walletA.String() + `"} {}` + "\n" +
+		`[1, 2]` + "\n"
+
+	_, errs := readAll(t, input, models.BulkJSONL)
+	require.Len(t, errs, 3)
+	for i, importErr := range errs {
+		assert.Equal(t, int64(i+1), importErr.Line)
+		assert.Equal(t, models.CodeInvalidRecord, importErr.Code)
+	}
+}
+
+// TestWriter_RoundTrip проверяет, что файл экспорта читается импортом без
+// потерь, включая лимит, метки, метаданные и кошелёк без владельца.
+func TestWriter_RoundTrip(t *testing.T) {
+	limit, defaultLimit := int64(5000), models.MaxBalance
+	legacyOwner := uuid.MustParse("9d8c7b6a-5f4e-4d3c-8b2a-1908f7e6d5c4")
+	walletC := uuid.MustParse("3e2d1c0b-4a59-4867-9564-738291a0b1c2")
+	wallets := []models.Wallet{
+		{ID: walletA, OwnerID: &ownerID, Currency: "RUB", Balance: 1250, Status: models.StatusActive, MaxBalance: &limit,
+			Labels: map[string]string{"team": "payments"}, Metadata: json.RawMessage(`{"crm":{"account":"A-1"}}`)},
+		{ID: walletB, OwnerID: &ownerID, Currency: "BHD", Balance: 7, Status: models.StatusFrozen, MaxBalance: &defaultLimit,
+			Labels: map[string]string{}, Metadata: json.RawMessage(`{}`)},
+		{ID: walletC, Currency: "RUB", Balance: 0, Status: models.StatusActive},
+	}
+	want := []models.ImportedWallet{
+		{Line: 2, ID: walletA, OwnerID: ownerID, Currency: "RUB", Balance: 1250, Status: models.StatusActive, MaxBalance: &limit,
+			Labels: map[string]string{"team": "payments"}, Metadata: json.RawMessage(`{"crm":{"account":"A-1"}}`)},
+		{Line: 3, ID: walletB, OwnerID: ownerID, Currency: "BHD", Balance: 7, Status: models.StatusFrozen},
+		{Line: 4, ID: walletC, OwnerID: legacyOwner, Currency: "RUB", Balance: 0, Status: models.StatusActive},
+	}
+
+	for _, format := range []models.BulkFormat{models.BulkCSV, models.BulkJSONL} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			writer, err := NewWriter(&buf, format)
+			require.NoError(t, err)
+			for i := range wallets {
+				require.NoError(t, writer.Write(&wallets[i]))
+			}
+			require.NoError(t, writer.Close())
+
+			// Без владельца для старых кошельков запись без ownerId
+			// отклоняется, а не загружается с чужим владельцем.
+			_, errs := readAll(t, buf.String(), format)
+			require.Len(t, errs, 1)
+			assert.Equal(t, models.CodeInvalidOwnerID, errs[0].Code)
+			assert.Equal(t, &walletC, errs[0].WalletID)
+
+			imported, errs := readAll(t, buf.String(), format, WithLegacyOwner(legacyOwner))
+			assert.Empty(t, errs)
+			if format == models.BulkJSONL {
+				for i := range want {
+					want[i].Line--
+				}
+			}
+			assert.Equal(t, want, imported)
+
+			totals := writer.Totals()
+			require.Len(t, totals, 2)
+			assert.Equal(t, models.Currency("BHD"), totals[0].Currency)
+			assert.Equal(t, "0.007", totals[0].BalanceDecimal)
+			assert.Equal(t, "12.50", totals[1].BalanceDecimal)
+		})
+	}
+}
+
+func TestWriter_EmptyCSV(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf, models.BulkCSV)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	assert.Equal(t, "wallet_id,owner_id,currency,balance,status,max_balance,labels,metadata\n", buf.String())
+}
@@ -0,0 +1,372 @@
+// Package bulk читает и пишет файлы массового импорта и экспорта кошельков
+// в CSV и JSON Lines.
+//
+// Оба формата содержат одни и те же поля: walletId, ownerId, currency,
+// balance — десятичная строка в основных единицах валюты, — status,
+// maxBalance, labels и metadata, так что файл экспорта можно загрузить
+// обратно без потерь. Пустой ownerId бывает только у кошельков, созданных
+// до появления владельцев; такие записи импорт принимает лишь с
+// WithLegacyOwner. Reader реализует
+// repository.WalletSource, Writer — repository.WalletSink; записи
+// читаются и пишутся по одной, без накопления в памяти.
+package bulk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// MaxRecordBytes — наибольшая длина записи JSON Lines.
+const MaxRecordBytes = 64 << 10
+
+// csvColumns — колонки CSV в порядке экспорта. При импорте порядок берётся
+// из заголовка, а колонки после balance можно опустить. Метки и метаданные
+// записываются в CSV JSON-объектами.
+var csvColumns = []string{"wallet_id", "owner_id", "currency", "balance", "status", "max_balance", "labels", "metadata"}
+
+// record — запись файла до проверки.
+type record struct {
+	line       int64
+	walletID   string
+	ownerID    string
+	currency   string
+	balance    string
+	status     string
+	maxBalance string
+	labels     string
+	metadata   string
+}
+
+// jsonRecord — запись JSON Lines. Баланс и лимит можно передать и строкой,
+// и числом: json.Number сохраняет текст без потери точности.
+type jsonRecord struct {
+	WalletID   string          `json:"walletId"`
+	OwnerID    string          `json:"ownerId"`
+	Currency   string          `json:"currency"`
+	Balance    json.Number     `json:"balance"`
+	Status     string          `json:"status"`
+	MaxBalance json.Number     `json:"maxBalance"`
+	Labels     json.RawMessage `json:"labels"`
+	Metadata   json.RawMessage `json:"metadata"`
+}
+
+// Reader читает кошельки из файла импорта и проверяет каждую запись.
+type Reader struct {
+	read        func() (*record, error)
+	done        bool
+	legacyOwner uuid.UUID
+}
+
+// Option настраивает Reader.
+type Option func(*Reader)
+
+// WithLegacyOwner назначает владельца ownerID записям с пустым ownerId,
+// которые экспорт пишет для кошельков, созданных до появления владельцев.
+// Без этой опции такие записи отклоняются с INVALID_OWNER_ID: база не
+// принимает новый кошелёк без владельца.
+func WithLegacyOwner(ownerID uuid.UUID) Option {
+	return func(r *Reader) {
+		r.legacyOwner = ownerID
+	}
+}
+
+// NewReader возвращает Reader, читающий in в формате format.
+func NewReader(in io.Reader, format models.BulkFormat, opts ...Option) (*Reader, error) {
+	r := &Reader{}
+	switch format {
+	case models.BulkCSV:
+		r.read = csvReader(in)
+	case models.BulkJSONL:
+		r.read = jsonReader(in)
+	default:
+		return nil, models.ErrInvalidFormat
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// Next возвращает следующую проверенную запись. Неверная запись
+// возвращается как *models.ImportError, после которой чтение можно
+// продолжить; конец файла — io.EOF. Если запись нельзя отделить от
+// следующих, например из-за незакрытой кавычки, после её ошибки Next
+// возвращает io.EOF.
+func (r *Reader) Next() (*models.ImportedWallet, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+	rec, err := r.read()
+	var importErr *models.ImportError
+	if errors.As(err, &importErr) && importErr.Code == models.CodeInvalidFormat {
+		r.done = true
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rec.validate(r.legacyOwner)
+}
+
+func csvReader(in io.Reader) func() (*record, error) {
+	cr := csv.NewReader(in)
+	cr.ReuseRecord = true
+	var columns map[string]int
+
+	return func() (*record, error) {
+		if columns == nil {
+			header, err := cr.Read()
+			if errors.Is(err, io.EOF) {
+				return nil, fatal(1, "file is empty, a header is required")
+			}
+			if err != nil {
+				return nil, csvError(err)
+			}
+			if columns, err = csvHeader(header); err != nil {
+				return nil, err
+			}
+		}
+
+		fields, err := cr.Read()
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, csvError(err)
+		}
+		line, _ := cr.FieldPos(0)
+		if err != nil {
+			return nil, invalid(int64(line), models.CodeInvalidRecord, "record has %d fields, header has %d", len(fields), len(columns))
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return fields[i]
+			}
+			return ""
+		}
+		return &record{
+			line:       int64(line),
+			walletID:   field("wallet_id"),
+			ownerID:    field("owner_id"),
+			currency:   field("currency"),
+			balance:    field("balance"),
+			status:     field("status"),
+			maxBalance: field("max_balance"),
+			labels:     field("labels"),
+			metadata:   field("metadata"),
+		}, nil
+	}
+}
+
+// csvHeader сопоставляет колонки заголовка с их номерами.
+func csvHeader(header []string) (map[string]int, error) {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		known := false
+		for _, column := range csvColumns {
+			known = known || name == column
+		}
+		if !known {
+			return nil, fatal(1, "unknown column %q, expected %v", name, csvColumns)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fatal(1, "column %q repeats", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range csvColumns[:4] {
+		if _, ok := columns[name]; !ok {
+			return nil, fatal(1, "column %q is missing", name)
+		}
+	}
+	return columns, nil
+}
+
+// csvError превращает синтаксическую ошибку CSV в ошибку записи, после
+// которой файл дальше не читается.
+func csvError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return fatal(int64(parseErr.StartLine), "%v", parseErr.Err)
+	}
+	return err
+}
+
+func jsonReader(in io.Reader) func() (*record, error) {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 4096), MaxRecordBytes)
+	var line int64
+
+	return func() (*record, error) {
+		for scanner.Scan() {
+			line++
+			data := bytes.TrimSpace(scanner.Bytes())
+			if len(data) == 0 {
+				continue
+			}
+
+			var rec jsonRecord
+			dec := json.NewDecoder(bytes.NewReader(data))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&rec); err != nil {
+				return nil, invalid(line, models.CodeInvalidRecord, "invalid JSON: %v", err)
+			}
+			if dec.InputOffset() != int64(len(data)) {
+				return nil, invalid(line, models.CodeInvalidRecord, "unexpected data after the JSON object")
+			}
+			return &record{
+				line:       line,
+				walletID:   rec.WalletID,
+				ownerID:    rec.OwnerID,
+				currency:   rec.Currency,
+				balance:    rec.Balance.String(),
+				status:     rec.Status,
+				maxBalance: rec.MaxBalance.String(),
+				labels:     string(rec.Labels),
+				metadata:   string(rec.Metadata),
+			}, nil
+		}
+		if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+			return nil, fatal(line+1, "record exceeds %d bytes", MaxRecordBytes)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+}
+
+// validate проверяет поля записи. Баланс и лимит разбираются по экспоненте
+// валюты записи, поэтому валюта проверяется раньше них. Пустой ownerId
+// заменяется на legacyOwner, если он задан.
+func (r *record) validate(legacyOwner uuid.UUID) (*models.ImportedWallet, error) {
+	walletID, err := uuid.Parse(r.walletID)
+	if err != nil || walletID == uuid.Nil {
+		return nil, invalid(r.line, models.CodeInvalidWalletID, "walletId must be a non-nil UUID")
+	}
+	fail := func(code models.Code, format string, args ...any) error {
+		importErr := invalid(r.line, code, format, args...)
+		importErr.WalletID = &walletID
+		return importErr
+	}
+
+	if r.ownerID == "" && legacyOwner == uuid.Nil {
+		return nil, fail(models.CodeInvalidOwnerID, "ownerId is empty: the wallet predates owners, import it with a legacy owner")
+	}
+	ownerID := legacyOwner
+	if r.ownerID != "" {
+		ownerID, err = uuid.Parse(r.ownerID)
+	}
+	if err != nil || ownerID == uuid.Nil {
+		return nil, fail(models.CodeInvalidOwnerID, "ownerId must be a non-nil UUID")
+	}
+	currency := models.Currency(r.currency)
+	if !currency.Valid() {
+		return nil, fail(models.CodeInvalidCurrency, "currency %q is not supported", r.currency)
+	}
+	balance, err := parseBalance(r.balance, currency.Exponent())
+	if err != nil {
+		return nil, fail(errorCode(err), "balance %q must be a non-negative decimal with at most %d decimal places, at most 10^18 minor units", r.balance, currency.Exponent())
+	}
+	var maxBalance *int64
+	if r.maxBalance != "" {
+		limit, err := parseBalance(r.maxBalance, currency.Exponent())
+		if err != nil {
+			return nil, fail(models.CodeInvalidBalanceLimit, "maxBalance %q must be a non-negative decimal with at most %d decimal places, at most 10^18 minor units", r.maxBalance, currency.Exponent())
+		}
+		if balance > limit {
+			return nil, fail(models.CodeBalanceLimitExceeded, "balance %q exceeds maxBalance %q", r.balance, r.maxBalance)
+		}
+		maxBalance = &limit
+	}
+	labels, metadata, err := parseMetadata(r.labels, r.metadata)
+	if err != nil {
+		return nil, fail(errorCode(err), "%v", err)
+	}
+
+	status := models.WalletStatus(r.status)
+	switch status {
+	case "":
+		status = models.StatusActive
+	case models.StatusActive, models.StatusFrozen:
+	default:
+		return nil, fail(models.CodeInvalidRecord, "status must be ACTIVE or FROZEN")
+	}
+
+	return &models.ImportedWallet{
+		Line:       r.line,
+		ID:         walletID,
+		OwnerID:    ownerID,
+		Currency:   currency,
+		Balance:    balance,
+		Status:     status,
+		MaxBalance: maxBalance,
+		Labels:     labels,
+		Metadata:   metadata,
+	}, nil
+}
+
+// parseBalance разбирает баланс или лимит, как models.ParseAmount, но
+// допускает и сам models.MaxBalance: у суммы операции на цифру меньше, а
+// баланс кошелька может дойти до лимита и должен загружаться обратно.
+func parseBalance(s string, exponent int) (int64, error) {
+	amount, err := models.ParseAmount(s, exponent)
+	if errors.Is(err, models.ErrAmountTooLarge) {
+		limit := big.NewRat(models.MaxBalance, int64(math.Pow10(exponent)))
+		if value, ok := new(big.Rat).SetString(s); ok && value.Cmp(limit) == 0 {
+			return models.MaxBalance, nil
+		}
+	}
+	return amount, err
+}
+
+// parseMetadata разбирает метки и метаданные записи и проверяет их, как
+// административный API. Пустые значения остаются nil.
+func parseMetadata(rawLabels, rawMetadata string) (map[string]string, json.RawMessage, error) {
+	var labels map[string]string
+	if rawLabels != "" && json.Unmarshal([]byte(rawLabels), &labels) != nil {
+		return nil, nil, models.ErrInvalidLabel
+	}
+	if len(labels) == 0 {
+		labels = nil
+	}
+	var metadata json.RawMessage
+	if trimmed := bytes.TrimSpace([]byte(rawMetadata)); len(trimmed) > 0 && !bytes.Equal(trimmed, []byte("null")) {
+		metadata = trimmed
+	}
+
+	request := models.WalletMetadataRequest{Labels: labels, Metadata: metadata}
+	if err := request.Validate(); err != nil {
+		if errors.Is(err, models.ErrInvalidLabel) {
+			return nil, nil, models.ErrInvalidLabel
+		}
+		return nil, nil, models.ErrInvalidMetadata
+	}
+	return labels, metadata, nil
+}
+
+// errorCode возвращает код ошибки модели err, а для прочих ошибок —
+// INVALID_AMOUNT.
+func errorCode(err error) models.Code {
+	var modelErr *models.Error
+	if errors.As(err, &modelErr) {
+		return modelErr.Code
+	}
+	return models.CodeInvalidAmount
+}
+
+func invalid(line int64, code models.Code, format string, args ...any) *models.ImportError {
+	return &models.ImportError{Line: line, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// fatal — ошибка, после которой файл дальше не читается. Её код —
+// INVALID_FORMAT, чтобы Next мог отличить её от ошибки одной записи.
+func fatal(line int64, format string, args ...any) *models.ImportError {
+	return invalid(line, models.CodeInvalidFormat, format, args...)
+}
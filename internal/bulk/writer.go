@@ -0,0 +1,133 @@
+package bulk
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math/big"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// exportRecord — запись JSON Lines экспорта, та же, что читает импорт.
+// Лимит по умолчанию, пустые метки и метаданные не пишутся.
+type exportRecord struct {
+	WalletID   uuid.UUID           `json:"walletId"`
+	OwnerID    *uuid.UUID          `json:"ownerId,omitempty"`
+	Currency   models.Currency     `json:"currency"`
+	Balance    string              `json:"balance"`
+	Status     models.WalletStatus `json:"status"`
+	MaxBalance string              `json:"maxBalance,omitempty"`
+	Labels     map[string]string   `json:"labels,omitempty"`
+	Metadata   json.RawMessage     `json:"metadata,omitempty"`
+}
+
+// Writer пишет кошельки экспорта и подводит итоги по валютам для сверки с
+// отчётом импорта.
+type Writer struct {
+	encode func(rec *exportRecord) error
+	flush  func() error
+	totals models.CurrencyTotals
+
+	started bool
+}
+
+// NewWriter возвращает Writer, пишущий в out в формате format.
+func NewWriter(out io.Writer, format models.BulkFormat) (*Writer, error) {
+	w := &Writer{totals: make(models.CurrencyTotals)}
+	switch format {
+	case models.BulkCSV:
+		cw := csv.NewWriter(out)
+		header := false
+		writeHeader := func() error {
+			if header {
+				return nil
+			}
+			header = true
+			return cw.Write(csvColumns)
+		}
+		w.encode = func(rec *exportRecord) error {
+			if err := writeHeader(); err != nil {
+				return err
+			}
+			ownerID := ""
+			if rec.OwnerID != nil {
+				ownerID = rec.OwnerID.String()
+			}
+			labels := ""
+			if rec.Labels != nil {
+				data, err := json.Marshal(rec.Labels)
+				if err != nil {
+					return err
+				}
+				labels = string(data)
+			}
+			return cw.Write([]string{rec.WalletID.String(), ownerID, string(rec.Currency), rec.Balance, string(rec.Status), rec.MaxBalance, labels, string(rec.Metadata)})
+		}
+		w.flush = func() error {
+			if err := writeHeader(); err != nil {
+				return err
+			}
+			cw.Flush()
+			return cw.Error()
+		}
+	case models.BulkJSONL:
+		enc := json.NewEncoder(out)
+		w.encode = func(rec *exportRecord) error { return enc.Encode(rec) }
+		w.flush = func() error { return nil }
+	default:
+		return nil, models.ErrInvalidFormat
+	}
+	return w, nil
+}
+
+// ContentType возвращает MIME-тип файла в формате format.
+func ContentType(format models.BulkFormat) string {
+	if format == models.BulkJSONL {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Started сообщает, начата ли запись: после этого ошибку уже нельзя
+// вернуть вместо файла.
+func (w *Writer) Started() bool {
+	return w.started
+}
+
+func (w *Writer) Write(wallet *models.Wallet) error {
+	w.started = true
+	w.totals.Add(wallet.Currency, 1, big.NewInt(wallet.Balance))
+	rec := &exportRecord{
+		WalletID: wallet.ID,
+		OwnerID:  wallet.OwnerID,
+		Currency: wallet.Currency,
+		Balance:  models.FormatAmount(wallet.Balance, wallet.Currency.Exponent()),
+		Status:   wallet.Status,
+	}
+	// Лимит по умолчанию не пишется: импорт назначит его сам.
+	if wallet.MaxBalance != nil && *wallet.MaxBalance != models.MaxBalance {
+		rec.MaxBalance = models.FormatAmount(*wallet.MaxBalance, wallet.Currency.Exponent())
+	}
+	if len(wallet.Labels) > 0 {
+		rec.Labels = wallet.Labels
+	}
+	if metadata := bytes.TrimSpace(wallet.Metadata); len(metadata) > 0 && !bytes.Equal(metadata, []byte("{}")) && !bytes.Equal(metadata, []byte("null")) {
+		rec.Metadata = metadata
+	}
+	return w.encode(rec)
+}
+
+// Close сбрасывает буфер. Пустой CSV-файл всё равно получает заголовок.
+func (w *Writer) Close() error {
+	w.started = true
+	return w.flush()
+}
+
+// Totals возвращает число кошельков и сумму балансов по валютам среди
+// записанных.
+func (w *Writer) Totals() []models.CurrencyTotal {
+	return w.totals.List()
+}
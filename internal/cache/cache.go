@@ -0,0 +1,21 @@
+// Package cache содержит кэш снимков кошельков для чтения баланса.
+package cache
+
+import (
+	"context"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// Cache хранит снимки кошельков. Реализация может быть локальной (LRU) или
+// внешней; она сама отвечает за то, чтобы не отдавать записи старше
+// допустимого возраста. Методы безопасны для конкурентного вызова;
+// ошибки внешнего хранилища реализация превращает в промах.
+type Cache interface {
+	Get(ctx context.Context, walletID uuid.UUID) (models.Wallet, bool)
+	Set(ctx context.Context, wallet models.Wallet)
+	Delete(ctx context.Context, walletID uuid.UUID)
+	// Purge удаляет все записи.
+	Purge(ctx context.Context)
+}
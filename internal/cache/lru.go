@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+)
+
+type lruEntry struct {
+	wallet   models.Wallet
+	storedAt time.Time
+}
+
+// LRU — кэш в памяти процесса на size записей с вытеснением давно не
+// читанных. Записи старше maxAge считаются промахом.
+type LRU struct {
+	size   int
+	maxAge time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[uuid.UUID]*list.Element
+}
+
+func NewLRU(size int, maxAge time.Duration) *LRU {
+	return &LRU{
+		size:    size,
+		maxAge:  maxAge,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[uuid.UUID]*list.Element, size),
+	}
+}
+
+func (c *LRU) Get(_ context.Context, walletID uuid.UUID) (models.Wallet, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[walletID]
+	if !ok {
+		return models.Wallet{}, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if c.now().Sub(entry.storedAt) > c.maxAge {
+		c.remove(elem)
+		return models.Wallet{}, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.wallet, true
+}
+
+func (c *LRU) Set(_ context.Context, wallet models.Wallet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &lruEntry{wallet: wallet, storedAt: c.now()}
+	if elem, ok := c.entries[wallet.ID]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[wallet.ID] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *LRU) Delete(_ context.Context, walletID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[walletID]; ok {
+		c.remove(elem)
+	}
+}
+
+func (c *LRU) Purge(context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.entries = make(map[uuid.UUID]*list.Element, c.size)
+}
+
+// Len возвращает число записей, включая ещё не удалённые устаревшие.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry).wallet.ID)
+}
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU_GetSet(t *testing.T) {
+	c := NewLRU(2, time.Minute)
+	ctx := context.Background()
+	wallet := models.Wallet{ID: uuid.New(), Balance: 100, Version: 3}
+
+	_, ok := c.Get(ctx, wallet.ID)
+	assert.False(t, ok)
+
+	c.Set(ctx, wallet)
+	got, ok := c.Get(ctx, wallet.ID)
+	assert.True(t, ok)
+	assert.Equal(t, wallet, got)
+
+	wallet.Balance = 200
+	c.Set(ctx, wallet)
+	got, _ = c.Get(ctx, wallet.ID)
+	assert.Equal(t, int64(200), got.Balance)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU(2, time.Minute)
+	ctx := context.Background()
+	a, b, d := uuid.New(), uuid.New(), uuid.New()
+
+	c.Set(ctx, models.Wallet{ID: a})
+	c.Set(ctx, models.Wallet{ID: b})
+	c.Get(ctx, a)
+	c.Set(ctx, models.Wallet{ID: d})
+
+	_, ok := c.Get(ctx, b)
+	assert.False(t, ok)
+	_, ok = c.Get(ctx, a)
+	assert.True(t, ok)
+	_, ok = c.Get(ctx, d)
+	assert.True(t, ok)
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestLRU_MaxAge(t *testing.T) {
+	c := NewLRU(10, time.Second)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+	id := uuid.New()
+
+	c.Set(ctx, models.Wallet{ID: id})
+	now = now.Add(time.Second)
+	_, ok := c.Get(ctx, id)
+	assert.True(t, ok)
+
+	now = now.Add(time.Millisecond)
+	_, ok = c.Get(ctx, id)
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestLRU_DeleteAndPurge(t *testing.T) {
+	c := NewLRU(10, time.Minute)
+	ctx := context.Background()
+	a, b := uuid.New(), uuid.New()
+
+	c.Set(ctx, models.Wallet{ID: a})
+	c.Set(ctx, models.Wallet{ID: b})
+
+	c.Delete(ctx, a)
+	_, ok := c.Get(ctx, a)
+	assert.False(t, ok)
+	_, ok = c.Get(ctx, b)
+	assert.True(t, ok)
+
+	c.Purge(ctx)
+	_, ok = c.Get(ctx, b)
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
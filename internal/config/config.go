@@ -1,15 +1,40 @@
 package config
 
 import (
+	"database/sql"
 	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+
+	UpdateLocking     = "locking"
+	UpdateConditional = "conditional"
+
+	DriverPQ  = "pq"
+	DriverPgx = "pgx"
+
+	RateLimitMemory   = "memory"
+	RateLimitPostgres = "postgres"
+)
+
+var isolationLevels = map[string]sql.IsolationLevel{
+	"read_committed":  sql.LevelReadCommitted,
+	"repeatable_read": sql.LevelRepeatableRead,
+	"serializable":    sql.LevelSerializable,
+}
+
 type Config struct {
+	StorageDriver  string
+
 	DBHost         string
 	DBPort         int
 	DBExternalPort int
@@ -17,11 +42,92 @@ type Config struct {
 	DBPassword     string
 	DBName         string
 	DBSSLMode      string
+	AutoMigrate    bool
+	WalletSharding bool
+	UpdateStrategy string
+	DBDriver       string
+
+	DBMaxOpenConns          int
+	DBMaxIdleConns          int
+	DBMinConns              int
+	DBConnMaxLifetime       time.Duration
+	DBConnMaxIdleTime       time.Duration
+	DBPoolHealthCheckPeriod time.Duration
+
+	TxIsolation      string
+	TxMaxAttempts    int
+	TxRetryBaseDelay time.Duration
+	TxRetryMaxDelay  time.Duration
+
+	DBLockTimeout      time.Duration
+	DBStatementTimeout time.Duration
+
+	ReplicaDSNs          []string
+	ReplicaMaxLag        time.Duration
+	ReplicaCheckInterval time.Duration
 	
 	ServerPort     int
 	ServerHost     string
+
+	ServerReadHeaderTimeout time.Duration
+	ServerReadTimeout       time.Duration
+	ServerWriteTimeout      time.Duration
+	ServerIdleTimeout       time.Duration
+	RequestReadTimeout      time.Duration
+	RequestWriteTimeout     time.Duration
+	// StatementTimeout — дедлайн выписки. Он может быть больше
+	// SERVER_WRITE_TIMEOUT: выписка продлевает срок записи ответа.
+	StatementTimeout time.Duration
 	
 	LogLevel       string
+
+	HealthCheckTimeout    time.Duration
+	PoolSaturationPercent int
+	ShutdownDrainDelay    time.Duration
+
+	BatchEnabled bool
+	BatchWindow  time.Duration
+	BatchMaxSize int
+
+	CacheEnabled      bool
+	CacheSize         int
+	CacheMaxStaleness time.Duration
+
+	DBMaxInFlight         int
+	DBInFlightWait        time.Duration
+	// DBMaxStatementStreams — выписки, одновременно читающие журнал, каждая
+	// в своей транзакции; 0 снимает ограничение.
+	DBMaxStatementStreams int
+
+	RateLimitEnabled     bool
+	RateLimitBackend     string
+	RateLimitTrustProxy  bool
+	RateLimitAPIKeyRPS   float64
+	RateLimitAPIKeyBurst int
+	RateLimitIPRPS       float64
+	RateLimitIPBurst     int
+	RateLimitWalletRPS   float64
+	RateLimitWalletBurst int
+
+	// CheckpointInterval — период записи контрольных точек баланса, 0
+	// отключает их. CheckpointDelay — насколько момент точки отстаёт от
+	// текущего времени: операции, начатые раньше, должны успеть
+	// зафиксироваться, поэтому он больше REQUEST_WRITE_TIMEOUT,
+	// STATEMENT_TIMEOUT и BULK_TIMEOUT.
+	CheckpointInterval time.Duration
+	CheckpointDelay    time.Duration
+
+	// ReconcileInterval — период сверки остатков кошельков с журналом
+	// операций, 0 отключает её.
+	ReconcileInterval time.Duration
+
+	// AdminToken открывает административные маршруты /api/v1/admin;
+	// пустой токен их отключает.
+	AdminToken string
+	// BulkTimeout — дедлайн импорта и экспорта кошельков, BulkMaxBytes —
+	// наибольший размер файла импорта.
+	BulkTimeout  time.Duration
+	BulkMaxBytes int64
 }
 
 func Load() (*Config, error) {
@@ -31,6 +137,8 @@ func Load() (*Config, error) {
 	}
 
 	cfg := &Config{
+		StorageDriver:  getEnv("STORAGE_DRIVER", StoragePostgres),
+
 		DBHost:         getEnv("DB_HOST", "localhost"),
 		DBPort:         getEnvAsInt("DB_PORT", 5432),
 		DBExternalPort: getEnvAsInt("DB_EXTERNAL_PORT", 5433),
@@ -38,11 +146,77 @@ func Load() (*Config, error) {
 		DBPassword:     getEnv("DB_PASSWORD", "wallet_password"),
 		DBName:         getEnv("DB_NAME", "wallet_db"),
 		DBSSLMode:      getEnv("DB_SSLMODE", "disable"),
+		AutoMigrate:    getEnvAsBool("DB_AUTO_MIGRATE", true),
+		WalletSharding: getEnvAsBool("WALLET_SHARDING", false),
+		UpdateStrategy: getEnv("DB_UPDATE_STRATEGY", UpdateLocking),
+		DBDriver:       getEnv("DB_DRIVER", DriverPQ),
+
+		DBMaxOpenConns:          getEnvAsInt("DB_MAX_OPEN_CONNS", 25),
+		DBMaxIdleConns:          getEnvAsInt("DB_MAX_IDLE_CONNS", 25),
+		DBMinConns:              getEnvAsInt("DB_MIN_CONNS", 0),
+		DBConnMaxLifetime:       getEnvAsDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
+		DBConnMaxIdleTime:       getEnvAsDuration("DB_CONN_MAX_IDLE_TIME", 30*time.Minute),
+		DBPoolHealthCheckPeriod: getEnvAsDuration("DB_POOL_HEALTH_CHECK_PERIOD", time.Minute),
+
+		TxIsolation:      getEnv("DB_TX_ISOLATION", "read_committed"),
+		TxMaxAttempts:    getEnvAsInt("DB_TX_MAX_ATTEMPTS", 5),
+		TxRetryBaseDelay: getEnvAsDuration("DB_TX_RETRY_BASE_DELAY", 5*time.Millisecond),
+		TxRetryMaxDelay:  getEnvAsDuration("DB_TX_RETRY_MAX_DELAY", 200*time.Millisecond),
+
+		DBLockTimeout:      getEnvAsDuration("DB_LOCK_TIMEOUT", time.Second),
+		DBStatementTimeout: getEnvAsDuration("DB_STATEMENT_TIMEOUT", 2*time.Second),
+
+		ReplicaDSNs:          getEnvAsList("DB_REPLICA_DSNS", ";"),
+		ReplicaMaxLag:        getEnvAsDuration("DB_REPLICA_MAX_LAG", time.Second),
+		ReplicaCheckInterval: getEnvAsDuration("DB_REPLICA_CHECK_INTERVAL", time.Second),
 		
 		ServerPort:     getEnvAsInt("SERVER_PORT", 8080),
 		ServerHost:     getEnv("SERVER_HOST", "0.0.0.0"),
+
+		ServerReadHeaderTimeout: getEnvAsDuration("SERVER_READ_HEADER_TIMEOUT", 2*time.Second),
+		ServerReadTimeout:       getEnvAsDuration("SERVER_READ_TIMEOUT", 5*time.Second),
+		ServerWriteTimeout:      getEnvAsDuration("SERVER_WRITE_TIMEOUT", 10*time.Second),
+		ServerIdleTimeout:       getEnvAsDuration("SERVER_IDLE_TIMEOUT", time.Minute),
+		RequestReadTimeout:      getEnvAsDuration("REQUEST_READ_TIMEOUT", 2*time.Second),
+		RequestWriteTimeout:     getEnvAsDuration("REQUEST_WRITE_TIMEOUT", 5*time.Second),
+		StatementTimeout:        getEnvAsDuration("STATEMENT_TIMEOUT", 5*time.Minute),
 		
 		LogLevel:       getEnv("LOG_LEVEL", "info"),
+
+		HealthCheckTimeout:    getEnvAsDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+		PoolSaturationPercent: getEnvAsInt("DB_POOL_SATURATION_PERCENT", 90),
+		ShutdownDrainDelay:    getEnvAsDuration("SHUTDOWN_DRAIN_DELAY", 5*time.Second),
+
+		BatchEnabled: getEnvAsBool("BATCH_ENABLED", false),
+		BatchWindow:  getEnvAsDuration("BATCH_WINDOW", 2*time.Millisecond),
+		BatchMaxSize: getEnvAsInt("BATCH_MAX_SIZE", 100),
+
+		CacheEnabled:      getEnvAsBool("CACHE_ENABLED", false),
+		CacheSize:         getEnvAsInt("CACHE_SIZE", 10000),
+		CacheMaxStaleness: getEnvAsDuration("CACHE_MAX_STALENESS", time.Second),
+
+		DBMaxInFlight:         getEnvAsInt("DB_MAX_IN_FLIGHT", 0),
+		DBInFlightWait:        getEnvAsDuration("DB_IN_FLIGHT_WAIT", 50*time.Millisecond),
+		DBMaxStatementStreams: getEnvAsInt("DB_MAX_STATEMENT_STREAMS", 5),
+
+		RateLimitEnabled:     getEnvAsBool("RATE_LIMIT_ENABLED", false),
+		RateLimitBackend:     getEnv("RATE_LIMIT_BACKEND", RateLimitMemory),
+		RateLimitTrustProxy:  getEnvAsBool("RATE_LIMIT_TRUST_PROXY", false),
+		RateLimitAPIKeyRPS:   getEnvAsFloat("RATE_LIMIT_API_KEY_RPS", 100),
+		RateLimitAPIKeyBurst: getEnvAsInt("RATE_LIMIT_API_KEY_BURST", 200),
+		RateLimitIPRPS:       getEnvAsFloat("RATE_LIMIT_IP_RPS", 50),
+		RateLimitIPBurst:     getEnvAsInt("RATE_LIMIT_IP_BURST", 100),
+		RateLimitWalletRPS:   getEnvAsFloat("RATE_LIMIT_WALLET_RPS", 2000),
+		RateLimitWalletBurst: getEnvAsInt("RATE_LIMIT_WALLET_BURST", 4000),
+
+		CheckpointInterval: getEnvAsDuration("CHECKPOINT_INTERVAL", time.Hour),
+		CheckpointDelay:    getEnvAsDuration("CHECKPOINT_DELAY", 45*time.Minute),
+
+		ReconcileInterval: getEnvAsDuration("RECONCILE_INTERVAL", 24*time.Hour),
+
+		AdminToken:   getEnv("ADMIN_TOKEN", ""),
+		BulkTimeout:  getEnvAsDuration("BULK_TIMEOUT", 30*time.Minute),
+		BulkMaxBytes: int64(getEnvAsInt("BULK_MAX_BYTES", 1<<30)),
 	}
 
 	if err := cfg.validate(); err != nil {
@@ -68,11 +242,88 @@ func (c *Config) GetDBConnectionString() string {
 	)
 }
 
+// GetTxIsolation возвращает уровень изоляции транзакций из DB_TX_ISOLATION.
+func (c *Config) GetTxIsolation() sql.IsolationLevel {
+	return isolationLevels[c.TxIsolation]
+}
+
 func (c *Config) GetServerAddress() string {
 	return fmt.Sprintf("%s:%d", c.ServerHost, c.ServerPort)
 }
 
 func (c *Config) validate() error {
+	if c.StorageDriver != StoragePostgres && c.StorageDriver != StorageMemory {
+		return fmt.Errorf("STORAGE_DRIVER must be %q or %q", StoragePostgres, StorageMemory)
+	}
+
+	if c.UpdateStrategy != UpdateLocking && c.UpdateStrategy != UpdateConditional {
+		return fmt.Errorf("DB_UPDATE_STRATEGY must be %q or %q", UpdateLocking, UpdateConditional)
+	}
+
+	if c.DBDriver != DriverPQ && c.DBDriver != DriverPgx {
+		return fmt.Errorf("DB_DRIVER must be %q or %q", DriverPQ, DriverPgx)
+	}
+
+	if c.DBDriver == DriverPgx {
+		var unsupported []string
+		if c.WalletSharding {
+			unsupported = append(unsupported, "WALLET_SHARDING")
+		}
+		if c.UpdateStrategy != UpdateLocking {
+			unsupported = append(unsupported, "DB_UPDATE_STRATEGY="+c.UpdateStrategy)
+		}
+		if len(c.ReplicaDSNs) > 0 {
+			unsupported = append(unsupported, "DB_REPLICA_DSNS")
+		}
+		if len(unsupported) > 0 {
+			return fmt.Errorf("DB_DRIVER=%s does not support %s", DriverPgx, strings.Join(unsupported, ", "))
+		}
+	}
+
+	if c.DBMaxOpenConns <= 0 {
+		return fmt.Errorf("DB_MAX_OPEN_CONNS must be positive")
+	}
+
+	if c.DBMaxIdleConns < 0 || c.DBMaxIdleConns > c.DBMaxOpenConns {
+		return fmt.Errorf("DB_MAX_IDLE_CONNS must be between 0 and DB_MAX_OPEN_CONNS")
+	}
+
+	if c.DBMinConns < 0 || c.DBMinConns > c.DBMaxOpenConns {
+		return fmt.Errorf("DB_MIN_CONNS must be between 0 and DB_MAX_OPEN_CONNS")
+	}
+
+	if c.DBConnMaxLifetime < 0 || c.DBConnMaxIdleTime < 0 {
+		return fmt.Errorf("DB_CONN_MAX_LIFETIME and DB_CONN_MAX_IDLE_TIME cannot be negative")
+	}
+
+	if c.DBPoolHealthCheckPeriod <= 0 {
+		return fmt.Errorf("DB_POOL_HEALTH_CHECK_PERIOD must be positive")
+	}
+
+	if _, ok := isolationLevels[c.TxIsolation]; !ok {
+		return fmt.Errorf("DB_TX_ISOLATION must be read_committed, repeatable_read or serializable")
+	}
+
+	if c.TxMaxAttempts <= 0 {
+		return fmt.Errorf("DB_TX_MAX_ATTEMPTS must be positive")
+	}
+
+	if c.TxRetryBaseDelay < 0 || c.TxRetryMaxDelay < c.TxRetryBaseDelay {
+		return fmt.Errorf("DB_TX_RETRY_BASE_DELAY must be non-negative and not greater than DB_TX_RETRY_MAX_DELAY")
+	}
+
+	if c.DBLockTimeout < 0 || c.DBStatementTimeout < 0 {
+		return fmt.Errorf("DB_LOCK_TIMEOUT and DB_STATEMENT_TIMEOUT cannot be negative")
+	}
+
+	if c.ReplicaMaxLag < 0 {
+		return fmt.Errorf("DB_REPLICA_MAX_LAG cannot be negative")
+	}
+
+	if c.ReplicaCheckInterval <= 0 {
+		return fmt.Errorf("DB_REPLICA_CHECK_INTERVAL must be positive")
+	}
+
 	if c.DBHost == "" {
 		return fmt.Errorf("DB_HOST cannot be empty")
 	}
@@ -104,6 +355,110 @@ func (c *Config) validate() error {
 	if c.ServerHost == "" {
 		return fmt.Errorf("SERVER_HOST cannot be empty")
 	}
+
+	if c.ServerReadHeaderTimeout < 0 || c.ServerReadTimeout < 0 || c.ServerWriteTimeout < 0 || c.ServerIdleTimeout < 0 {
+		return fmt.Errorf("SERVER_*_TIMEOUT cannot be negative")
+	}
+
+	if c.RequestReadTimeout < 0 || c.RequestWriteTimeout < 0 {
+		return fmt.Errorf("REQUEST_READ_TIMEOUT and REQUEST_WRITE_TIMEOUT cannot be negative")
+	}
+
+	// Дедлайн операции должен истечь раньше, чем сервер оборвёт запись
+	// ответа: иначе клиент вместо 504 получит разорванное соединение.
+	if c.ServerWriteTimeout > 0 && (c.RequestReadTimeout >= c.ServerWriteTimeout || c.RequestWriteTimeout >= c.ServerWriteTimeout) {
+		return fmt.Errorf("REQUEST_READ_TIMEOUT and REQUEST_WRITE_TIMEOUT must be less than SERVER_WRITE_TIMEOUT")
+	}
+
+	if c.HealthCheckTimeout <= 0 {
+		return fmt.Errorf("HEALTH_CHECK_TIMEOUT must be positive")
+	}
+
+	if c.PoolSaturationPercent <= 0 || c.PoolSaturationPercent > 100 {
+		return fmt.Errorf("DB_POOL_SATURATION_PERCENT must be between 1 and 100")
+	}
+
+	if c.ShutdownDrainDelay < 0 {
+		return fmt.Errorf("SHUTDOWN_DRAIN_DELAY cannot be negative")
+	}
+
+	if c.BatchWindow <= 0 {
+		return fmt.Errorf("BATCH_WINDOW must be positive")
+	}
+
+	if c.BatchMaxSize <= 0 {
+		return fmt.Errorf("BATCH_MAX_SIZE must be positive")
+	}
+
+	if c.DBMaxInFlight < 0 {
+		return fmt.Errorf("DB_MAX_IN_FLIGHT cannot be negative")
+	}
+
+	if c.DBInFlightWait < 0 {
+		return fmt.Errorf("DB_IN_FLIGHT_WAIT cannot be negative")
+	}
+
+	if c.DBMaxStatementStreams < 0 || c.DBMaxStatementStreams >= c.DBMaxOpenConns {
+		return fmt.Errorf("DB_MAX_STATEMENT_STREAMS must be between 0 and DB_MAX_OPEN_CONNS - 1")
+	}
+
+	if c.RateLimitBackend != RateLimitMemory && c.RateLimitBackend != RateLimitPostgres {
+		return fmt.Errorf("RATE_LIMIT_BACKEND must be %q or %q", RateLimitMemory, RateLimitPostgres)
+	}
+
+	if c.RateLimitEnabled && c.RateLimitBackend == RateLimitPostgres && c.StorageDriver != StoragePostgres {
+		return fmt.Errorf("RATE_LIMIT_BACKEND=%s requires STORAGE_DRIVER=%s", RateLimitPostgres, StoragePostgres)
+	}
+
+	if c.RateLimitAPIKeyRPS < 0 || c.RateLimitIPRPS < 0 || c.RateLimitWalletRPS < 0 {
+		return fmt.Errorf("RATE_LIMIT_*_RPS cannot be negative")
+	}
+
+	if c.RateLimitAPIKeyBurst < 0 || c.RateLimitIPBurst < 0 || c.RateLimitWalletBurst < 0 {
+		return fmt.Errorf("RATE_LIMIT_*_BURST cannot be negative")
+	}
+
+	if c.StatementTimeout <= 0 {
+		return fmt.Errorf("STATEMENT_TIMEOUT must be positive")
+	}
+
+	if c.BulkTimeout <= 0 || c.BulkMaxBytes <= 0 {
+		return fmt.Errorf("BULK_TIMEOUT and BULK_MAX_BYTES must be positive")
+	}
+
+	if c.ReconcileInterval < 0 {
+		return fmt.Errorf("RECONCILE_INTERVAL cannot be negative")
+	}
+
+	if c.CheckpointInterval < 0 {
+		return fmt.Errorf("CHECKPOINT_INTERVAL cannot be negative")
+	}
+
+	// Транзакции с операциями ограничены дедлайном запроса, выписки или
+	// импорта; точка должна отставать больше самого долгого из них.
+	if c.CheckpointInterval > 0 {
+		bounds := []struct {
+			name    string
+			timeout time.Duration
+		}{
+			{"REQUEST_WRITE_TIMEOUT", c.RequestWriteTimeout},
+			{"STATEMENT_TIMEOUT", c.StatementTimeout},
+			{"BULK_TIMEOUT", c.BulkTimeout},
+		}
+		for _, bound := range bounds {
+			if c.CheckpointDelay <= bound.timeout {
+				return fmt.Errorf("CHECKPOINT_DELAY must be greater than %s", bound.name)
+			}
+		}
+	}
+
+	if c.CacheSize <= 0 {
+		return fmt.Errorf("CACHE_SIZE must be positive")
+	}
+
+	if c.CacheMaxStaleness <= 0 {
+		return fmt.Errorf("CACHE_MAX_STALENESS must be positive")
+	}
 	
 	return nil
 }
@@ -135,6 +490,17 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvAsList разбивает значение по sep и отбрасывает пустые элементы.
+func getEnvAsList(key, sep string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), sep) {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 func getEnvAsInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
@@ -145,6 +511,16 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+		log.Printf("Warning: Invalid float value for %s: %s, using default: %g", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
 func getEnvAsBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
@@ -153,4 +529,14 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 		log.Printf("Warning: Invalid boolean value for %s: %s, using default: %t", key, value, defaultValue)
 	}
 	return defaultValue
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if durationValue, err := time.ParseDuration(value); err == nil {
+			return durationValue
+		}
+		log.Printf("Warning: Invalid duration value for %s: %s, using default: %s", key, value, defaultValue)
+	}
+	return defaultValue
 }
\ No newline at end of file
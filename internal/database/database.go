@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/config"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/lib/pq"
+)
+
+const pingTimeout = 5 * time.Second
+
+// Open открывает пул соединений с PostgreSQL и проверяет доступность базы.
+func Open(ctx context.Context, cfg *config.Config) (*sql.DB, error) {
+	db, err := NewPool(cfg, cfg.GetDBConnectionString())
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping database: %w", err)
+	}
+	return db, nil
+}
+
+// NewPool создаёт пул соединений по строке подключения с настройками пула
+// из cfg, не обращаясь к базе: соединения открываются при первом запросе.
+func NewPool(cfg *config.Config, dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	db.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	db.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
+	return db, nil
+}
+
+// OpenPgx открывает pgxpool с настройками пула из cfg и проверяет
+// доступность базы. afterConnect выполняется на каждом новом соединении,
+// например для подготовки запросов.
+func OpenPgx(ctx context.Context, cfg *config.Config, afterConnect func(context.Context, *pgx.Conn) error) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.GetDBConnectionString())
+	if err != nil {
+		return nil, err
+	}
+
+	poolConfig.MaxConns = int32(cfg.DBMaxOpenConns)
+	poolConfig.MinConns = int32(cfg.DBMinConns)
+	poolConfig.MaxConnLifetime = cfg.DBConnMaxLifetime
+	poolConfig.MaxConnIdleTime = cfg.DBConnMaxIdleTime
+	poolConfig.HealthCheckPeriod = cfg.DBPoolHealthCheckPeriod
+	poolConfig.AfterConnect = afterConnect
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("ping database: %w", err)
+	}
+	return pool, nil
+}
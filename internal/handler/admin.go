@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/DisasterWoman/wallet-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type AdminHandler struct {
+	service service.AdminService
+}
+
+func NewAdminHandler(service service.AdminService) *AdminHandler {
+	return &AdminHandler{service: service}
+}
+
+// AdminAuth пропускает только запросы с заголовком
+// "Authorization: Bearer <token>". Токен сравнивается за постоянное время.
+func AdminAuth(token string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				writeProblem(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "admin token is missing or invalid")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SearchWallets обрабатывает административный поиск кошельков
+// @Summary Поиск кошельков
+// @Description Ищет кошельки по меткам, статусу, диапазону баланса и дате создания. Кошельки упорядочены по времени создания; следующую страницу запрашивают с cursor из nextCursor.
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param label query []string false "Метка key:value, можно повторять" collectionFormat(multi)
+// @Param status query string false "Статус кошелька" Enums(ACTIVE, FROZEN)
+// @Param minBalance query integer false "Баланс не меньше, в минимальных единицах"
+// @Param maxBalance query integer false "Баланс не больше, в минимальных единицах"
+// @Param createdFrom query string false "Создан не раньше (RFC 3339)"
+// @Param createdTo query string false "Создан раньше (RFC 3339)"
+// @Param limit query integer false "Размер страницы, до 500" default(50)
+// @Param cursor query string false "Курсор следующей страницы"
+// @Success 200 {object} models.WalletPage "Страница кошельков с метками и метаданными"
+// @Failure 400 {object} models.Problem "Неверный фильтр: VALIDATION_FAILED или код ошибки параметра (INVALID_LABEL, INVALID_FILTER, INVALID_LIMIT); ошибки параметров — в errors"
+// @Failure 401 {object} models.Problem "Нет или неверный токен администратора: UNAUTHORIZED"
+// @Failure 503 {object} models.Problem "Сервис перегружен: SERVICE_OVERLOADED"
+// @Failure 504 {object} models.Problem "Поиск не уложился в дедлайн: TIMEOUT"
+// @Failure 500 {object} models.Problem "Внутренняя ошибка сервера: INTERNAL_ERROR"
+// @Router /api/v1/admin/wallets [get]
+func (h *AdminHandler) SearchWallets(w http.ResponseWriter, r *http.Request) {
+	filter, err := models.ParseWalletFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	page, err := h.service.SearchWallets(r.Context(), filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(page)
+}
+
+// SetWalletMetadata обрабатывает замену меток и метаданных кошелька
+// @Summary Задать метки и метаданные кошелька
+// @Description Заменяет метки и метаданные кошелька целиком. Метки — до 64 пар ключ-значение для поиска, метаданные — JSON-объект до 16 КБ. Версия кошелька не меняется.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param walletId path string true "UUID кошелька"
+// @Param request body models.WalletMetadataRequest true "Метки и метаданные"
+// @Success 200 {object} models.Wallet "Кошелёк с новыми метками и метаданными"
+// @Failure 400 {object} models.Problem "Неверный запрос: INVALID_REQUEST, VALIDATION_FAILED, INVALID_LABEL или INVALID_METADATA; ошибки полей — в errors"
+// @Failure 401 {object} models.Problem "Нет или неверный токен администратора: UNAUTHORIZED"
+// @Failure 404 {object} models.Problem "Кошелёк не найден: WALLET_NOT_FOUND"
+// @Failure 413 {object} models.Problem "Тело запроса больше 64 КБ: REQUEST_TOO_LARGE"
+// @Failure 503 {object} models.Problem "Сервис перегружен: SERVICE_OVERLOADED"
+// @Failure 504 {object} models.Problem "Операция не уложилась в дедлайн: TIMEOUT"
+// @Failure 500 {object} models.Problem "Внутренняя ошибка сервера: INTERNAL_ERROR"
+// @Router /api/v1/admin/wallets/{walletId}/metadata [put]
+func (h *AdminHandler) SetWalletMetadata(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(mux.Vars(r)["walletId"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, models.CodeInvalidRequest, "invalid wallet ID")
+		return
+	}
+
+	var req models.WalletMetadataRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	wallet, err := h.service.SetWalletMetadata(r.Context(), walletID, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(wallet)
+}
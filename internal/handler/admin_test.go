@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testAdminToken = "s3cret"
+
+type MockAdminService struct {
+	mock.Mock
+}
+
+func (m *MockAdminService) SetWalletMetadata(ctx context.Context, walletID uuid.UUID, req *models.WalletMetadataRequest) (*models.Wallet, error) {
+	args := m.Called(ctx, walletID, req)
+	wallet, _ := args.Get(0).(*models.Wallet)
+	return wallet, args.Error(1)
+}
+
+func (m *MockAdminService) SearchWallets(ctx context.Context, filter models.WalletFilter) (*models.WalletPage, error) {
+	args := m.Called(ctx, filter)
+	page, _ := args.Get(0).(*models.WalletPage)
+	return page, args.Error(1)
+}
+
+func adminRouter(handler *AdminHandler) *mux.Router {
+	router := mux.NewRouter()
+	admin := router.PathPrefix("/api/v1/admin").Subrouter()
+	admin.Use(AdminAuth(testAdminToken))
+	admin.HandleFunc("/wallets", handler.SearchWallets).Methods(http.MethodGet)
+	admin.HandleFunc("/wallets/{walletId}/metadata", handler.SetWalletMetadata).Methods(http.MethodPut)
+	return router
+}
+
+func adminRequest(router *mux.Router, method, target, body, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestAdminAuth(t *testing.T) {
+	router := adminRouter(NewAdminHandler(new(MockAdminService)))
+
+	for _, token := range []string{"", "wrong"} {
+		rr := adminRequest(router, http.MethodGet, "/api/v1/admin/wallets", "", token)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.NotEmpty(t, rr.Header().Get("WWW-Authenticate"))
+		assert.Equal(t, models.CodeUnauthorized, decodeProblem(t, rr).Code)
+	}
+}
+
+func TestAdminHandler_SearchWallets(t *testing.T) {
+	mockService := new(MockAdminService)
+	minBalance := int64(100)
+	filter := models.WalletFilter{
+		Labels:     map[string]string{"team": "payments"},
+		Status:     models.StatusActive,
+		MinBalance: &minBalance,
+		Limit:      2,
+	}
+	page := &models.WalletPage{
+		Wallets:    []models.Wallet{{ID: uuid.New(), Balance: 150, Labels: map[string]string{"team": "payments"}}},
+		NextCursor: "next",
+	}
+	mockService.On("SearchWallets", mock.Anything, filter).Return(page, nil)
+
+	rr := adminRequest(adminRouter(NewAdminHandler(mockService)), http.MethodGet,
+		"/api/v1/admin/wallets?label=team:payments&status=ACTIVE&minBalance=100&limit=2", "", testAdminToken)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response models.WalletPage
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, *page, response)
+	mockService.AssertExpectations(t)
+}
+
+func TestAdminHandler_SearchWallets_InvalidFilter(t *testing.T) {
+	mockService := new(MockAdminService)
+
+	rr := adminRequest(adminRouter(NewAdminHandler(mockService)), http.MethodGet,
+		"/api/v1/admin/wallets?status=DELETED&limit=0", "", testAdminToken)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	problem := decodeProblem(t, rr)
+	assert.Equal(t, models.CodeValidationFailed, problem.Code)
+	assert.Len(t, problem.Errors, 2)
+	mockService.AssertNotCalled(t, "SearchWallets", mock.Anything, mock.Anything)
+}
+
+func TestAdminHandler_SetWalletMetadata(t *testing.T) {
+	mockService := new(MockAdminService)
+	walletID := uuid.New()
+	wallet := &models.Wallet{ID: walletID, Labels: map[string]string{"team": "payments"}, Metadata: json.RawMessage(`{"crm":"A-1"}`)}
+	mockService.On("SetWalletMetadata", mock.Anything, walletID, mock.MatchedBy(func(req *models.WalletMetadataRequest) bool {
+		return req.Labels["team"] == "payments" && string(req.Metadata) == `{"crm":"A-1"}`
+	})).Return(wallet, nil)
+	mockService.On("SetWalletMetadata", mock.Anything, mock.Anything, mock.Anything).Return(nil, models.ErrWalletNotFound)
+	router := adminRouter(NewAdminHandler(mockService))
+
+	rr := adminRequest(router, http.MethodPut, "/api/v1/admin/wallets/"+walletID.String()+"/metadata",
+		`{"labels":{"team":"payments"},"metadata":{"crm":"A-1"}}`, testAdminToken)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response models.Wallet
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, wallet.Labels, response.Labels)
+	assert.JSONEq(t, `{"crm":"A-1"}`, string(response.Metadata))
+
+	rr = adminRequest(router, http.MethodPut, "/api/v1/admin/wallets/"+uuid.NewString()+"/metadata", `{}`, testAdminToken)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = adminRequest(router, http.MethodPut, "/api/v1/admin/wallets/not-a-uuid/metadata", `{}`, testAdminToken)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = adminRequest(router, http.MethodPut, "/api/v1/admin/wallets/"+walletID.String()+"/metadata", `{"tags":[]}`, testAdminToken)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
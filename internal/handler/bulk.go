@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/bulk"
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/DisasterWoman/wallet-service/internal/service"
+	"github.com/google/uuid"
+)
+
+type BulkHandler struct {
+	service  service.BulkService
+	maxBytes int64
+}
+
+// NewBulkHandler ограничивает файл импорта maxBytes байтами.
+func NewBulkHandler(service service.BulkService, maxBytes int64) *BulkHandler {
+	return &BulkHandler{service: service, maxBytes: maxBytes}
+}
+
+// ImportWallets обрабатывает массовый импорт кошельков
+// @Summary Импорт кошельков
+// @Description Загружает кошельки и их начальные балансы из CSV (колонки wallet_id, owner_id, currency, balance и необязательные status, max_balance, labels, metadata) или JSON Lines (поля walletId, ownerId, currency, balance, status, maxBalance, labels, metadata) командой COPY одной транзакцией. Баланс и лимит — десятичные строки в основных единицах валюты, баланс записывается в журнал операцией OPENING; метки и метаданные — JSON-объекты; недостающие владельцы создаются. Пустой ownerId экспорт пишет для кошельков, созданных до появления владельцев: такие записи принимаются, только если задан legacyOwner, иначе INVALID_OWNER_ID. Если есть неверные записи или дубликаты (повтор в файле — DUPLICATE_WALLET, кошелёк уже есть — WALLET_EXISTS), ничего не записывается. Отчёт сверяет итоги по валютам из файла с итогами записанных кошельков; с dryRun=true импорт проверяется и откатывается.
+// @Tags admin
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce json
+// @Security AdminToken
+// @Param format query string false "Формат файла" Enums(csv, jsonl) default(csv)
+// @Param dryRun query boolean false "Проверить и откатить импорт" default(false)
+// @Param legacyOwner query string false "Владелец для записей с пустым ownerId" format(uuid)
+// @Param file body string true "Файл импорта"
+// @Success 200 {object} models.ImportReport "Отчёт импорта"
+// @Failure 400 {object} models.Problem "Неверный запрос: INVALID_REQUEST, INVALID_FORMAT или INVALID_OWNER_ID"
+// @Failure 401 {object} models.Problem "Нет или неверный токен администратора: UNAUTHORIZED"
+// @Failure 413 {object} models.Problem "Файл больше BULK_MAX_BYTES: REQUEST_TOO_LARGE"
+// @Failure 422 {object} models.ImportReport "В файле есть ошибки, ничего не записано; ошибки записей — в errors"
+// @Failure 504 {object} models.Problem "Импорт не уложился в дедлайн: TIMEOUT"
+// @Failure 500 {object} models.Problem "Внутренняя ошибка сервера: INTERNAL_ERROR"
+// @Router /api/v1/admin/wallets/import [post]
+func (h *BulkHandler) ImportWallets(w http.ResponseWriter, r *http.Request) {
+	format, err := models.ParseBulkFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	dryRun := false
+	if raw := r.URL.Query().Get("dryRun"); raw != "" {
+		if dryRun, err = strconv.ParseBool(raw); err != nil {
+			writeProblem(w, r, http.StatusBadRequest, models.CodeInvalidRequest, "dryRun must be true or false")
+			return
+		}
+	}
+	var opts []bulk.Option
+	if raw := r.URL.Query().Get("legacyOwner"); raw != "" {
+		legacyOwner, err := uuid.Parse(raw)
+		if err != nil || legacyOwner == uuid.Nil {
+			writeProblem(w, r, http.StatusBadRequest, models.CodeInvalidOwnerID, "legacyOwner must be a non-nil UUID")
+			return
+		}
+		opts = append(opts, bulk.WithLegacyOwner(legacyOwner))
+	}
+
+	// Большой файл читается дольше SERVER_READ_TIMEOUT: сроки чтения и
+	// записи продлеваются до дедлайна запроса.
+	extendDeadlines(w, r)
+	reader, err := bulk.NewReader(http.MaxBytesReader(w, r.Body, h.maxBytes), format, opts...)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	report, err := h.service.ImportWallets(r.Context(), reader, dryRun)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, models.ErrImportRejected):
+		writeImportReport(w, http.StatusUnprocessableEntity, report)
+	case errors.As(err, &tooLarge):
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, models.CodeRequestTooLarge, fmt.Sprintf("file exceeds %d bytes", tooLarge.Limit))
+	case err != nil:
+		writeError(w, r, err)
+	default:
+		writeImportReport(w, http.StatusOK, report)
+	}
+}
+
+// ExportWallets обрабатывает массовый экспорт кошельков
+// @Summary Экспорт кошельков
+// @Description Выгружает все кошельки в порядке создания в формате импорта вместе с лимитами, метками и метаданными, из одного снимка базы. У кошельков, созданных до появления владельцев, ownerId пуст: загрузить их обратно можно с legacyOwner. Файл пишется потоком; ошибка после начала передачи обрывает соединение, поэтому неполный файл не выглядит полным.
+// @Tags admin
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Security AdminToken
+// @Param format query string false "Формат файла" Enums(csv, jsonl) default(csv)
+// @Success 200 {string} string "Файл экспорта"
+// @Header 200 {string} Content-Disposition "attachment; filename=wallets-<time>.<format>"
+// @Failure 400 {object} models.Problem "Неизвестный формат: INVALID_FORMAT"
+// @Failure 401 {object} models.Problem "Нет или неверный токен администратора: UNAUTHORIZED"
+// @Failure 504 {object} models.Problem "Экспорт не уложился в дедлайн: TIMEOUT"
+// @Failure 500 {object} models.Problem "Внутренняя ошибка сервера: INTERNAL_ERROR"
+// @Router /api/v1/admin/wallets/export [get]
+func (h *BulkHandler) ExportWallets(w http.ResponseWriter, r *http.Request) {
+	format, err := models.ParseBulkFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writer, err := bulk.NewWriter(w, format)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	extendDeadlines(w, r)
+	fileName := fmt.Sprintf("wallets-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+	w.Header().Set("Content-Type", bulk.ContentType(format))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+fileName+`"`)
+
+	err = h.service.ExportWallets(r.Context(), writer)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err == nil {
+		return
+	}
+	if !writer.Started() {
+		w.Header().Del("Content-Disposition")
+		writeError(w, r, err)
+		return
+	}
+	log.Printf("%s %s aborted mid-stream: %v", r.Method, r.URL.Path, err)
+	panic(http.ErrAbortHandler)
+}
+
+// extendDeadlines продлевает сроки чтения запроса и записи ответа до
+// дедлайна запроса.
+func extendDeadlines(w http.ResponseWriter, r *http.Request) {
+	deadline, _ := r.Context().Deadline()
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(deadline)
+	rc.SetWriteDeadline(deadline)
+}
+
+func writeImportReport(w http.ResponseWriter, status int, report *models.ImportReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
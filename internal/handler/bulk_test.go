@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/DisasterWoman/wallet-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockBulkService struct {
+	mock.Mock
+}
+
+func (m *MockBulkService) ImportWallets(ctx context.Context, source repository.WalletSource, dryRun bool) (*models.ImportReport, error) {
+	args := m.Called(ctx, source, dryRun)
+	report, _ := args.Get(0).(*models.ImportReport)
+	return report, args.Error(1)
+}
+
+func (m *MockBulkService) ExportWallets(ctx context.Context, sink repository.WalletSink) error {
+	return m.Called(ctx, sink).Error(0)
+}
+
+func bulkRouter(handler *BulkHandler) *mux.Router {
+	router := mux.NewRouter()
+	admin := router.PathPrefix("/api/v1/admin").Subrouter()
+	admin.Use(AdminAuth(testAdminToken))
+	admin.HandleFunc("/wallets/import", handler.ImportWallets).Methods(http.MethodPost)
+	admin.HandleFunc("/wallets/export", handler.ExportWallets).Methods(http.MethodGet)
+	return router
+}
+
+func decodeImportReport(t *testing.T, rr *httptest.ResponseRecorder) models.ImportReport {
+	t.Helper()
+
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	var report models.ImportReport
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+	return report
+}
+
+func TestBulkHandler_ImportWallets(t *testing.T) {
+	mockService := new(MockBulkService)
+	router := bulkRouter(NewBulkHandler(mockService, 1<<20))
+
+	walletID := uuid.New()
+	file := `{"walletId":"` + walletID.String() + `","ownerId":"` + uuid.NewString() + `","currency":"RUB","balance":"12.50"}` + "\n"
+	mockService.On("ImportWallets", mock.Anything, mock.Anything, true).Run(func(args mock.Arguments) {
+		wallet, err := args.Get(1).(repository.WalletSource).Next()
+		require.NoError(t, err)
+		assert.Equal(t, walletID, wallet.ID)
+		assert.Equal(t, int64(1250), wallet.Balance)
+	}).Return(&models.ImportReport{DryRun: true, Records: 1, Wallets: 1, Balanced: true}, nil)
+
+	rr := adminRequest(router, http.MethodPost, "/api/v1/admin/wallets/import?format=jsonl&dryRun=true", file, testAdminToken)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	report := decodeImportReport(t, rr)
+	assert.True(t, report.DryRun)
+	assert.True(t, report.Balanced)
+	assert.Equal(t, int64(1), report.Wallets)
+
+	mockService.AssertExpectations(t)
+}
+
+// TestBulkHandler_ImportWallets_LegacyOwner проверяет, что запись экспорта
+// без владельца получает владельца из legacyOwner.
+func TestBulkHandler_ImportWallets_LegacyOwner(t *testing.T) {
+	mockService := new(MockBulkService)
+	router := bulkRouter(NewBulkHandler(mockService, 1<<20))
+
+	walletID, legacyOwner := uuid.New(), uuid.New()
+	file := "wallet_id,owner_id,currency,balance,status,max_balance,labels,metadata\n" +
+		walletID.String() + ",,RUB,12.50,ACTIVE,,,\n"
+	mockService.On("ImportWallets", mock.Anything, mock.Anything, false).Run(func(args mock.Arguments) {
+		wallet, err := args.Get(1).(repository.WalletSource).Next()
+		require.NoError(t, err)
+		assert.Equal(t, legacyOwner, wallet.OwnerID)
+	}).Return(&models.ImportReport{Records: 1, Wallets: 1, Balanced: true, Committed: true}, nil)
+
+	rr := adminRequest(router, http.MethodPost, "/api/v1/admin/wallets/import?legacyOwner="+legacyOwner.String(), file, testAdminToken)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestBulkHandler_ImportWallets_Rejected(t *testing.T) {
+	mockService := new(MockBulkService)
+	router := bulkRouter(NewBulkHandler(mockService, 1<<20))
+
+	rejected := &models.ImportReport{Records: 2, ErrorCount: 1, Errors: []models.ImportError{{Line: 3, Code: models.CodeDuplicateWallet, Message: "duplicate"}}}
+	mockService.On("ImportWallets", mock.Anything, mock.Anything, false).Return(rejected, models.ErrImportRejected)
+
+	rr := adminRequest(router, http.MethodPost, "/api/v1/admin/wallets/import", "wallet_id,owner_id,currency,balance\n", testAdminToken)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	report := decodeImportReport(t, rr)
+	assert.False(t, report.Committed)
+	assert.Equal(t, rejected.Errors, report.Errors)
+}
+
+func TestBulkHandler_ImportWallets_Invalid(t *testing.T) {
+	mockService := new(MockBulkService)
+	router := bulkRouter(NewBulkHandler(mockService, 1<<20))
+
+	rr := adminRequest(router, http.MethodPost, "/api/v1/admin/wallets/import?format=xml", "", testAdminToken)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, models.CodeInvalidFormat, decodeProblem(t, rr).Code)
+
+	rr = adminRequest(router, http.MethodPost, "/api/v1/admin/wallets/import?dryRun=maybe", "", testAdminToken)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, models.CodeInvalidRequest, decodeProblem(t, rr).Code)
+
+	rr = adminRequest(router, http.MethodPost, "/api/v1/admin/wallets/import?legacyOwner=nobody", "", testAdminToken)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, models.CodeInvalidOwnerID, decodeProblem(t, rr).Code)
+
+	rr = adminRequest(router, http.MethodPost, "/api/v1/admin/wallets/import", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	mockService.AssertNotCalled(t, "ImportWallets")
+}
+
+func TestBulkHandler_ImportWallets_TooLarge(t *testing.T) {
+	mockService := new(MockBulkService)
+	router := bulkRouter(NewBulkHandler(mockService, 16))
+
+	mockService.On("ImportWallets", mock.Anything, mock.Anything, false).Return(nil, &http.MaxBytesError{Limit: 16})
+
+	rr := adminRequest(router, http.MethodPost, "/api/v1/admin/wallets/import", strings.Repeat("x", 64), testAdminToken)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	assert.Equal(t, models.CodeRequestTooLarge, decodeProblem(t, rr).Code)
+}
+
+func TestBulkHandler_ExportWallets(t *testing.T) {
+	mockService := new(MockBulkService)
+	router := bulkRouter(NewBulkHandler(mockService, 1<<20))
+
+	walletID, ownerID := uuid.New(), uuid.New()
+	mockService.On("ExportWallets", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		sink := args.Get(1).(repository.WalletSink)
+		assert.NoError(t, sink.Write(&models.Wallet{ID: walletID, OwnerID: &ownerID, Currency: "RUB", Balance: 1250, Status: models.StatusActive}))
+	}).Return(nil)
+
+	rr := adminRequest(router, http.MethodGet, "/api/v1/admin/wallets/export", "", testAdminToken)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), ".csv")
+	assert.Equal(t, "wallet_id,owner_id,currency,balance,status,max_balance,labels,metadata\n"+
+		walletID.String()+","+ownerID.String()+",RUB,12.50,ACTIVE,,,\n", rr.Body.String())
+}
+
+func TestBulkHandler_ExportWallets_Errors(t *testing.T) {
+	mockService := new(MockBulkService)
+	router := bulkRouter(NewBulkHandler(mockService, 1<<20))
+
+	mockService.On("ExportWallets", mock.Anything, mock.Anything).Return(errors.New("connection refused")).Once()
+	rr := adminRequest(router, http.MethodGet, "/api/v1/admin/wallets/export?format=jsonl", "", testAdminToken)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Empty(t, rr.Header().Get("Content-Disposition"))
+
+	mockService.On("ExportWallets", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		sink := args.Get(1).(repository.WalletSink)
+		assert.NoError(t, sink.Write(&models.Wallet{ID: uuid.New(), Currency: "RUB", Status: models.StatusActive}))
+	}).Return(errors.New("connection reset")).Once()
+
+	// Начатый файл нельзя заменить ошибкой: соединение обрывается.
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		adminRequest(router, http.MethodGet, "/api/v1/admin/wallets/export?format=jsonl", "", testAdminToken)
+	})
+}
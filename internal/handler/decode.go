@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+)
+
+// maxRequestBody — наибольший размер JSON-тела запроса.
+const maxRequestBody = 64 << 10
+
+var errTrailingData = errors.New("request body must contain a single JSON object")
+
+// decodeJSON строго читает тело запроса в dst: не больше maxRequestBody
+// байт, без неизвестных полей и без данных после объекта.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return errTrailingData
+	}
+	return nil
+}
+
+// writeDecodeError отвечает на ошибку decodeJSON: 413 на слишком большое
+// тело, 400 с полем на неизвестное поле или неверный тип, 400 на остальное.
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	var (
+		tooLarge  *http.MaxBytesError
+		typeError *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &tooLarge):
+		detail := fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit)
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, models.CodeRequestTooLarge, detail)
+	case errors.As(err, &typeError):
+		detail := fmt.Sprintf("must be a JSON %s", typeError.Type)
+		writeProblem(w, r, http.StatusBadRequest, models.CodeInvalidRequest, typeError.Field+": "+detail,
+			models.FieldError{Field: typeError.Field, Code: models.CodeInvalidRequest, Message: detail})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json не экспортирует тип этой ошибки.
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		writeProblem(w, r, http.StatusBadRequest, models.CodeInvalidRequest, err.Error(),
+			models.FieldError{Field: field, Code: models.CodeInvalidRequest, Message: "unknown field"})
+	default:
+		writeProblem(w, r, http.StatusBadRequest, models.CodeInvalidRequest, err.Error())
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func postWallet(t *testing.T, handler *WalletHandler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.UpdateWalletBalance(rr, req)
+	return rr
+}
+
+func TestWalletHandler_UpdateWalletBalance_StrictDecoding(t *testing.T) {
+	walletID := uuid.NewString()
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantField  string
+	}{
+		{"unknown field", `{"walletId":"` + walletID + `","operationType":"DEPOSIT","amount":1,"currency":"USD"}`, http.StatusBadRequest, "currency"},
+		{"wrong type", `{"walletId":"` + walletID + `","operationType":"DEPOSIT","amount":true}`, http.StatusBadRequest, "amount"},
+		{"fractional number", `{"walletId":"` + walletID + `","operationType":"DEPOSIT","amount":12.5}`, http.StatusBadRequest, "amount"},
+		{"trailing data", `{"walletId":"` + walletID + `","operationType":"DEPOSIT","amount":1} {}`, http.StatusBadRequest, ""},
+		{"too large", `{"walletId":"` + walletID + `","operationType":"` + strings.Repeat("A", maxRequestBody) + `"}`, http.StatusRequestEntityTooLarge, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockService)
+
+			rr := postWallet(t, NewWalletHandler(mockService), tt.body)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			problem := decodeProblem(t, rr)
+			if tt.wantField != "" {
+				assert.Len(t, problem.Errors, 1)
+				assert.Equal(t, tt.wantField, problem.Errors[0].Field)
+			}
+			mockService.AssertNotCalled(t, "UpdateBalance")
+		})
+	}
+}
+
+func TestWalletHandler_UpdateWalletBalance_FieldErrors(t *testing.T) {
+	mockService := new(MockService)
+	handler := NewWalletHandler(mockService)
+
+	req := models.OperationRequest{OperationType: "FOO", Amount: 0}
+	mockService.On("UpdateBalance", mock.Anything, &req).Return(int64(0), req.Validate())
+
+	rr := postWallet(t, handler, `{"operationType":"FOO","amount":0}`)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	problem := decodeProblem(t, rr)
+	assert.Equal(t, models.CodeValidationFailed, problem.Code)
+	fields := make([]string, len(problem.Errors))
+	for i, fieldErr := range problem.Errors {
+		fields[i] = fieldErr.Field
+	}
+	assert.Equal(t, []string{"walletId", "operationType", "amount"}, fields)
+}
+
+func TestWalletHandler_UpdateWalletBalance_DecimalAmount(t *testing.T) {
+	mockService := new(MockService)
+	handler := NewWalletHandler(mockService)
+
+	walletID := uuid.New()
+	decimal := mock.MatchedBy(func(req *models.OperationRequest) bool {
+		return req.WalletID == walletID && req.DecimalAmount()
+	})
+	mockService.On("UpdateBalance", mock.Anything, decimal).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(*models.OperationRequest).ResolveAmount("USD")
+		}).
+		Return(int64(2050), nil)
+
+	rr := postWallet(t, handler, `{"walletId":"`+walletID.String()+`","operationType":"DEPOSIT","amount":"12.50"}`)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response models.OperationResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, models.OperationResponse{Status: "success", Balance: 2050, Currency: "USD", BalanceDecimal: "20.50"}, response)
+	mockService.AssertExpectations(t)
+}
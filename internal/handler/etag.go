@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var errInvalidETag = errors.New("If-Match must contain a single strong entity tag")
+
+// formatETag возвращает версию кошелька в виде сильного ETag.
+func formatETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseETag разбирает значение If-Match, выданное formatETag. Слабые теги и
+// списки не поддерживаются: операция требует точного совпадения версии.
+func parseETag(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return 0, errInvalidETag
+	}
+	version, err := strconv.ParseInt(value[1:len(value)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, errInvalidETag
+	}
+	return version, nil
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/DisasterWoman/wallet-service/internal/health"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Liveness обрабатывает запрос проверки жизнеспособности процесса
+// @Summary Проверка жизнеспособности
+// @Description Возвращает 200, пока процесс способен обслуживать запросы. Зависимости не проверяются
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report "Процесс жив"
+// @Router /livez [get]
+// @Router /health [get]
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, http.StatusOK, health.Report{Status: health.StatusOK})
+}
+
+// Readiness обрабатывает запрос проверки готовности к приёму трафика
+// @Summary Проверка готовности
+// @Description Проверяет доступность базы данных, заполненность пула соединений и версию схемы. Во время остановки сервиса возвращает 503
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report "Сервис готов"
+// @Failure 503 {object} health.Report "Сервис не готов"
+// @Router /readyz [get]
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := h.checker.Ready(r.Context())
+
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeReport(w, status, report)
+}
+
+func writeReport(w http.ResponseWriter, status int, report health.Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
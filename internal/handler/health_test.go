@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/health"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthHandler_Liveness(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	checker.Register("database", func(ctx context.Context) (map[string]interface{}, error) {
+		return nil, errors.New("connection refused")
+	})
+	handler := NewHealthHandler(checker)
+
+	req := httptest.NewRequest("GET", "/livez", nil)
+	rr := httptest.NewRecorder()
+
+	handler.Liveness(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestHealthHandler_Readiness_Ready(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	checker.Register("database", func(ctx context.Context) (map[string]interface{}, error) {
+		return nil, nil
+	})
+	handler := NewHealthHandler(checker)
+
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	rr := httptest.NewRecorder()
+
+	handler.Readiness(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var report health.Report
+	json.Unmarshal(rr.Body.Bytes(), &report)
+	assert.Equal(t, health.StatusOK, report.Status)
+	assert.Equal(t, health.StatusOK, report.Checks["database"].Status)
+}
+
+func TestHealthHandler_Readiness_DatabaseDown(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	checker.Register("database", func(ctx context.Context) (map[string]interface{}, error) {
+		return nil, errors.New("connection refused")
+	})
+	handler := NewHealthHandler(checker)
+
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	rr := httptest.NewRecorder()
+
+	handler.Readiness(rr, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	var report health.Report
+	json.Unmarshal(rr.Body.Bytes(), &report)
+	assert.Equal(t, "connection refused", report.Checks["database"].Error)
+}
+
+func TestHealthHandler_Readiness_Draining(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	handler := NewHealthHandler(checker)
+	checker.SetDraining()
+
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	rr := httptest.NewRecorder()
+
+	handler.Readiness(rr, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// GetOwnerWallets обрабатывает запрос на список кошельков владельца
+// @Summary Кошельки владельца
+// @Description Возвращает кошельки владельца в порядке создания и их суммарный баланс по каждой валюте
+// @Tags owner
+// @Produce json
+// @Param ownerId path string true "UUID владельца"
+// @Success 200 {object} models.OwnerWallets "Кошельки и суммы по валютам"
+// @Failure 400 {object} models.Problem "Неверный UUID: INVALID_REQUEST"
+// @Failure 404 {object} models.Problem "Владелец не найден: OWNER_NOT_FOUND"
+// @Failure 429 {object} models.Problem "Превышен лимит частоты запросов: RATE_LIMITED"
+// @Failure 503 {object} models.Problem "Сервис перегружен: SERVICE_OVERLOADED"
+// @Failure 504 {object} models.Problem "Чтение не уложилось в дедлайн: TIMEOUT"
+// @Failure 500 {object} models.Problem "Внутренняя ошибка сервера: INTERNAL_ERROR"
+// @Router /api/v1/owners/{ownerId}/wallets [get]
+func (h *WalletHandler) GetOwnerWallets(w http.ResponseWriter, r *http.Request) {
+	ownerID, err := uuid.Parse(mux.Vars(r)["ownerId"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, models.CodeInvalidRequest, "invalid owner ID")
+		return
+	}
+
+	wallets, err := h.service.OwnerWallets(r.Context(), ownerID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(wallets)
+}
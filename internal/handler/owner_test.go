@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func getOwnerWallets(handler *WalletHandler, ownerID string) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/owners/{ownerId}/wallets", handler.GetOwnerWallets)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/owners/"+ownerID+"/wallets", nil))
+	return rr
+}
+
+func TestWalletHandler_GetOwnerWallets(t *testing.T) {
+	mockService := new(MockService)
+	ownerID := uuid.New()
+	wallets := models.NewOwnerWallets(ownerID, []models.Wallet{
+		{ID: uuid.New(), Balance: 1250, Currency: "RUB", OwnerID: &ownerID},
+		{ID: uuid.New(), Balance: 50, Currency: "RUB", OwnerID: &ownerID},
+	})
+	mockService.On("OwnerWallets", mock.Anything, ownerID).Return(&wallets, nil)
+
+	rr := getOwnerWallets(NewWalletHandler(mockService), ownerID.String())
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response models.OwnerWallets
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Len(t, response.Wallets, 2)
+	if assert.Len(t, response.Totals, 1) {
+		assert.Equal(t, big.NewInt(1300), response.Totals[0].Balance)
+		assert.Equal(t, "13.00", response.Totals[0].BalanceDecimal)
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestWalletHandler_GetOwnerWallets_Errors(t *testing.T) {
+	mockService := new(MockService)
+	handler := NewWalletHandler(mockService)
+	unknown := uuid.New()
+	mockService.On("OwnerWallets", mock.Anything, unknown).Return(nil, models.ErrOwnerNotFound)
+
+	rr := getOwnerWallets(handler, "not-a-uuid")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = getOwnerWallets(handler, unknown.String())
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, models.CodeOwnerNotFound, decodeProblem(t, rr).Code)
+}
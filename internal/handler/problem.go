@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/DisasterWoman/wallet-service/internal/repository"
+)
+
+const problemContentType = "application/problem+json"
+
+// kindStatus — статус ответа для каждой категории доменной ошибки.
+var kindStatus = map[models.Kind]int{
+	models.KindInvalid:      http.StatusBadRequest,
+	models.KindNotFound:     http.StatusNotFound,
+	models.KindConflict:     http.StatusConflict,
+	models.KindPrecondition: http.StatusPreconditionFailed,
+}
+
+// writeProblem отвечает телом RFC 7807 с кодом ошибки code и ошибками
+// отдельных полей fields.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code models.Code, detail string, fields ...models.FieldError) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     code,
+		Errors:   fields,
+	})
+}
+
+// writeError переводит ошибку сервиса в ответ: доменные ошибки — по их
+// категории и коду, временные — в 503/504, остальные — в 500 без
+// подробностей.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if writeTransient(w, r, err) {
+		return
+	}
+
+	var validationErr *models.ValidationError
+	if errors.As(err, &validationErr) {
+		writeProblem(w, r, http.StatusBadRequest, validationErr.Code(), validationErr.Error(), validationErr.Fields...)
+		return
+	}
+
+	var domainErr *models.Error
+	if errors.As(err, &domainErr) {
+		writeProblem(w, r, kindStatus[domainErr.Kind], domainErr.Code, domainErr.Message)
+		return
+	}
+
+	log.Printf("%s %s failed: %v", r.Method, r.URL.Path, err)
+	writeProblem(w, r, http.StatusInternalServerError, models.CodeInternal, "internal server error")
+}
+
+// writeTransient отвечает 503 с Retry-After на ошибки перегрузки и
+// конкуренции за кошелёк и 504 на истёкший дедлайн операции. После таких
+// ошибок запрос стоит повторить. Возвращает, был ли отправлен ответ.
+func writeTransient(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, repository.ErrRetriesExhausted):
+		w.Header().Set("Retry-After", "1")
+		writeProblem(w, r, http.StatusServiceUnavailable, models.CodeWalletBusy, "wallet is busy, retry later")
+	case errors.Is(err, repository.ErrLockTimeout):
+		w.Header().Set("Retry-After", "1")
+		writeProblem(w, r, http.StatusServiceUnavailable, models.CodeWalletBusy, "wallet is locked by concurrent operations, retry later")
+	case errors.Is(err, repository.ErrOverloaded):
+		w.Header().Set("Retry-After", "1")
+		writeProblem(w, r, http.StatusServiceUnavailable, models.CodeOverloaded, "service is overloaded, retry later")
+	case errors.Is(err, repository.ErrStatementTimeout), errors.Is(err, context.DeadlineExceeded):
+		writeProblem(w, r, http.StatusGatewayTimeout, models.CodeTimeout, "operation timed out")
+	default:
+		return false
+	}
+	return true
+}
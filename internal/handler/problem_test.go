@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/DisasterWoman/wallet-service/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeProblem(t *testing.T, rr *httptest.ResponseRecorder) models.Problem {
+	t.Helper()
+
+	assert.Equal(t, problemContentType, rr.Header().Get("Content-Type"))
+	var problem models.Problem
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+	assert.Equal(t, rr.Code, problem.Status)
+	assert.Equal(t, http.StatusText(rr.Code), problem.Title)
+	return problem
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   models.Code
+	}{
+		{"wrapped insufficient funds", fmt.Errorf("withdraw: %w", models.ErrInsufficientFunds), http.StatusConflict, models.CodeInsufficientFunds},
+		{"wallet not found", repository.ErrWalletNotFound, http.StatusNotFound, models.CodeWalletNotFound},
+		{"balance limit", models.ErrBalanceLimitExceeded, http.StatusConflict, models.CodeBalanceLimitExceeded},
+		{"invalid operation type", models.ErrInvalidOperationType, http.StatusBadRequest, models.CodeInvalidOperationType},
+		{"version mismatch", models.ErrVersionMismatch, http.StatusPreconditionFailed, models.CodeVersionMismatch},
+		{"overloaded", repository.ErrOverloaded, http.StatusServiceUnavailable, models.CodeOverloaded},
+		{"unknown", errors.New("connection reset"), http.StatusInternalServerError, models.CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			writeError(rr, httptest.NewRequest(http.MethodGet, "/api/v1/wallets/abc", nil), tt.err)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			problem := decodeProblem(t, rr)
+			assert.Equal(t, tt.wantCode, problem.Code)
+			assert.Equal(t, "/api/v1/wallets/abc", problem.Instance)
+		})
+	}
+}
+
+func TestWriteError_HidesInternalDetails(t *testing.T) {
+	rr := httptest.NewRecorder()
+	writeError(rr, httptest.NewRequest(http.MethodGet, "/", nil), errors.New("pq: password authentication failed"))
+
+	problem := decodeProblem(t, rr)
+	assert.Equal(t, "internal server error", problem.Detail)
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/metrics"
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/DisasterWoman/wallet-service/internal/ratelimit"
+	"github.com/gorilla/mux"
+)
+
+// maxPeekBody — сколько байт тела запроса WalletKey читает в поисках
+// walletId.
+const maxPeekBody = 1 << 20
+
+// OperationRoute — имя маршрута POST /api/v1/wallet. Только у него WalletKey
+// ищет walletId в теле: ограничение частоты стоит до AdminAuth, и тела
+// остальных запросов, например файлов импорта, читать до проверки
+// доступа нельзя.
+const OperationRoute = "wallet-operation"
+
+// RateLimitScope — одно ограничение частоты: Key выделяет из запроса ключ,
+// по которому ведётся учёт. Пустой ключ означает, что запрос под это
+// ограничение не попадает.
+type RateLimitScope struct {
+	Name    string
+	Limiter ratelimit.Limiter
+	Key     func(r *http.Request) string
+}
+
+// RateLimit отклоняет запрос с 429 и Retry-After, если он превышает любое из
+// ограничений. Ошибка хранилища лимитов запрос не блокирует.
+func RateLimit(scopes ...RateLimitScope) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, scope := range scopes {
+				key := scope.Key(r)
+				if key == "" {
+					continue
+				}
+
+				allowed, retryAfter, err := scope.Limiter.Take(r.Context(), key)
+				if err != nil {
+					log.Printf("Rate limit %s unavailable: %v", scope.Name, err)
+					continue
+				}
+				if !allowed {
+					metrics.RateLimited.WithLabelValues(scope.Name).Inc()
+					w.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+					writeProblem(w, r, http.StatusTooManyRequests, models.CodeRateLimited, "rate limit exceeded")
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// APIKey возвращает ключ клиента из заголовка X-API-Key.
+func APIKey(r *http.Request) string {
+	return r.Header.Get("X-API-Key")
+}
+
+// ClientIP возвращает функцию, выделяющую IP клиента. С trustProxy берётся
+// первый адрес из X-Forwarded-For: так можно делать, только если сервис
+// доступен исключительно через прокси, который этот заголовок перезаписывает.
+func ClientIP(trustProxy bool) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		if trustProxy {
+			if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+				client, _, _ := strings.Cut(forwarded, ",")
+				return strings.TrimSpace(client)
+			}
+		}
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+		return host
+	}
+}
+
+// WalletKey возвращает id кошелька из пути или, для маршрута
+// OperationRoute, из поля walletId JSON-тела. Прочитанное тело
+// возвращается в запрос для обработчика.
+func WalletKey(r *http.Request) string {
+	if walletID := mux.Vars(r)["walletId"]; walletID != "" {
+		return walletID
+	}
+	route := mux.CurrentRoute(r)
+	if r.Body == nil || route == nil || route.GetName() != OperationRoute {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPeekBody))
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+	if err != nil {
+		return ""
+	}
+
+	var req struct {
+		WalletID string `json:"walletId"`
+	}
+	if json.Unmarshal(body, &req) != nil {
+		return ""
+	}
+	return req.WalletID
+}
+
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Max(1, math.Ceil(d.Seconds()))))
+}
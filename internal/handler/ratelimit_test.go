@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/ratelimit"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingLimiter struct{}
+
+func (failingLimiter) Take(context.Context, string) (bool, time.Duration, error) {
+	return false, 0, errors.New("store unavailable")
+}
+
+func rateLimitedRouter(scopes ...RateLimitScope) *mux.Router {
+	r := mux.NewRouter()
+	r.Use(RateLimit(scopes...))
+	r.HandleFunc("/api/v1/wallet", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}).Methods(http.MethodPost).Name(OperationRoute)
+	r.HandleFunc("/api/v1/admin/wallets/import", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/wallets/{walletId}", func(w http.ResponseWriter, r *http.Request) {}).Methods(http.MethodGet)
+	return r
+}
+
+func TestRateLimit_RejectsOverLimit(t *testing.T) {
+	router := rateLimitedRouter(RateLimitScope{
+		Name:    "api_key",
+		Limiter: ratelimit.NewMemoryLimiter(ratelimit.Rate{Rate: 0.5, Burst: 1}),
+		Key:     APIKey,
+	})
+
+	send := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+"abc", nil)
+		req.Header.Set("X-API-Key", key)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusOK, send("client-a").Code)
+
+	rr := send("client-a")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("Retry-After"))
+
+	// Ведро другого клиента не затронуто.
+	assert.Equal(t, http.StatusOK, send("client-b").Code)
+}
+
+func TestRateLimit_SkipsEmptyKey(t *testing.T) {
+	router := rateLimitedRouter(RateLimitScope{
+		Name:    "api_key",
+		Limiter: ratelimit.NewMemoryLimiter(ratelimit.Rate{Rate: 1, Burst: 1}),
+		Key:     APIKey,
+	})
+
+	for i := 0; i < 3; i++ {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/wallets/abc", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
+}
+
+func TestRateLimit_FailsOpen(t *testing.T) {
+	router := rateLimitedRouter(RateLimitScope{Name: "ip", Limiter: failingLimiter{}, Key: ClientIP(false)})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/wallets/abc", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestRateLimit_WalletKeyPreservesBody(t *testing.T) {
+	router := rateLimitedRouter(RateLimitScope{
+		Name:    "wallet",
+		Limiter: ratelimit.NewMemoryLimiter(ratelimit.Rate{Rate: 1, Burst: 1}),
+		Key:     WalletKey,
+	})
+	body := `{"walletId":"4f1c6a9e-0000-4000-8000-000000000001","operationType":"DEPOSIT","amount":1}`
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/wallet", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, body, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/wallet", strings.NewReader(body)))
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+}
+
+func TestRateLimit_WalletKeyIgnoresOtherBodies(t *testing.T) {
+	router := rateLimitedRouter(RateLimitScope{
+		Name:    "wallet",
+		Limiter: ratelimit.NewMemoryLimiter(ratelimit.Rate{Rate: 1, Burst: 1}),
+		Key:     WalletKey,
+	})
+	body := `{"walletId":"4f1c6a9e-0000-4000-8000-000000000001"}`
+
+	// Тело чужого маршрута не разбирается, поэтому и лимит кошелька к нему
+	// не применяется.
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/admin/wallets/import", strings.NewReader(body)))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, body, rr.Body.String())
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.7:51234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9, 10.0.0.1")
+
+	assert.Equal(t, "10.0.0.7", ClientIP(false)(req))
+	assert.Equal(t, "203.0.113.9", ClientIP(true)(req))
+}
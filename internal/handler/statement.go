@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/DisasterWoman/wallet-service/internal/statement"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// GetStatement обрабатывает запрос выписки по кошельку
+// @Summary Выписка по кошельку
+// @Description Выписка за период (from, to]: строка OPENING с остатком на момент from, строка на каждую операцию с остатком после неё и строка CLOSING с остатком на момент to. Пишется потоком по мере чтения журнала, в CSV (по умолчанию) или JSON Lines. Ошибка после начала передачи обрывает соединение, поэтому неполная выписка не выглядит полной.
+// @Tags wallet
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param walletId path string true "UUID кошелька"
+// @Param from query string true "Начало периода (RFC 3339), не включается"
+// @Param to query string false "Конец периода (RFC 3339), включается; по умолчанию — текущий момент"
+// @Param format query string false "Формат выписки" Enums(csv, jsonl) default(csv)
+// @Success 200 {string} string "Выписка"
+// @Header 200 {string} Content-Disposition "attachment; filename=statement-<walletId>-<from>-<to>.<format>"
+// @Failure 400 {object} models.Problem "Неверный запрос: INVALID_REQUEST, VALIDATION_FAILED или код ошибки параметра (INVALID_PERIOD, INVALID_FORMAT); ошибки параметров — в errors"
+// @Failure 404 {object} models.Problem "Кошелек не найден: WALLET_NOT_FOUND"
+// @Failure 429 {object} models.Problem "Превышен лимит частоты запросов: RATE_LIMITED"
+// @Failure 503 {object} models.Problem "Сервис перегружен: SERVICE_OVERLOADED"
+// @Failure 504 {object} models.Problem "Выписка не уложилась в дедлайн: TIMEOUT"
+// @Failure 500 {object} models.Problem "Внутренняя ошибка сервера: INTERNAL_ERROR"
+// @Router /api/v1/wallets/{walletId}/statement [get]
+func (h *WalletHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(mux.Vars(r)["walletId"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, models.CodeInvalidRequest, "invalid wallet ID")
+		return
+	}
+
+	req, err := models.ParseStatementQuery(walletID, r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writer, err := statement.NewWriter(w, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Выписка за большой период пишется дольше SERVER_WRITE_TIMEOUT.
+	extendDeadlines(w, r)
+
+	w.Header().Set("Content-Type", statement.ContentType(req.Format))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+statement.FileName(&req)+`"`)
+
+	err = h.service.Statement(r.Context(), &req, writer)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err == nil {
+		return
+	}
+	if !writer.Started() {
+		w.Header().Del("Content-Disposition")
+		writeError(w, r, err)
+		return
+	}
+	log.Printf("%s %s aborted mid-stream: %v", r.Method, r.URL.Path, err)
+	panic(http.ErrAbortHandler)
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/DisasterWoman/wallet-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func statementRouter(handler *WalletHandler) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/wallets/{walletId}/statement", handler.GetStatement)
+	return router
+}
+
+func getStatement(router *mux.Router, walletID uuid.UUID, query string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/wallets/"+walletID.String()+"/statement"+query, nil))
+	return rr
+}
+
+func TestWalletHandler_GetStatement(t *testing.T) {
+	mockService := new(MockService)
+	router := statementRouter(NewWalletHandler(mockService))
+
+	walletID := uuid.New()
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	matchReq := mock.MatchedBy(func(req *models.StatementRequest) bool {
+		return req.WalletID == walletID && req.From.Equal(from) && req.To.Equal(to) && req.Format == models.StatementCSV
+	})
+	mockService.On("Statement", mock.Anything, matchReq, mock.Anything).Run(func(args mock.Arguments) {
+		sink := args.Get(2).(repository.StatementSink)
+		assert.NoError(t, sink.Opening(&models.Wallet{ID: walletID, Currency: "RUB"}, 1000))
+		assert.NoError(t, sink.Operation(&models.Operation{ID: 1, Type: models.Withdraw, Amount: -300, CreatedAt: from.Add(time.Hour)}))
+	}).Return(nil)
+
+	rr := getStatement(router, walletID, "?from=2026-09-01T00:00:00Z&to=2026-10-01T00:00:00Z")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), "statement-"+walletID.String()+"-20260901-20261001.csv")
+	assert.Equal(t, strings.Join([]string{
+		"record,time,operation_id,operation_type,transfer_id,amount,balance,currency",
+		"OPENING,2026-09-01T00:00:00Z,,,,,10.00,RUB",
+		"OPERATION,2026-09-01T01:00:00Z,1,WITHDRAW,,-3.00,7.00,RUB",
+		"CLOSING,2026-10-01T00:00:00Z,,,,,7.00,RUB",
+	}, "\n")+"\n", rr.Body.String())
+
+	mockService.AssertExpectations(t)
+}
+
+func TestWalletHandler_GetStatement_Invalid(t *testing.T) {
+	mockService := new(MockService)
+	router := statementRouter(NewWalletHandler(mockService))
+
+	rr := getStatement(router, uuid.New(), "?from=2026-10-01T00:00:00Z&to=2026-09-01T00:00:00Z")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, models.CodeInvalidPeriod, decodeProblem(t, rr).Code)
+
+	rr = getStatement(router, uuid.New(), "?from=2026-09-01T00:00:00Z&format=pdf")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, models.CodeInvalidFormat, decodeProblem(t, rr).Code)
+
+	mockService.AssertNotCalled(t, "Statement")
+}
+
+func TestWalletHandler_GetStatement_WalletNotFound(t *testing.T) {
+	mockService := new(MockService)
+	router := statementRouter(NewWalletHandler(mockService))
+
+	walletID := uuid.New()
+	mockService.On("Statement", mock.Anything, mock.Anything, mock.Anything).Return(repository.ErrWalletNotFound)
+
+	rr := getStatement(router, walletID, "?from=2026-09-01T00:00:00Z")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Empty(t, rr.Header().Get("Content-Disposition"))
+	assert.Equal(t, models.CodeWalletNotFound, decodeProblem(t, rr).Code)
+}
+
+func TestWalletHandler_GetStatement_AbortMidStream(t *testing.T) {
+	mockService := new(MockService)
+	router := statementRouter(NewWalletHandler(mockService))
+
+	walletID := uuid.New()
+	mockService.On("Statement", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		sink := args.Get(2).(repository.StatementSink)
+		assert.NoError(t, sink.Opening(&models.Wallet{ID: walletID, Currency: "RUB"}, 1000))
+	}).Return(errors.New("connection reset"))
+
+	// Начатую выписку нельзя заменить ошибкой: соединение обрывается.
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		getStatement(router, walletID, "?from=2026-09-01T00:00:00Z")
+	})
+}
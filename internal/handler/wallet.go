@@ -2,7 +2,9 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/DisasterWoman/wallet-service/internal/models"
 	"github.com/DisasterWoman/wallet-service/internal/repository"
@@ -21,67 +23,167 @@ func NewWalletHandler(service service.WalletService) *WalletHandler {
 
 // UpdateWalletBalance обрабатывает запрос на изменение баланса
 // @Summary Изменить баланс кошелька
-// @Description Выполняет операцию пополнения или списания средств
+// @Description Выполняет операцию пополнения или списания средств. Сумма — целое число минимальных единиц (1250) или десятичная строка в основных единицах валюты кошелька ("12.50"); во втором случае ответ содержит и balanceDecimal. Лишние знаки после запятой отклоняются (AMOUNT_PRECISION).
 // @Tags wallet
 // @Accept json
 // @Produce json
-// @Param request body models.OperationRequest true "Данные операции"
-// @Success 200 {object} map[string]string "Успешное выполнение операции"
-// @Failure 400 {object} map[string]string "Неверный запрос"
-// @Failure 409 {object} map[string]string "Конфликт (недостаточно средств или кошелек не найден)"
-// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Param request body models.OperationRequestDoc true "Данные операции"
+// @Param If-Match header string false "ETag кошелька: операция выполнится, только если кошелёк не менялся"
+// @Success 200 {object} models.OperationResponse "Успешное выполнение операции и новый баланс"
+// @Header 200 {string} ETag "Версия кошелька после операции (только при If-Match)"
+// @Failure 400 {object} models.Problem "Неверный запрос: INVALID_REQUEST, VALIDATION_FAILED или код ошибки поля (INVALID_WALLET_ID, INVALID_OPERATION_TYPE, INVALID_AMOUNT, AMOUNT_TOO_LARGE, AMOUNT_PRECISION); ошибки полей — в errors"
+// @Failure 409 {object} models.Problem "Конфликт: INSUFFICIENT_FUNDS, BALANCE_LIMIT_EXCEEDED, WALLET_NOT_FOUND, WALLET_FROZEN"
+// @Failure 412 {object} models.Problem "Кошелёк изменился после чтения: VERSION_MISMATCH"
+// @Failure 413 {object} models.Problem "Тело запроса больше 64 КБ: REQUEST_TOO_LARGE"
+// @Failure 429 {object} models.Problem "Превышен лимит частоты запросов: RATE_LIMITED"
+// @Failure 503 {object} models.Problem "Кошелёк занят (WALLET_BUSY) или сервис перегружен (SERVICE_OVERLOADED)"
+// @Failure 504 {object} models.Problem "Операция не уложилась в дедлайн: TIMEOUT"
+// @Failure 500 {object} models.Problem "Внутренняя ошибка сервера: INTERNAL_ERROR"
 // @Router /api/v1/wallet [post]
 func (h *WalletHandler) UpdateWalletBalance(w http.ResponseWriter, r *http.Request) {
 	var req models.OperationRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeDecodeError(w, r, err)
 		return
 	}
 
-	if err := h.service.UpdateBalance(r.Context(), &req); err != nil {
-		switch err {
-		case models.ErrInvalidAmount:
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		case models.ErrInsufficientFunds, repository.ErrWalletNotFound:
-			http.Error(w, err.Error(), http.StatusConflict)
-		default:
-			http.Error(w, "internal server error", http.StatusInternalServerError)
-		}
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && ifMatch != "*" {
+		h.updateWalletBalanceIfMatch(w, r, &req, ifMatch)
+		return
+	}
+
+	balance, err := h.service.UpdateBalance(r.Context(), &req)
+	if err != nil {
+		writeOperationError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	json.NewEncoder(w).Encode(operationResponse(&req, balance))
+}
+
+func (h *WalletHandler) updateWalletBalanceIfMatch(w http.ResponseWriter, r *http.Request, req *models.OperationRequest, ifMatch string) {
+	version, err := parseETag(ifMatch)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, models.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	wallet, err := h.service.UpdateBalanceIfVersion(r.Context(), req, version)
+	if err != nil {
+		writeOperationError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(wallet.Version))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(operationResponse(req, wallet.Balance))
+}
+
+// operationResponse отвечает в формате суммы запроса: на десятичную сумму —
+// и балансом в основных единицах валюты кошелька.
+func operationResponse(req *models.OperationRequest, balance int64) models.OperationResponse {
+	response := models.OperationResponse{Status: "success", Balance: balance}
+	if currency := req.Currency(); currency != "" {
+		response.Currency = currency
+		response.BalanceDecimal = models.FormatAmount(balance, currency.Exponent())
+	}
+	return response
+}
+
+// writeOperationError отвечает на ошибку операции. Кошелёк операции задан в
+// теле, а не в пути запроса, поэтому его отсутствие — 409, а не 404.
+func writeOperationError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, models.ErrWalletNotFound) {
+		writeProblem(w, r, http.StatusConflict, models.CodeWalletNotFound, err.Error())
+		return
+	}
+	writeError(w, r, err)
 }
 
 // GetWalletBalance обрабатывает запрос на получение баланса
 // @Summary Получить баланс кошелька
-// @Description Возвращает текущий баланс указанного кошелька
+// @Description Возвращает текущий баланс указанного кошелька в минимальных единицах и десятичной строкой в основных единицах его валюты
 // @Tags wallet
 // @Produce json
 // @Param walletId path string true "UUID кошелька"
-// @Success 200 {object} map[string]int64 "Баланс кошелька"
-// @Failure 400 {object} map[string]string "Неверный UUID"
-// @Failure 404 {object} map[string]string "Кошелек не найден"
-// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Param consistency query string false "strong — читать с основной базы в обход реплик и кэша" Enums(eventual, strong)
+// @Success 200 {object} models.BalanceResponse "Баланс кошелька"
+// @Header 200 {string} ETag "Версия кошелька для If-Match"
+// @Failure 400 {object} models.Problem "Неверный UUID или consistency: INVALID_REQUEST"
+// @Failure 404 {object} models.Problem "Кошелек не найден: WALLET_NOT_FOUND"
+// @Failure 429 {object} models.Problem "Превышен лимит частоты запросов: RATE_LIMITED"
+// @Failure 503 {object} models.Problem "Сервис перегружен: SERVICE_OVERLOADED, WALLET_BUSY"
+// @Failure 504 {object} models.Problem "Чтение не уложилось в дедлайн: TIMEOUT"
+// @Failure 500 {object} models.Problem "Внутренняя ошибка сервера: INTERNAL_ERROR"
 // @Router /api/v1/wallets/{walletId} [get]
 func (h *WalletHandler) GetWalletBalance(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	walletID, err := uuid.Parse(vars["walletId"])
 	if err != nil {
-		http.Error(w, "invalid wallet ID", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, models.CodeInvalidRequest, "invalid wallet ID")
 		return
 	}
 
-	balance, err := h.service.GetBalance(r.Context(), walletID)
+	ctx := r.Context()
+	switch r.URL.Query().Get("consistency") {
+	case "", "eventual":
+	case "strong":
+		ctx = repository.WithStrongRead(ctx)
+	default:
+		writeProblem(w, r, http.StatusBadRequest, models.CodeInvalidRequest, "consistency must be eventual or strong")
+		return
+	}
+
+	wallet, err := h.service.GetWallet(ctx, walletID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(wallet.Version))
+	json.NewEncoder(w).Encode(models.NewBalanceResponse(wallet))
+}
+
+// GetWalletBalanceAsOf обрабатывает запрос баланса на момент времени
+// @Summary Баланс кошелька на момент времени
+// @Description Без asOf возвращает текущий баланс, как GET /api/v1/wallets/{walletId}. С asOf — баланс по журналу операций на этот момент: сумма операций не позже asOf, считаемая от ближайшей предыдущей контрольной точки. До создания кошелька баланс нулевой.
+// @Tags wallet
+// @Produce json
+// @Param walletId path string true "UUID кошелька"
+// @Param asOf query string false "Момент времени (RFC 3339), не в будущем"
+// @Param consistency query string false "strong — читать текущий баланс с основной базы в обход реплик и кэша" Enums(eventual, strong)
+// @Success 200 {object} models.BalanceResponse "Баланс кошелька"
+// @Failure 400 {object} models.Problem "Неверный UUID или consistency (INVALID_REQUEST), неверный asOf (INVALID_AS_OF)"
+// @Failure 404 {object} models.Problem "Кошелек не найден: WALLET_NOT_FOUND"
+// @Failure 429 {object} models.Problem "Превышен лимит частоты запросов: RATE_LIMITED"
+// @Failure 503 {object} models.Problem "Сервис перегружен: SERVICE_OVERLOADED"
+// @Failure 504 {object} models.Problem "Чтение не уложилось в дедлайн: TIMEOUT"
+// @Failure 500 {object} models.Problem "Внутренняя ошибка сервера: INTERNAL_ERROR"
+// @Router /api/v1/wallets/{walletId}/balance [get]
+func (h *WalletHandler) GetWalletBalanceAsOf(w http.ResponseWriter, r *http.Request) {
+	rawAsOf := r.URL.Query().Get("asOf")
+	if rawAsOf == "" {
+		h.GetWalletBalance(w, r)
+		return
+	}
+
+	walletID, err := uuid.Parse(mux.Vars(r)["walletId"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, models.CodeInvalidRequest, "invalid wallet ID")
+		return
+	}
+	asOf, err := time.Parse(time.RFC3339Nano, rawAsOf)
 	if err != nil {
-		if err == repository.ErrWalletNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else {
-			http.Error(w, "internal server error", http.StatusInternalServerError)
-		}
+		writeError(w, r, models.ErrInvalidAsOf)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]int64{"balance": balance})
-}
\ No newline at end of file
+	balance, err := h.service.BalanceAsOf(r.Context(), walletID, asOf)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(balance)
+}
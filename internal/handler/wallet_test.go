@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/DisasterWoman/wallet-service/internal/models"
 	"github.com/DisasterWoman/wallet-service/internal/repository"
@@ -20,9 +22,49 @@ type MockService struct {
 	mock.Mock
 }
 
-func (m *MockService) UpdateBalance(ctx context.Context, req *models.OperationRequest) error {
+func (m *MockService) CreateOwner(ctx context.Context, ownerID uuid.UUID, name string) (*models.Owner, error) {
+	args := m.Called(ctx, ownerID, name)
+	owner, _ := args.Get(0).(*models.Owner)
+	return owner, args.Error(1)
+}
+
+func (m *MockService) CreateWallet(ctx context.Context, walletID, ownerID uuid.UUID) (*models.Wallet, error) {
+	args := m.Called(ctx, walletID, ownerID)
+	wallet, _ := args.Get(0).(*models.Wallet)
+	return wallet, args.Error(1)
+}
+
+func (m *MockService) BalanceAsOf(ctx context.Context, walletID uuid.UUID, asOf time.Time) (*models.BalanceResponse, error) {
+	args := m.Called(ctx, walletID, asOf)
+	balance, _ := args.Get(0).(*models.BalanceResponse)
+	return balance, args.Error(1)
+}
+
+func (m *MockService) Statement(ctx context.Context, req *models.StatementRequest, sink repository.StatementSink) error {
+	return m.Called(ctx, req, sink).Error(0)
+}
+
+func (m *MockService) OwnerWallets(ctx context.Context, ownerID uuid.UUID) (*models.OwnerWallets, error) {
+	args := m.Called(ctx, ownerID)
+	wallets, _ := args.Get(0).(*models.OwnerWallets)
+	return wallets, args.Error(1)
+}
+
+func (m *MockService) GetWallet(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error) {
+	args := m.Called(ctx, walletID)
+	wallet, _ := args.Get(0).(*models.Wallet)
+	return wallet, args.Error(1)
+}
+
+func (m *MockService) UpdateBalance(ctx context.Context, req *models.OperationRequest) (int64, error) {
 	args := m.Called(ctx, req)
-	return args.Error(0)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockService) UpdateBalanceIfVersion(ctx context.Context, req *models.OperationRequest, version int64) (*models.Wallet, error) {
+	args := m.Called(ctx, req, version)
+	wallet, _ := args.Get(0).(*models.Wallet)
+	return wallet, args.Error(1)
 }
 
 func (m *MockService) GetBalance(ctx context.Context, walletID uuid.UUID) (int64, error) {
@@ -30,6 +72,27 @@ func (m *MockService) GetBalance(ctx context.Context, walletID uuid.UUID) (int64
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockService) Transfer(ctx context.Context, req *models.TransferRequest) (uuid.UUID, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockService) Freeze(ctx context.Context, walletID uuid.UUID) error {
+	args := m.Called(ctx, walletID)
+	return args.Error(0)
+}
+
+func (m *MockService) Unfreeze(ctx context.Context, walletID uuid.UUID) error {
+	args := m.Called(ctx, walletID)
+	return args.Error(0)
+}
+
+func (m *MockService) History(ctx context.Context, walletID uuid.UUID, limit int) ([]models.Operation, error) {
+	args := m.Called(ctx, walletID, limit)
+	operations, _ := args.Get(0).([]models.Operation)
+	return operations, args.Error(1)
+}
+
 func TestWalletHandler_UpdateWalletBalance_Success(t *testing.T) {
 	mockService := new(MockService)
 	handler := NewWalletHandler(mockService)
@@ -41,7 +104,7 @@ func TestWalletHandler_UpdateWalletBalance_Success(t *testing.T) {
 		Amount:        1000,
 	}
 
-	mockService.On("UpdateBalance", mock.Anything, &reqBody).Return(nil)
+	mockService.On("UpdateBalance", mock.Anything, &reqBody).Return(int64(1000), nil)
 
 	body, _ := json.Marshal(reqBody)
 	req := httptest.NewRequest("POST", "/api/v1/wallet", bytes.NewReader(body))
@@ -52,9 +115,10 @@ func TestWalletHandler_UpdateWalletBalance_Success(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 	
-	var response map[string]string
+	var response models.OperationResponse
 	json.Unmarshal(rr.Body.Bytes(), &response)
-	assert.Equal(t, "success", response["status"])
+	assert.Equal(t, "success", response.Status)
+	assert.Equal(t, int64(1000), response.Balance)
 	
 	mockService.AssertExpectations(t)
 }
@@ -86,7 +150,31 @@ func TestWalletHandler_UpdateWalletBalance_InsufficientFunds(t *testing.T) {
 		Amount:        1000,
 	}
 
-	mockService.On("UpdateBalance", mock.Anything, &reqBody).Return(models.ErrInsufficientFunds)
+	mockService.On("UpdateBalance", mock.Anything, &reqBody).Return(int64(0), models.ErrInsufficientFunds)
+
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/api/v1/wallet", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	handler.UpdateWalletBalance(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestWalletHandler_UpdateWalletBalance_WalletFrozen(t *testing.T) {
+	mockService := new(MockService)
+	handler := NewWalletHandler(mockService)
+
+	walletID := uuid.New()
+	reqBody := models.OperationRequest{
+		WalletID:      walletID,
+		OperationType: models.Deposit,
+		Amount:        1000,
+	}
+
+	mockService.On("UpdateBalance", mock.Anything, &reqBody).Return(int64(0), models.ErrWalletFrozen)
 
 	body, _ := json.Marshal(reqBody)
 	req := httptest.NewRequest("POST", "/api/v1/wallet", bytes.NewReader(body))
@@ -110,7 +198,7 @@ func TestWalletHandler_UpdateWalletBalance_WalletNotFound(t *testing.T) {
 		Amount:        1000,
 	}
 
-	mockService.On("UpdateBalance", mock.Anything, &reqBody).Return(repository.ErrWalletNotFound)
+	mockService.On("UpdateBalance", mock.Anything, &reqBody).Return(int64(0), repository.ErrWalletNotFound)
 
 	body, _ := json.Marshal(reqBody)
 	req := httptest.NewRequest("POST", "/api/v1/wallet", bytes.NewReader(body))
@@ -123,6 +211,113 @@ func TestWalletHandler_UpdateWalletBalance_WalletNotFound(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestWalletHandler_UpdateWalletBalance_RetriesExhausted(t *testing.T) {
+	mockService := new(MockService)
+	handler := NewWalletHandler(mockService)
+
+	walletID := uuid.New()
+	reqBody := models.OperationRequest{
+		WalletID:      walletID,
+		OperationType: models.Withdraw,
+		Amount:        100,
+	}
+
+	err := fmt.Errorf("%w: deadlock detected", repository.ErrRetriesExhausted)
+	mockService.On("UpdateBalance", mock.Anything, &reqBody).Return(int64(0), err)
+
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/api/v1/wallet", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	handler.UpdateWalletBalance(rr, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	mockService.AssertExpectations(t)
+}
+
+func TestWalletHandler_UpdateWalletBalance_Overloaded(t *testing.T) {
+	mockService := new(MockService)
+	handler := NewWalletHandler(mockService)
+
+	reqBody := models.OperationRequest{
+		WalletID:      uuid.New(),
+		OperationType: models.Deposit,
+		Amount:        100,
+	}
+	mockService.On("UpdateBalance", mock.Anything, &reqBody).Return(int64(0), repository.ErrOverloaded)
+
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/api/v1/wallet", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	handler.UpdateWalletBalance(rr, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	mockService.AssertExpectations(t)
+}
+
+func TestWalletHandler_UpdateWalletBalance_Timeouts(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		retryAfter string
+	}{
+		{"lock timeout", fmt.Errorf("%w: canceling statement due to lock timeout", repository.ErrLockTimeout), http.StatusServiceUnavailable, "1"},
+		{"statement timeout", repository.ErrStatementTimeout, http.StatusGatewayTimeout, ""},
+		{"deadline", context.DeadlineExceeded, http.StatusGatewayTimeout, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockService)
+			handler := NewWalletHandler(mockService)
+
+			reqBody := models.OperationRequest{
+				WalletID:      uuid.New(),
+				OperationType: models.Deposit,
+				Amount:        100,
+			}
+			mockService.On("UpdateBalance", mock.Anything, &reqBody).Return(int64(0), tt.err)
+
+			body, _ := json.Marshal(reqBody)
+			req := httptest.NewRequest("POST", "/api/v1/wallet", bytes.NewReader(body))
+			rr := httptest.NewRecorder()
+
+			handler.UpdateWalletBalance(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.retryAfter, rr.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestWalletHandler_UpdateWalletBalance_InvalidOperationType(t *testing.T) {
+	mockService := new(MockService)
+	handler := NewWalletHandler(mockService)
+
+	reqBody := models.OperationRequest{
+		WalletID:      uuid.New(),
+		OperationType: "REFUND",
+		Amount:        100,
+	}
+	mockService.On("UpdateBalance", mock.Anything, &reqBody).Return(int64(0), models.ErrInvalidOperationType)
+
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/api/v1/wallet", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	handler.UpdateWalletBalance(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, models.CodeInvalidOperationType, decodeProblem(t, rr).Code)
+	mockService.AssertExpectations(t)
+}
+
 func TestWalletHandler_UpdateWalletBalance_InvalidAmount(t *testing.T) {
 	mockService := new(MockService)
 	handler := NewWalletHandler(mockService)
@@ -134,7 +329,7 @@ func TestWalletHandler_UpdateWalletBalance_InvalidAmount(t *testing.T) {
 		Amount:        -100, // Невалидная сумма
 	}
 
-	mockService.On("UpdateBalance", mock.Anything, &reqBody).Return(models.ErrInvalidAmount)
+	mockService.On("UpdateBalance", mock.Anything, &reqBody).Return(int64(0), models.ErrInvalidAmount)
 
 	body, _ := json.Marshal(reqBody)
 	req := httptest.NewRequest("POST", "/api/v1/wallet", bytes.NewReader(body))
@@ -147,6 +342,81 @@ func TestWalletHandler_UpdateWalletBalance_InvalidAmount(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestWalletHandler_UpdateWalletBalance_IfMatch(t *testing.T) {
+	mockService := new(MockService)
+	handler := NewWalletHandler(mockService)
+
+	walletID := uuid.New()
+	reqBody := models.OperationRequest{
+		WalletID:      walletID,
+		OperationType: models.Withdraw,
+		Amount:        200,
+	}
+
+	mockService.On("UpdateBalanceIfVersion", mock.Anything, &reqBody, int64(7)).
+		Return(&models.Wallet{ID: walletID, Balance: 800, Version: 8}, nil)
+
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/api/v1/wallet", bytes.NewReader(body))
+	req.Header.Set("If-Match", `"7"`)
+	rr := httptest.NewRecorder()
+
+	handler.UpdateWalletBalance(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"8"`, rr.Header().Get("ETag"))
+
+	var response models.OperationResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, int64(800), response.Balance)
+	mockService.AssertExpectations(t)
+}
+
+func TestWalletHandler_UpdateWalletBalance_IfMatchStale(t *testing.T) {
+	mockService := new(MockService)
+	handler := NewWalletHandler(mockService)
+
+	reqBody := models.OperationRequest{
+		WalletID:      uuid.New(),
+		OperationType: models.Deposit,
+		Amount:        100,
+	}
+
+	mockService.On("UpdateBalanceIfVersion", mock.Anything, &reqBody, int64(3)).Return(nil, models.ErrVersionMismatch)
+
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/api/v1/wallet", bytes.NewReader(body))
+	req.Header.Set("If-Match", `"3"`)
+	rr := httptest.NewRecorder()
+
+	handler.UpdateWalletBalance(rr, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestWalletHandler_UpdateWalletBalance_IfMatchInvalid(t *testing.T) {
+	mockService := new(MockService)
+	handler := NewWalletHandler(mockService)
+
+	body, _ := json.Marshal(models.OperationRequest{
+		WalletID:      uuid.New(),
+		OperationType: models.Deposit,
+		Amount:        100,
+	})
+
+	for _, header := range []string{`W/"3"`, `3`, `"3", "4"`, `"abc"`} {
+		req := httptest.NewRequest("POST", "/api/v1/wallet", bytes.NewReader(body))
+		req.Header.Set("If-Match", header)
+		rr := httptest.NewRecorder()
+
+		handler.UpdateWalletBalance(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, header)
+	}
+	mockService.AssertNotCalled(t, "UpdateBalanceIfVersion")
+}
+
 func TestWalletHandler_GetWalletBalance_Success(t *testing.T) {
 	mockService := new(MockService)
 	handler := NewWalletHandler(mockService)
@@ -154,7 +424,7 @@ func TestWalletHandler_GetWalletBalance_Success(t *testing.T) {
 	walletID := uuid.New()
 	expectedBalance := int64(1500)
 
-	mockService.On("GetBalance", mock.Anything, walletID).Return(expectedBalance, nil)
+	mockService.On("GetWallet", mock.Anything, walletID).Return(&models.Wallet{ID: walletID, Balance: expectedBalance, Version: 7, Currency: "RUB"}, nil)
 
 	req := httptest.NewRequest("GET", "/api/v1/wallets/"+walletID.String(), nil)
 	rr := httptest.NewRecorder()
@@ -165,21 +435,84 @@ func TestWalletHandler_GetWalletBalance_Success(t *testing.T) {
 	router.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"7"`, rr.Header().Get("ETag"))
 	
-	var response map[string]int64
+	var response models.BalanceResponse
 	json.Unmarshal(rr.Body.Bytes(), &response)
-	assert.Equal(t, expectedBalance, response["balance"])
+	assert.Equal(t, expectedBalance, response.Balance)
+	assert.Equal(t, models.Currency("RUB"), response.Currency)
+	assert.Equal(t, "15.00", response.BalanceDecimal)
 	
 	mockService.AssertExpectations(t)
 }
 
+func TestWalletHandler_GetWalletBalance_StrongRead(t *testing.T) {
+	mockService := new(MockService)
+	handler := NewWalletHandler(mockService)
+
+	walletID := uuid.New()
+	strong := mock.MatchedBy(func(ctx context.Context) bool { return repository.IsStrongRead(ctx) })
+	mockService.On("GetWallet", strong, walletID).Return(&models.Wallet{ID: walletID, Balance: 10, Version: 2}, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/wallets/{walletId}", handler.GetWalletBalance)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/wallets/"+walletID.String()+"?consistency=strong", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/wallets/"+walletID.String()+"?consistency=linearizable", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestWalletHandler_GetWalletBalanceAsOf(t *testing.T) {
+	mockService := new(MockService)
+	handler := NewWalletHandler(mockService)
+
+	walletID := uuid.New()
+	asOf := time.Date(2026, 9, 30, 20, 59, 59, 0, time.UTC)
+	mockService.On("BalanceAsOf", mock.Anything, walletID, mock.MatchedBy(asOf.Equal)).
+		Return(&models.BalanceResponse{Balance: 1250, Currency: "RUB", BalanceDecimal: "12.50", AsOf: &asOf}, nil)
+	mockService.On("GetWallet", mock.Anything, walletID).Return(&models.Wallet{ID: walletID, Balance: 1500, Version: 3, Currency: "RUB"}, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/wallets/{walletId}/balance", handler.GetWalletBalanceAsOf)
+	get := func(query string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/wallets/"+walletID.String()+"/balance"+query, nil))
+		return rr
+	}
+
+	rr := get("?asOf=2026-09-30T23:59:59%2B03:00")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response models.BalanceResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, int64(1250), response.Balance)
+	if assert.NotNil(t, response.AsOf) {
+		assert.True(t, asOf.Equal(*response.AsOf))
+	}
+
+	rr = get("")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"3"`, rr.Header().Get("ETag"))
+
+	rr = get("?asOf=yesterday")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, models.CodeInvalidAsOf, decodeProblem(t, rr).Code)
+
+	mockService.AssertExpectations(t)
+}
+
 func TestWalletHandler_GetWalletBalance_WalletNotFound(t *testing.T) {
 	mockService := new(MockService)
 	handler := NewWalletHandler(mockService)
 
 	walletID := uuid.New()
 
-	mockService.On("GetBalance", mock.Anything, walletID).Return(int64(0), repository.ErrWalletNotFound)
+	mockService.On("GetWallet", mock.Anything, walletID).Return(nil, repository.ErrWalletNotFound)
 
 	req := httptest.NewRequest("GET", "/api/v1/wallets/"+walletID.String(), nil)
 	rr := httptest.NewRecorder()
@@ -205,7 +538,7 @@ func TestWalletHandler_GetWalletBalance_InvalidUUID(t *testing.T) {
 	router.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusBadRequest, rr.Code)
-	mockService.AssertNotCalled(t, "GetBalance")
+	mockService.AssertNotCalled(t, "GetWallet")
 }
 
 func TestWalletHandler_GetWalletBalance_InternalError(t *testing.T) {
@@ -214,7 +547,7 @@ func TestWalletHandler_GetWalletBalance_InternalError(t *testing.T) {
 
 	walletID := uuid.New()
 
-	mockService.On("GetBalance", mock.Anything, walletID).Return(int64(0), assert.AnError)
+	mockService.On("GetWallet", mock.Anything, walletID).Return(nil, assert.AnError)
 
 	req := httptest.NewRequest("GET", "/api/v1/wallets/"+walletID.String(), nil)
 	rr := httptest.NewRecorder()
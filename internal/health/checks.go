@@ -0,0 +1,98 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func DatabaseCheck(db *sql.DB) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		return nil, db.PingContext(ctx)
+	}
+}
+
+// PoolCheck сообщает о насыщении пула, когда занято не меньше thresholdPercent
+// соединений от MaxOpenConns.
+func PoolCheck(db *sql.DB, thresholdPercent int) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		stats := db.Stats()
+		details := map[string]interface{}{
+			"maxOpen":   stats.MaxOpenConnections,
+			"open":      stats.OpenConnections,
+			"inUse":     stats.InUse,
+			"idle":      stats.Idle,
+			"waitCount": stats.WaitCount,
+		}
+
+		if stats.MaxOpenConnections <= 0 {
+			return details, nil
+		}
+
+		usage := stats.InUse * 100 / stats.MaxOpenConnections
+		details["usagePercent"] = usage
+		if usage >= thresholdPercent {
+			return details, fmt.Errorf("connection pool saturated: %d/%d in use", stats.InUse, stats.MaxOpenConnections)
+		}
+		return details, nil
+	}
+}
+
+// PgxDatabaseCheck — аналог DatabaseCheck для pgxpool.
+func PgxDatabaseCheck(pool *pgxpool.Pool) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		return nil, pool.Ping(ctx)
+	}
+}
+
+// PgxPoolCheck — аналог PoolCheck для pgxpool: насыщение считается от
+// MaxConns.
+func PgxPoolCheck(pool *pgxpool.Pool, thresholdPercent int) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		stats := pool.Stat()
+		details := map[string]interface{}{
+			"maxOpen":   stats.MaxConns(),
+			"open":      stats.TotalConns(),
+			"inUse":     stats.AcquiredConns(),
+			"idle":      stats.IdleConns(),
+			"waitCount": stats.EmptyAcquireCount(),
+		}
+
+		if stats.MaxConns() <= 0 {
+			return details, nil
+		}
+
+		usage := stats.AcquiredConns() * 100 / stats.MaxConns()
+		details["usagePercent"] = usage
+		if usage >= int32(thresholdPercent) {
+			return details, fmt.Errorf("connection pool saturated: %d/%d in use", stats.AcquiredConns(), stats.MaxConns())
+		}
+		return details, nil
+	}
+}
+
+type SchemaVersioner interface {
+	Version(ctx context.Context) (int64, error)
+	Latest() int64
+}
+
+// MigrationCheck проверяет, что к базе применены все миграции, встроенные в бинарник.
+func MigrationCheck(m SchemaVersioner) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		current, err := m.Version(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		details := map[string]interface{}{
+			"current":  current,
+			"expected": m.Latest(),
+		}
+		if current < m.Latest() {
+			return details, fmt.Errorf("schema version %d is behind expected %d", current, m.Latest())
+		}
+		return details, nil
+	}
+}
@@ -0,0 +1,69 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func okCheck(ctx context.Context) (map[string]interface{}, error) {
+	return map[string]interface{}{"ok": true}, nil
+}
+
+func TestChecker_Ready_AllChecksPass(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Register("database", okCheck)
+	checker.Register("pool", okCheck)
+
+	report := checker.Ready(context.Background())
+
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Len(t, report.Checks, 2)
+	assert.Equal(t, StatusOK, report.Checks["database"].Status)
+	assert.Equal(t, true, report.Checks["pool"].Details["ok"])
+}
+
+func TestChecker_Ready_FailingCheck(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Register("database", okCheck)
+	checker.Register("schema", func(ctx context.Context) (map[string]interface{}, error) {
+		return nil, errors.New("table wallets does not exist")
+	})
+
+	report := checker.Ready(context.Background())
+
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, StatusOK, report.Checks["database"].Status)
+	assert.Equal(t, StatusFail, report.Checks["schema"].Status)
+	assert.Equal(t, "table wallets does not exist", report.Checks["schema"].Error)
+}
+
+func TestChecker_Ready_CheckTimeout(t *testing.T) {
+	checker := NewChecker(20 * time.Millisecond)
+	checker.Register("database", func(ctx context.Context) (map[string]interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	start := time.Now()
+	report := checker.Ready(context.Background())
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["database"].Error)
+}
+
+func TestChecker_Ready_Draining(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Register("database", okCheck)
+
+	checker.SetDraining()
+	report := checker.Ready(context.Background())
+
+	assert.Equal(t, StatusFail, report.Status)
+	assert.True(t, report.Draining)
+	assert.Equal(t, StatusOK, report.Checks["database"].Status)
+}
@@ -0,0 +1,233 @@
+package load
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/bulk"
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/DisasterWoman/wallet-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/lib/pq"
+)
+
+const benchDSN = "host=localhost port=5433 user=wallet_user password=wallet_password dbname=wallet_db sslmode=disable"
+
+// BenchmarkUpdateBalance_HotWallet сравнивает пути записи на одном кошельке
+// при параллельной нагрузке:
+//
+//	go test ./internal/load -run '^$' -bench HotWallet -cpu 1,8,32
+func BenchmarkUpdateBalance_HotWallet(b *testing.B) {
+	db := openBenchDB(b)
+
+	postgres := repository.NewPostgresRepository(db)
+
+	benchmarks := []struct {
+		name string
+		repo repository.Repository
+	}{
+		{"locking", postgres},
+		{"conditional", repository.NewPostgresRepository(db, repository.WithConditionalUpdate())},
+		{"batching", repository.NewBatchingRepository(postgres, postgres, 2*time.Millisecond, 100)},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			walletID := uuid.New()
+			if _, err := db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 0, newOwner(b, db)); err != nil {
+				b.Fatal(err)
+			}
+			defer db.Exec("DELETE FROM wallets WHERE id = $1", walletID)
+
+			b.SetParallelism(4)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := bm.repo.UpdateBalance(context.Background(), walletID, 1); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkDrivers сравнивает реализации на lib/pq и pgx на чтении и записи
+// по множеству кошельков при одинаковом размере пула:
+//
+//	go test ./internal/load -run '^$' -bench Drivers -cpu 1,8,32
+func BenchmarkDrivers(b *testing.B) {
+	db := openBenchDB(b)
+	pool := openBenchPgxPool(b)
+	pgx := repository.NewPgxRepository(pool)
+
+	ownerID := newOwner(b, db)
+	wallets := make([]models.Wallet, 1000)
+	ids := make([]uuid.UUID, len(wallets))
+	for i := range wallets {
+		ids[i] = uuid.New()
+		wallets[i] = models.Wallet{ID: ids[i], Balance: 1_000_000, OwnerID: &ownerID}
+	}
+	importWallets(b, pgx, wallets)
+	defer db.Exec("DELETE FROM wallets WHERE id = ANY($1)", pqArray(ids))
+
+	drivers := []struct {
+		name string
+		repo repository.Repository
+	}{
+		{"pq", repository.NewPostgresRepository(db)},
+		{"pgx", pgx},
+	}
+
+	for _, driver := range drivers {
+		b.Run(driver.name+"/GetBalance", func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				rnd := rand.New(rand.NewSource(rand.Int63()))
+				for pb.Next() {
+					if _, err := driver.repo.GetBalance(context.Background(), ids[rnd.Intn(len(ids))]); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+		})
+
+		b.Run(driver.name+"/UpdateBalance", func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				rnd := rand.New(rand.NewSource(rand.Int63()))
+				for pb.Next() {
+					if _, err := driver.repo.UpdateBalance(context.Background(), ids[rnd.Intn(len(ids))], 1); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkBulkLoad сравнивает загрузку 1000 кошельков построчными INSERT
+// через lib/pq и импортом через pgx: COPY во временную таблицу, проверка и
+// перенос одной транзакцией.
+func BenchmarkBulkLoad(b *testing.B) {
+	db := openBenchDB(b)
+	pgx := repository.NewPgxRepository(openBenchPgxPool(b))
+
+	const size = 1000
+	ownerID := newOwner(b, db)
+	newBatch := func() ([]models.Wallet, []uuid.UUID) {
+		wallets := make([]models.Wallet, size)
+		ids := make([]uuid.UUID, size)
+		for i := range wallets {
+			ids[i] = uuid.New()
+			wallets[i] = models.Wallet{ID: ids[i], Balance: 100, OwnerID: &ownerID}
+		}
+		return wallets, ids
+	}
+
+	b.Run("pq/insert", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			wallets, ids := newBatch()
+			for _, wallet := range wallets {
+				if _, err := db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", wallet.ID, wallet.Balance, wallet.OwnerID); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+			db.Exec("DELETE FROM wallets WHERE id = ANY($1)", pqArray(ids))
+			b.StartTimer()
+		}
+	})
+
+	b.Run("pgx/import", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			wallets, ids := newBatch()
+			importWallets(b, pgx, wallets)
+			b.StopTimer()
+			db.Exec("DELETE FROM wallets WHERE id = ANY($1)", pqArray(ids))
+			b.StartTimer()
+		}
+	})
+}
+
+// importWallets загружает кошельки через ImportWallets, записывая их файлом
+// импорта, как это делает экспорт.
+func importWallets(b *testing.B, repo repository.WalletBulk, wallets []models.Wallet) {
+	b.Helper()
+
+	var file bytes.Buffer
+	writer, err := bulk.NewWriter(&file, models.BulkCSV)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := range wallets {
+		if wallets[i].Currency == "" {
+			wallets[i].Currency = models.DefaultCurrency
+		}
+		if err := writer.Write(&wallets[i]); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		b.Fatal(err)
+	}
+	reader, err := bulk.NewReader(&file, models.BulkCSV)
+	if err != nil {
+		b.Fatal(err)
+	}
+	report, err := repo.ImportWallets(context.Background(), reader, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if !report.Committed {
+		b.Fatalf("import rejected: %+v", report.Errors)
+	}
+}
+
+func openBenchDB(b *testing.B) *sql.DB {
+	b.Helper()
+
+	db, err := sql.Open("postgres", benchDSN)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+	if err := db.Ping(); err != nil {
+		b.Skipf("database is not available: %v", err)
+	}
+	db.SetMaxOpenConns(50)
+	return db
+}
+
+func openBenchPgxPool(b *testing.B) *pgxpool.Pool {
+	b.Helper()
+
+	config, err := pgxpool.ParseConfig(benchDSN)
+	if err != nil {
+		b.Fatal(err)
+	}
+	config.MaxConns = 50
+	config.AfterConnect = repository.PreparePgxStatements
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(pool.Close)
+	if err := pool.Ping(context.Background()); err != nil {
+		b.Skipf("database is not available: %v", err)
+	}
+	return pool
+}
+
+func pqArray(ids []uuid.UUID) interface{} {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = id.String()
+	}
+	return pq.Array(values)
+}
@@ -31,7 +31,7 @@ func TestWalletService_Load_1000RPS(t *testing.T) {
 	walletService := service.NewWalletService(repo)
 
 	walletID := uuid.New()
-	_, err = db.Exec("INSERT INTO wallets (id, balance) VALUES ($1, $2)", walletID, 0)
+	_, err = db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 0, newOwner(t, db))
 	assert.NoError(t, err)
 	defer db.Exec("DELETE FROM wallets WHERE id = $1", walletID)
 
@@ -57,7 +57,7 @@ func TestWalletService_Load_1000RPS(t *testing.T) {
 					Amount:        1,
 				}
 				
-				err := walletService.UpdateBalance(context.Background(), req)
+				_, err := walletService.UpdateBalance(context.Background(), req)
 				if err != nil {
 					errorCh <- fmt.Errorf("worker %d, request %d: %w", workerID, j, err)
 				} else {
@@ -114,7 +114,7 @@ func TestWalletService_Load_StableConcurrentDeposits(t *testing.T) {
 	walletService := service.NewWalletService(repo)
 
 	walletID := uuid.New()
-	_, err = db.Exec("INSERT INTO wallets (id, balance) VALUES ($1, $2)", walletID, 0)
+	_, err = db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 0, newOwner(t, db))
 	assert.NoError(t, err)
 	defer db.Exec("DELETE FROM wallets WHERE id = $1", walletID)
 
@@ -140,7 +140,7 @@ func TestWalletService_Load_StableConcurrentDeposits(t *testing.T) {
 					Amount:        1,
 				}
 				
-				err := walletService.UpdateBalance(context.Background(), req)
+				_, err := walletService.UpdateBalance(context.Background(), req)
 				if err != nil {
 					errorCh <- fmt.Errorf("worker %d: %w", workerID, err)
 				} else {
@@ -175,4 +175,179 @@ func TestWalletService_Load_StableConcurrentDeposits(t *testing.T) {
 	assert.Equal(t, 0, errorCount, "Pure deposits should have no errors")
 	assert.Equal(t, int64(totalRequests), finalBalance, "Final balance should match total deposits")
 	assert.True(t, actualRPS >= 200, "Should handle at least 200 RPS for deposits, got %.2f", actualRPS)
-}
\ No newline at end of file
+}
+func TestWalletService_Load_ShardedHotWallet(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping load test in short mode")
+	}
+
+	connStr := "host=localhost port=5433 user=wallet_user password=wallet_password dbname=wallet_db sslmode=disable"
+	db, err := sql.Open("postgres", connStr)
+	assert.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(50)
+
+	repo := repository.NewPostgresRepository(db, repository.WithSharding())
+	walletService := service.NewWalletService(repo)
+
+	walletID := uuid.New()
+	_, err = db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 0, newOwner(t, db))
+	assert.NoError(t, err)
+	defer db.Exec("DELETE FROM wallets WHERE id = $1", walletID)
+	assert.NoError(t, repo.SetShardCount(context.Background(), walletID, 16))
+
+	totalRequests := 2000
+	concurrentWorkers := 100
+	requestsPerWorker := totalRequests / concurrentWorkers
+
+	var wg sync.WaitGroup
+	errorCh := make(chan error, totalRequests)
+
+	startTime := time.Now()
+
+	for i := 0; i < concurrentWorkers; i++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+
+			for j := 0; j < requestsPerWorker; j++ {
+				req := &models.OperationRequest{
+					WalletID:      walletID,
+					OperationType: models.Deposit,
+					Amount:        2,
+				}
+				// Каждое пятое обращение — списание, чтобы задействовать
+				// и быстрый путь по шардам, и перераспределение остатка.
+				if j%5 == 4 {
+					req.OperationType = models.Withdraw
+					req.Amount = 3
+				}
+
+				if _, err := walletService.UpdateBalance(context.Background(), req); err != nil {
+					errorCh <- fmt.Errorf("worker %d, request %d: %w", workerID, j, err)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errorCh)
+
+	duration := time.Since(startTime)
+	actualRPS := float64(totalRequests) / duration.Seconds()
+
+	errorCount := 0
+	for err := range errorCh {
+		t.Log(err)
+		errorCount++
+	}
+
+	finalBalance, err := walletService.GetBalance(context.Background(), walletID)
+	assert.NoError(t, err)
+
+	deposits := int64(totalRequests * 4 / 5)
+	withdrawals := int64(totalRequests / 5)
+
+	t.Logf("Sharded Hot Wallet Test (16 shards):")
+	t.Logf("Duration: %v", duration)
+	t.Logf("Total Requests: %d", totalRequests)
+	t.Logf("Errors: %d", errorCount)
+	t.Logf("Actual RPS: %.2f", actualRPS)
+	t.Logf("Final Balance: %d", finalBalance)
+
+	assert.Equal(t, 0, errorCount, "Withdrawals are always covered by earlier deposits of the same worker")
+	assert.Equal(t, deposits*2-withdrawals*3, finalBalance)
+	assert.True(t, finalBalance >= 0, "Balance must never go negative")
+}
+
+func TestWalletService_Load_BatchedHotWallet(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping load test in short mode")
+	}
+
+	connStr := "host=localhost port=5433 user=wallet_user password=wallet_password dbname=wallet_db sslmode=disable"
+	db, err := sql.Open("postgres", connStr)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	postgres := repository.NewPostgresRepository(db)
+	repo := repository.NewBatchingRepository(postgres, postgres, 2*time.Millisecond, 100)
+	walletService := service.NewWalletService(repo)
+
+	walletID := uuid.New()
+	_, err = db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 0, newOwner(t, db))
+	assert.NoError(t, err)
+	defer db.Exec("DELETE FROM wallets WHERE id = $1", walletID)
+
+	totalRequests := 2000
+	concurrentWorkers := 100
+	requestsPerWorker := totalRequests / concurrentWorkers
+
+	var wg sync.WaitGroup
+	errorCh := make(chan error, totalRequests)
+
+	startTime := time.Now()
+
+	for i := 0; i < concurrentWorkers; i++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+
+			for j := 0; j < requestsPerWorker; j++ {
+				req := &models.OperationRequest{
+					WalletID:      walletID,
+					OperationType: models.Deposit,
+					Amount:        2,
+				}
+				if j%5 == 4 {
+					req.OperationType = models.Withdraw
+					req.Amount = 3
+				}
+
+				if _, err := walletService.UpdateBalance(context.Background(), req); err != nil {
+					errorCh <- fmt.Errorf("worker %d, request %d: %w", workerID, j, err)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errorCh)
+
+	duration := time.Since(startTime)
+	actualRPS := float64(totalRequests) / duration.Seconds()
+
+	errorCount := 0
+	for err := range errorCh {
+		t.Log(err)
+		errorCount++
+	}
+
+	finalBalance, err := walletService.GetBalance(context.Background(), walletID)
+	assert.NoError(t, err)
+
+	deposits := int64(totalRequests * 4 / 5)
+	withdrawals := int64(totalRequests / 5)
+
+	t.Logf("Batched Hot Wallet Test (window 2ms, max 100):")
+	t.Logf("Duration: %v", duration)
+	t.Logf("Total Requests: %d", totalRequests)
+	t.Logf("Errors: %d", errorCount)
+	t.Logf("Actual RPS: %.2f", actualRPS)
+	t.Logf("Final Balance: %d", finalBalance)
+
+	assert.Equal(t, 0, errorCount, "Withdrawals are always covered by earlier deposits of the same worker")
+	assert.Equal(t, deposits*2-withdrawals*3, finalBalance)
+}
+
+// newOwner заводит владельца для тестового кошелька: база не принимает
+// кошелёк без владельца.
+func newOwner(t testing.TB, db *sql.DB) uuid.UUID {
+	t.Helper()
+
+	ownerID := uuid.New()
+	if _, err := db.Exec("INSERT INTO owners (id, name) VALUES ($1, $2)", ownerID, "load"); err != nil {
+		t.Fatal(err)
+	}
+	return ownerID
+}
@@ -0,0 +1,154 @@
+// Package metrics содержит метрики сервиса в формате Prometheus. Метрики
+// регистрируются в реестре по умолчанию и отдаются на /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// TxRetries — число повторов транзакций после конфликта сериализации
+	// или взаимной блокировки.
+	TxRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "wallet",
+		Subsystem: "db",
+		Name:      "tx_retries_total",
+		Help:      "Transactions retried after a serialization failure or deadlock.",
+	}, []string{"operation", "reason"})
+
+	// TxRetriesExhausted — число операций, которые не удалось выполнить за
+	// отведённое число попыток.
+	TxRetriesExhausted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "wallet",
+		Subsystem: "db",
+		Name:      "tx_retries_exhausted_total",
+		Help:      "Operations that failed after using up all transaction attempts.",
+	}, []string{"operation"})
+
+	// TxTimeouts — операции, прерванные по таймауту, по виду: lock
+	// (lock_timeout), statement (statement_timeout) или deadline (дедлайн
+	// запроса).
+	TxTimeouts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "wallet",
+		Subsystem: "db",
+		Name:      "tx_timeouts_total",
+		Help:      "Operations aborted by a lock, statement or request timeout.",
+	}, []string{"operation", "kind"})
+
+	// CacheRequests — чтения кошельков через кэш по результату: hit или miss.
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "wallet",
+		Subsystem: "cache",
+		Name:      "requests_total",
+		Help:      "Wallet reads served through the cache, by result.",
+	}, []string{"result"})
+
+	// CacheInvalidations — инвалидации кэша по источнику: local (запись в
+	// этом инстансе), notify (NOTIFY из базы) или reset (сброс всего кэша
+	// после потери соединения слушателя).
+	CacheInvalidations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "wallet",
+		Subsystem: "cache",
+		Name:      "invalidations_total",
+		Help:      "Wallet cache invalidations, by source.",
+	}, []string{"source"})
+
+	// ReplicaReads — чтения по месту выполнения: replica, primary (подходящей
+	// реплики нет или запрошено строгое чтение) или fallback (реплика
+	// ответила ошибкой, чтение повторено на основной базе).
+	ReplicaReads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "wallet",
+		Subsystem: "db",
+		Name:      "reads_total",
+		Help:      "Wallet reads, by where they were served.",
+	}, []string{"target"})
+
+	// ReplicaLag — последнее измеренное отставание реплики; -1, если
+	// реплика недоступна.
+	ReplicaLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "wallet",
+		Subsystem: "db",
+		Name:      "replica_lag_seconds",
+		Help:      "Last measured replication lag, -1 when unknown.",
+	}, []string{"replica"})
+
+	// InFlightOperations — операции репозитория, занимающие слот
+	// LimitingRepository.
+	InFlightOperations = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "wallet",
+		Subsystem: "db",
+		Name:      "in_flight_operations",
+		Help:      "Repository operations currently holding an in-flight slot.",
+	})
+
+	// InFlightStatements — выписки, занимающие слот выписки
+	// LimitingRepository.
+	InFlightStatements = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "wallet",
+		Subsystem: "db",
+		Name:      "in_flight_statements",
+		Help:      "Statement downloads currently holding a statement slot.",
+	})
+
+	// ShedOperations — операции, отклонённые с ErrOverloaded.
+	ShedOperations = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "wallet",
+		Subsystem: "db",
+		Name:      "shed_operations_total",
+		Help:      "Repository operations rejected because no in-flight slot freed up in time.",
+	})
+
+	// CheckpointAsOf — момент последней записанной контрольной точки
+	// баланса, Unix-время в секундах.
+	CheckpointAsOf = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "wallet",
+		Subsystem: "db",
+		Name:      "balance_checkpoint_timestamp_seconds",
+		Help:      "As-of time of the latest balance checkpoint run.",
+	})
+
+	// CheckpointsCreated — записанные контрольные точки баланса.
+	CheckpointsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "wallet",
+		Subsystem: "db",
+		Name:      "balance_checkpoints_total",
+		Help:      "Balance checkpoint rows written.",
+	})
+
+	// LedgerDiscrepancies — расхождения каждого вида, найденные последней
+	// сверкой журнала в этом инстансе. Сверку выполняет один инстанс за
+	// раз, поэтому в оповещениях стоит брать max по инстансам.
+	LedgerDiscrepancies = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "wallet",
+		Subsystem: "ledger",
+		Name:      "discrepancies",
+		Help:      "Discrepancies found by the latest ledger reconciliation, by kind.",
+	}, []string{"kind"})
+
+	// ReconciliationFinished — время окончания последней сверки журнала,
+	// Unix-время в секундах.
+	ReconciliationFinished = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "wallet",
+		Subsystem: "ledger",
+		Name:      "reconciliation_timestamp_seconds",
+		Help:      "Finish time of the latest ledger reconciliation run.",
+	})
+
+	// ReconciliationFailures — сверки журнала, завершившиеся ошибкой.
+	ReconciliationFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "wallet",
+		Subsystem: "ledger",
+		Name:      "reconciliation_failures_total",
+		Help:      "Ledger reconciliation runs that failed with an error.",
+	})
+
+	// RateLimited — запросы, отклонённые ограничением частоты, по области:
+	// api_key, ip или wallet.
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "wallet",
+		Subsystem: "http",
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected by rate limiting, by scope.",
+	}, []string{"scope"})
+)
@@ -0,0 +1,64 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/DisasterWoman/wallet-service/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_OrdersByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_status.up.sql":        {Data: []byte("ALTER TABLE wallets ADD COLUMN status TEXT;")},
+		"0002_add_status.down.sql":      {Data: []byte("ALTER TABLE wallets DROP COLUMN status;")},
+		"0001_create_wallets.up.sql":    {Data: []byte("CREATE TABLE wallets (id UUID);")},
+		"0001_create_wallets.down.sql":  {Data: []byte("DROP TABLE wallets;")},
+		"0010_create_operations.up.sql": {Data: []byte("CREATE TABLE operations (id BIGINT);")},
+		"README.md":                     {Data: []byte("not a migration")},
+	}
+
+	loaded, err := Load(fsys)
+
+	require.NoError(t, err)
+	require.Len(t, loaded, 3)
+	assert.Equal(t, int64(1), loaded[0].Version)
+	assert.Equal(t, "create_wallets", loaded[0].Name)
+	assert.Equal(t, "DROP TABLE wallets;", loaded[0].Down)
+	assert.Equal(t, int64(2), loaded[1].Version)
+	assert.Equal(t, int64(10), loaded[2].Version)
+	assert.Empty(t, loaded[2].Down)
+}
+
+func TestLoad_MissingUpScript(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_create_wallets.down.sql": {Data: []byte("DROP TABLE wallets;")},
+	}
+
+	_, err := Load(fsys)
+
+	assert.Error(t, err)
+}
+
+func TestLoad_ConflictingNames(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_create_wallets.up.sql": {Data: []byte("CREATE TABLE wallets (id UUID);")},
+		"0001_create_owners.up.sql":  {Data: []byte("CREATE TABLE owners (id UUID);")},
+	}
+
+	_, err := Load(fsys)
+
+	assert.Error(t, err)
+}
+
+func TestLoad_EmbeddedMigrations(t *testing.T) {
+	loaded, err := Load(migrations.FS)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, loaded)
+	for i, m := range loaded {
+		assert.Equal(t, int64(i+1), m.Version, "migration versions must be sequential")
+		assert.NotEmpty(t, m.Down, "migration %d_%s must have a down script", m.Version, m.Name)
+	}
+}
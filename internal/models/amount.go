@@ -0,0 +1,108 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Currency — код валюты ISO 4217. Суммы в API и в базе хранятся в
+// минимальных единицах валюты; экспонента валюты связывает их с основными
+// единицами, в которых работают клиенты.
+type Currency string
+
+// DefaultCurrency — валюта кошельков, для которых она не задана явно.
+const DefaultCurrency = Currency("RUB")
+
+// currencyExponents — число знаков после запятой у поддерживаемых валют.
+var currencyExponents = map[Currency]int{
+	"RUB": 2, "USD": 2, "EUR": 2, "GBP": 2, "CHF": 2, "CNY": 2,
+	"KZT": 2, "BYN": 2, "UAH": 2, "TRY": 2, "AED": 2,
+	"JPY": 0, "KRW": 0, "VND": 0,
+	"BHD": 3, "KWD": 3, "OMR": 3, "TND": 3,
+}
+
+// Valid сообщает, поддерживается ли валюта.
+func (c Currency) Valid() bool {
+	_, ok := currencyExponents[c]
+	return ok
+}
+
+// Exponent возвращает число знаков после запятой у валюты.
+func (c Currency) Exponent() int {
+	if exponent, ok := currencyExponents[c]; ok {
+		return exponent
+	}
+	return currencyExponents[DefaultCurrency]
+}
+
+// maxAmountDigits — наибольшее число цифр суммы в минимальных единицах,
+// которое заведомо помещается в int64.
+const maxAmountDigits = 18
+
+// ParseAmount переводит неотрицательную десятичную строку в основных
+// единицах ("12.50") в минимальные единицы валюты с экспонентой exponent.
+// Разбор идёт по цифрам, без float64: сумма либо представима точно, либо
+// отклоняется. Лишние значащие знаки после запятой — ErrAmountPrecision,
+// завершающие нули допускаются.
+func ParseAmount(s string, exponent int) (int64, error) {
+	if !isDecimal(s) {
+		return 0, ErrAmountFormat
+	}
+	whole, fraction, _ := strings.Cut(s, ".")
+
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > exponent {
+		return 0, ErrAmountPrecision
+	}
+
+	digits := strings.TrimLeft(whole, "0") + fraction + strings.Repeat("0", exponent-len(fraction))
+	if len(digits) > maxAmountDigits {
+		return 0, ErrAmountTooLarge
+	}
+	if digits == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(digits, 10, 64)
+}
+
+// FormatAmount записывает сумму в минимальных единицах десятичной строкой
+// в основных единицах валюты с экспонентой exponent: 1250 и 2 дают "12.50".
+func FormatAmount(amount int64, exponent int) string {
+	return formatDecimal(strconv.FormatInt(amount, 10), exponent)
+}
+
+// formatDecimal ставит десятичную точку в целое число digits, записанное
+// в минимальных единицах.
+func formatDecimal(digits string, exponent int) string {
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	point := len(digits) - exponent
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// isDecimal сообщает, записана ли s как неотрицательное десятичное число
+// без знака и экспоненты: "12", "12.5", "0.05".
+func isDecimal(s string) bool {
+	whole, fraction, hasPoint := strings.Cut(s, ".")
+	return isDigits(whole) && (!hasPoint || isDigits(fraction))
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
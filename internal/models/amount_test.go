@@ -0,0 +1,111 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		exponent int
+		want     int64
+		wantErr  error
+	}{
+		{"major and minor", "12.50", 2, 1250, nil},
+		{"whole", "12", 2, 1200, nil},
+		{"minor only", "0.05", 2, 5, nil},
+		{"trailing zeros", "12.500", 2, 1250, nil},
+		{"leading zeros", "007.1", 2, 710, nil},
+		{"zero exponent", "1500", 0, 1500, nil},
+		{"three digits", "1.234", 3, 1234, nil},
+		{"excess precision", "12.505", 2, 0, ErrAmountPrecision},
+		{"fraction with zero exponent", "1.5", 0, 0, ErrAmountPrecision},
+		{"too many digits", "100000000000000000", 2, 0, ErrAmountTooLarge},
+		{"negative", "-1.00", 2, 0, ErrAmountFormat},
+		{"exponent notation", "1e3", 2, 0, ErrAmountFormat},
+		{"empty fraction", "12.", 2, 0, ErrAmountFormat},
+		{"empty whole", ".5", 2, 0, ErrAmountFormat},
+		{"comma", "12,50", 2, 0, ErrAmountFormat},
+		{"empty", "", 2, 0, ErrAmountFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAmount(tt.s, tt.exponent)
+
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	assert.Equal(t, "12.50", FormatAmount(1250, 2))
+	assert.Equal(t, "0.05", FormatAmount(5, 2))
+	assert.Equal(t, "0.00", FormatAmount(0, 2))
+	assert.Equal(t, "-0.05", FormatAmount(-5, 2))
+	assert.Equal(t, "1500", FormatAmount(1500, 0))
+	assert.Equal(t, "1.234", FormatAmount(1234, 3))
+}
+
+func TestOperationRequest_UnmarshalJSON(t *testing.T) {
+	t.Run("minor units", func(t *testing.T) {
+		var req OperationRequest
+		require.NoError(t, json.Unmarshal([]byte(`{"operationType":"DEPOSIT","amount":1250}`), &req))
+
+		assert.Equal(t, int64(1250), req.Amount)
+		assert.False(t, req.DecimalAmount())
+	})
+
+	t.Run("decimal string", func(t *testing.T) {
+		var req OperationRequest
+		require.NoError(t, json.Unmarshal([]byte(`{"operationType":"DEPOSIT","amount":"12.5"}`), &req))
+		assert.True(t, req.DecimalAmount())
+		assert.Equal(t, Currency(""), req.Currency())
+
+		require.NoError(t, req.ResolveAmount("USD"))
+		assert.Equal(t, int64(1250), req.Amount)
+	})
+
+	t.Run("fractional number", func(t *testing.T) {
+		var req OperationRequest
+		err := json.Unmarshal([]byte(`{"amount":12.5}`), &req)
+
+		var typeError *json.UnmarshalTypeError
+		require.ErrorAs(t, err, &typeError)
+		assert.Equal(t, "amount", typeError.Field)
+	})
+
+	t.Run("malformed decimal", func(t *testing.T) {
+		var req OperationRequest
+		require.NoError(t, json.Unmarshal([]byte(`{"walletId":"4f1c6a9e-0000-4000-8000-000000000001","operationType":"DEPOSIT","amount":"12,50"}`), &req))
+
+		err := req.Validate()
+		assert.ErrorIs(t, err, ErrAmountFormat)
+		assert.NotErrorIs(t, err, ErrInvalidAmount, "the shared code must not make a format error look like a non-positive amount")
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		var req OperationRequest
+		assert.Error(t, json.Unmarshal([]byte(`{"amount":1,"currency":"USD"}`), &req))
+	})
+}
+
+func TestOperationRequest_ResolveAmount(t *testing.T) {
+	var req OperationRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":"12.50"}`), &req))
+
+	require.NoError(t, req.ResolveAmount("USD"))
+	assert.Equal(t, int64(1250), req.Amount)
+	assert.Equal(t, Currency("USD"), req.Currency())
+
+	assert.ErrorIs(t, req.ResolveAmount("JPY"), ErrAmountPrecision)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":"0.00"}`), &req))
+	assert.ErrorIs(t, req.ResolveAmount("USD"), ErrInvalidAmount)
+}
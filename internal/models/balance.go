@@ -0,0 +1,28 @@
+package models
+
+import "math"
+
+// MaxBalance — потолок баланса кошелька по умолчанию и наибольший
+// допустимый лимит. MaxBalance + MaxAmount намного меньше math.MaxInt64.
+const MaxBalance int64 = 1_000_000_000_000_000_000
+
+// ApplyAmount возвращает balance + amount. Если результат меньше нуля,
+// возвращается ErrInsufficientFunds, если больше limit или не помещается в
+// int64 — ErrBalanceLimitExceeded.
+func ApplyAmount(balance, amount, limit int64) (int64, error) {
+	switch {
+	case amount > 0 && balance > math.MaxInt64-amount:
+		return 0, ErrBalanceLimitExceeded
+	case amount < 0 && balance < math.MinInt64-amount:
+		return 0, ErrInsufficientFunds
+	}
+
+	next := balance + amount
+	switch {
+	case next < 0 && amount < 0:
+		return 0, ErrInsufficientFunds
+	case next > limit && amount > 0:
+		return 0, ErrBalanceLimitExceeded
+	}
+	return next, nil
+}
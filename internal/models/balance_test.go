@@ -0,0 +1,37 @@
+package models
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyAmount(t *testing.T) {
+	tests := []struct {
+		name    string
+		balance int64
+		amount  int64
+		limit   int64
+		want    int64
+		wantErr error
+	}{
+		{"deposit", 100, 50, 1000, 150, nil},
+		{"deposit to limit", 100, 900, 1000, 1000, nil},
+		{"deposit over limit", 100, 901, 1000, 0, ErrBalanceLimitExceeded},
+		{"int64 overflow", math.MaxInt64 - 1, 2, math.MaxInt64, 0, ErrBalanceLimitExceeded},
+		{"withdraw", 100, -100, 1000, 0, nil},
+		{"overdraft", 100, -101, 1000, 0, ErrInsufficientFunds},
+		{"int64 underflow", 0, math.MinInt64, 1000, 0, ErrInsufficientFunds},
+		{"withdraw above lowered limit", 2000, -10, 1000, 1990, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyAmount(tt.balance, tt.amount, tt.limit)
+
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
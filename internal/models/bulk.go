@@ -0,0 +1,118 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/google/uuid"
+)
+
+// BulkFormat — формат файла импорта и экспорта кошельков.
+type BulkFormat string
+
+const (
+	BulkCSV   BulkFormat = "csv"
+	BulkJSONL BulkFormat = "jsonl"
+)
+
+// ParseBulkFormat разбирает формат файла; пустая строка означает CSV.
+func ParseBulkFormat(s string) (BulkFormat, error) {
+	switch format := BulkFormat(s); format {
+	case "":
+		return BulkCSV, nil
+	case BulkCSV, BulkJSONL:
+		return format, nil
+	default:
+		return "", ErrInvalidFormat
+	}
+}
+
+// ImportedWallet — проверенная запись файла импорта. Line — номер строки
+// файла, с которой начинается запись. Пустые MaxBalance, Labels и Metadata
+// оставляют значения по умолчанию: лимит MaxBalance и пустые объекты.
+type ImportedWallet struct {
+	Line       int64
+	ID         uuid.UUID
+	OwnerID    uuid.UUID
+	Currency   Currency
+	Balance    int64
+	Status     WalletStatus
+	MaxBalance *int64
+	Labels     map[string]string
+	Metadata   json.RawMessage
+}
+
+// ImportError — ошибка одной записи файла импорта.
+type ImportError struct {
+	Line     int64      `json:"line" example:"12"`
+	WalletID *uuid.UUID `json:"walletId,omitempty"`
+	Code     Code       `json:"code" example:"DUPLICATE_WALLET"`
+	Message  string     `json:"message" example:"wallet ID repeats an earlier record"`
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// NewImportError описывает ошибку err в записи со строки line.
+func NewImportError(line int64, walletID *uuid.UUID, err *Error) *ImportError {
+	return &ImportError{Line: line, WalletID: walletID, Code: err.Code, Message: err.Message}
+}
+
+// MaxImportErrors — сколько ошибок записей перечисляет отчёт импорта;
+// остальные только учитываются в ErrorCount.
+const MaxImportErrors = 100
+
+// ImportReport — отчёт импорта со сверкой итогов: Input — итоги по валютам
+// по проверенным записям файла, Loaded — итоги тех же кошельков,
+// прочитанные из базы после загрузки. Загрузка фиксируется, только если
+// ошибок нет, итоги сошлись и это не пробный запуск.
+type ImportReport struct {
+	DryRun        bool            `json:"dryRun"`
+	Committed     bool            `json:"committed"`
+	Records       int64           `json:"records" example:"1000"`
+	Wallets       int64           `json:"wallets" example:"1000"`
+	OwnersCreated int64           `json:"ownersCreated" example:"250"`
+	Input         []CurrencyTotal `json:"input"`
+	Loaded        []CurrencyTotal `json:"loaded"`
+	Balanced      bool            `json:"balanced"`
+	ErrorCount    int64           `json:"errorCount"`
+	Errors        []ImportError   `json:"errors,omitempty"`
+
+	input CurrencyTotals
+}
+
+// NewImportReport создаёт пустой отчёт импорта.
+func NewImportReport(dryRun bool) *ImportReport {
+	return &ImportReport{DryRun: dryRun, Input: []CurrencyTotal{}, Loaded: []CurrencyTotal{}, input: make(CurrencyTotals)}
+}
+
+// AddRecord учитывает проверенную запись файла в итогах Input.
+func (r *ImportReport) AddRecord(wallet *ImportedWallet) {
+	r.Records++
+	r.input.Add(wallet.Currency, 1, big.NewInt(wallet.Balance))
+}
+
+// AddInvalid учитывает запись, не прошедшую проверку: она считается в
+// Records, но не в итогах.
+func (r *ImportReport) AddInvalid(err *ImportError) {
+	r.Records++
+	r.AddError(err)
+}
+
+// AddError учитывает ошибку проверенной записи, например дубликат.
+func (r *ImportReport) AddError(err *ImportError) {
+	r.ErrorCount++
+	if len(r.Errors) < MaxImportErrors {
+		r.Errors = append(r.Errors, *err)
+	}
+}
+
+// Reconcile записывает итоги файла и загруженных кошельков и сверяет их.
+// Если загрузка не дошла до записи в базу, loaded пуст.
+func (r *ImportReport) Reconcile(loaded CurrencyTotals) {
+	r.Input = r.input.List()
+	r.Loaded = loaded.List()
+	r.Balanced = r.input.Equal(loaded)
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBulkFormat(t *testing.T) {
+	for input, want := range map[string]BulkFormat{"": BulkCSV, "csv": BulkCSV, "jsonl": BulkJSONL} {
+		format, err := ParseBulkFormat(input)
+		require.NoError(t, err)
+		assert.Equal(t, want, format)
+	}
+
+	_, err := ParseBulkFormat("xlsx")
+	assert.ErrorIs(t, err, ErrInvalidFormat)
+}
+
+func TestImportReport(t *testing.T) {
+	report := NewImportReport(false)
+	report.AddRecord(&ImportedWallet{ID: uuid.New(), Currency: "RUB", Balance: 1250})
+	report.AddRecord(&ImportedWallet{ID: uuid.New(), Currency: "RUB", Balance: 50})
+	report.AddRecord(&ImportedWallet{ID: uuid.New(), Currency: "JPY", Balance: 7})
+	report.AddInvalid(&ImportError{Line: 5, Code: CodeInvalidCurrency})
+
+	loaded := make(CurrencyTotals)
+	loaded.Add("RUB", 2, big.NewInt(1300))
+	loaded.Add("JPY", 1, big.NewInt(7))
+	report.Reconcile(loaded)
+
+	assert.Equal(t, int64(4), report.Records)
+	assert.Equal(t, int64(1), report.ErrorCount)
+	assert.True(t, report.Balanced)
+	require.Len(t, report.Input, 2)
+	assert.Equal(t, Currency("JPY"), report.Input[0].Currency)
+	assert.Equal(t, "13.00", report.Input[1].BalanceDecimal)
+
+	loaded.Add("RUB", 0, big.NewInt(1))
+	report.Reconcile(loaded)
+	assert.False(t, report.Balanced)
+}
+
+func TestImportReport_ErrorLimit(t *testing.T) {
+	report := NewImportReport(true)
+	for i := 0; i < MaxImportErrors+5; i++ {
+		report.AddError(&ImportError{Line: int64(i), Code: CodeDuplicateWallet})
+	}
+	assert.Equal(t, int64(MaxImportErrors+5), report.ErrorCount)
+	assert.Len(t, report.Errors, MaxImportErrors)
+	assert.Zero(t, report.Records)
+}
@@ -0,0 +1,114 @@
+package models
+
+// Code — стабильный машиночитаемый код ошибки API. Клиенты ветвятся по нему,
+// а не по тексту ошибки, поэтому существующие коды не переименовываются.
+type Code string
+
+const (
+	CodeWalletNotFound       Code = "WALLET_NOT_FOUND"
+	CodeInsufficientFunds    Code = "INSUFFICIENT_FUNDS"
+	CodeBalanceLimitExceeded Code = "BALANCE_LIMIT_EXCEEDED"
+	CodeInvalidBalanceLimit  Code = "INVALID_BALANCE_LIMIT"
+	CodeInvalidOperationType Code = "INVALID_OPERATION_TYPE"
+	CodeInvalidAmount        Code = "INVALID_AMOUNT"
+	CodeWalletFrozen         Code = "WALLET_FROZEN"
+	CodeSameWallet           Code = "SAME_WALLET"
+	CodeInvalidLimit         Code = "INVALID_LIMIT"
+	CodeVersionMismatch      Code = "VERSION_MISMATCH"
+	CodeInvalidWalletID      Code = "INVALID_WALLET_ID"
+	CodeAmountTooLarge       Code = "AMOUNT_TOO_LARGE"
+	CodeValidationFailed     Code = "VALIDATION_FAILED"
+	CodeAmountPrecision      Code = "AMOUNT_PRECISION"
+	CodeInvalidCurrency      Code = "INVALID_CURRENCY"
+	CodeWalletNotEmpty       Code = "WALLET_NOT_EMPTY"
+	CodeOwnerNotFound        Code = "OWNER_NOT_FOUND"
+	CodeInvalidOwnerID       Code = "INVALID_OWNER_ID"
+	CodeInvalidLabel         Code = "INVALID_LABEL"
+	CodeInvalidMetadata      Code = "INVALID_METADATA"
+	CodeInvalidFilter        Code = "INVALID_FILTER"
+	CodeInvalidAsOf          Code = "INVALID_AS_OF"
+	CodeInvalidPeriod        Code = "INVALID_PERIOD"
+	CodeInvalidFormat        Code = "INVALID_FORMAT"
+	CodeInvalidRecord        Code = "INVALID_RECORD"
+	CodeDuplicateWallet      Code = "DUPLICATE_WALLET"
+	CodeWalletExists         Code = "WALLET_EXISTS"
+	CodeImportRejected       Code = "IMPORT_REJECTED"
+
+	// Коды ошибок транспорта и инфраструктуры: у них нет доменной
+	// переменной Err*, их выставляет обработчик.
+	CodeInvalidRequest  Code = "INVALID_REQUEST"
+	CodeUnauthorized    Code = "UNAUTHORIZED"
+	CodeRequestTooLarge Code = "REQUEST_TOO_LARGE"
+	CodeRateLimited     Code = "RATE_LIMITED"
+	CodeWalletBusy      Code = "WALLET_BUSY"
+	CodeOverloaded      Code = "SERVICE_OVERLOADED"
+	CodeTimeout         Code = "TIMEOUT"
+	CodeInternal        Code = "INTERNAL_ERROR"
+)
+
+// Kind — категория доменной ошибки, по которой транспорт выбирает статус
+// ответа.
+type Kind int
+
+const (
+	KindInvalid Kind = iota + 1
+	KindNotFound
+	KindConflict
+	KindPrecondition
+)
+
+// Error — доменная ошибка с кодом для клиентов API. Переменные Err* —
+// единственные экземпляры, поэтому errors.Is находит их и в обёрнутых
+// ошибках, а errors.As даёт доступ к коду и категории.
+type Error struct {
+	Code    Code
+	Kind    Kind
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+var (
+	ErrWalletNotFound       = &Error{Code: CodeWalletNotFound, Kind: KindNotFound, Message: "wallet not found"}
+	ErrInvalidAmount        = &Error{Code: CodeInvalidAmount, Kind: KindInvalid, Message: "amount must be positive"}
+	ErrWalletIDRequired     = &Error{Code: CodeInvalidWalletID, Kind: KindInvalid, Message: "wallet ID is required"}
+	ErrAmountTooLarge       = &Error{Code: CodeAmountTooLarge, Kind: KindInvalid, Message: "amount exceeds the maximum"}
+	ErrAmountFormat         = &Error{Code: CodeInvalidAmount, Kind: KindInvalid, Message: "amount must be an integer in minor units or a decimal string like \"12.50\""}
+	ErrAmountPrecision      = &Error{Code: CodeAmountPrecision, Kind: KindInvalid, Message: "amount has more decimal places than the wallet currency allows"}
+	ErrInvalidCurrency      = &Error{Code: CodeInvalidCurrency, Kind: KindInvalid, Message: "currency is not supported"}
+	ErrOwnerNotFound        = &Error{Code: CodeOwnerNotFound, Kind: KindNotFound, Message: "owner not found"}
+	ErrOwnerIDRequired      = &Error{Code: CodeInvalidOwnerID, Kind: KindInvalid, Message: "owner ID is required"}
+	ErrInvalidLabel         = &Error{Code: CodeInvalidLabel, Kind: KindInvalid, Message: "label keys must match [a-z0-9][a-z0-9._/-]{0,62} and values be at most 256 bytes, 64 labels at most"}
+	ErrInvalidMetadata      = &Error{Code: CodeInvalidMetadata, Kind: KindInvalid, Message: "metadata must be a JSON object of at most 16 KiB"}
+	ErrInvalidFilter        = &Error{Code: CodeInvalidFilter, Kind: KindInvalid, Message: "filter value is invalid"}
+	ErrInvalidAsOf          = &Error{Code: CodeInvalidAsOf, Kind: KindInvalid, Message: "asOf must be an RFC 3339 timestamp not in the future"}
+	ErrInvalidPeriod        = &Error{Code: CodeInvalidPeriod, Kind: KindInvalid, Message: "period must be RFC 3339 timestamps with from before to and to not in the future"}
+	ErrInvalidFormat        = &Error{Code: CodeInvalidFormat, Kind: KindInvalid, Message: "format must be csv or jsonl"}
+	ErrDuplicateWallet      = &Error{Code: CodeDuplicateWallet, Kind: KindConflict, Message: "wallet ID repeats an earlier record"}
+	ErrWalletExists         = &Error{Code: CodeWalletExists, Kind: KindConflict, Message: "wallet already exists"}
+	ErrImportRejected       = &Error{Code: CodeImportRejected, Kind: KindInvalid, Message: "import has invalid records and was not applied"}
+	ErrWalletNotEmpty       = &Error{Code: CodeWalletNotEmpty, Kind: KindConflict, Message: "currency can only change while the wallet is empty"}
+	ErrInvalidOperationType = &Error{Code: CodeInvalidOperationType, Kind: KindInvalid, Message: "operation type must be DEPOSIT or WITHDRAW"}
+	ErrInsufficientFunds    = &Error{Code: CodeInsufficientFunds, Kind: KindConflict, Message: "insufficient funds"}
+	ErrBalanceLimitExceeded = &Error{Code: CodeBalanceLimitExceeded, Kind: KindConflict, Message: "balance would exceed the wallet limit"}
+	ErrInvalidBalanceLimit  = &Error{Code: CodeInvalidBalanceLimit, Kind: KindInvalid, Message: "balance limit is out of range"}
+	ErrWalletFrozen         = &Error{Code: CodeWalletFrozen, Kind: KindConflict, Message: "wallet is frozen"}
+	ErrSameWallet           = &Error{Code: CodeSameWallet, Kind: KindInvalid, Message: "source and destination wallets must differ"}
+	ErrInvalidLimit         = &Error{Code: CodeInvalidLimit, Kind: KindInvalid, Message: "limit is out of range"}
+	ErrVersionMismatch      = &Error{Code: CodeVersionMismatch, Kind: KindPrecondition, Message: "wallet version does not match"}
+)
+
+// Problem — тело ответа об ошибке в формате RFC 7807
+// (application/problem+json). Code — расширение со стабильным кодом ошибки.
+type Problem struct {
+	Type     string `json:"type" example:"about:blank"`
+	Title    string `json:"title" example:"Conflict"`
+	Status   int    `json:"status" example:"409"`
+	Detail   string `json:"detail,omitempty" example:"insufficient funds"`
+	Instance string `json:"instance,omitempty" example:"/api/v1/wallet"`
+	Code     Code   `json:"code" example:"INSUFFICIENT_FUNDS"`
+	// Errors — ошибки отдельных полей, только для ошибок валидации.
+	Errors []FieldError `json:"errors,omitempty"`
+}
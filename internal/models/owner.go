@@ -0,0 +1,91 @@
+package models
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Owner — владелец кошельков, клиент сервиса. У владельца может быть
+// несколько кошельков, в том числе в разных валютах.
+type Owner struct {
+	ID        uuid.UUID `json:"ownerId" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// CurrencyTotal — суммарный баланс кошельков в одной валюте.
+// Сумма нескольких балансов может не поместиться в int64, поэтому Balance —
+// big.Int; в JSON это по-прежнему число.
+type CurrencyTotal struct {
+	Currency       Currency `json:"currency" swaggertype:"string" example:"RUB"`
+	Wallets        int      `json:"wallets" example:"2"`
+	Balance        *big.Int `json:"balance" swaggertype:"integer" example:"1250"`
+	BalanceDecimal string   `json:"balanceDecimal" example:"12.50"`
+}
+
+// OwnerWallets — кошельки владельца и их балансы по валютам.
+type OwnerWallets struct {
+	OwnerID uuid.UUID       `json:"ownerId"`
+	Wallets []Wallet        `json:"wallets"`
+	Totals  []CurrencyTotal `json:"totals"`
+}
+
+// NewOwnerWallets собирает ответ со списком кошельков владельца и суммами
+// по валютам, упорядоченными по коду валюты.
+func NewOwnerWallets(ownerID uuid.UUID, wallets []Wallet) OwnerWallets {
+	totals := make(CurrencyTotals)
+	for _, wallet := range wallets {
+		totals.Add(wallet.Currency, 1, big.NewInt(wallet.Balance))
+	}
+
+	result := OwnerWallets{OwnerID: ownerID, Wallets: wallets, Totals: totals.List()}
+	if result.Wallets == nil {
+		result.Wallets = []Wallet{}
+	}
+	return result
+}
+
+// CurrencyTotals накапливает число кошельков и сумму балансов по валютам.
+type CurrencyTotals map[Currency]*CurrencyTotal
+
+// Add добавляет к итогу валюты wallets кошельков с общим балансом balance.
+func (t CurrencyTotals) Add(currency Currency, wallets int, balance *big.Int) {
+	total, ok := t[currency]
+	if !ok {
+		total = &CurrencyTotal{Currency: currency, Balance: new(big.Int)}
+		t[currency] = total
+	}
+	total.Wallets += wallets
+	total.Balance.Add(total.Balance, balance)
+}
+
+// Equal сообщает, совпадают ли итоги по всем валютам.
+func (t CurrencyTotals) Equal(other CurrencyTotals) bool {
+	if len(t) != len(other) {
+		return false
+	}
+	for currency, total := range t {
+		o, ok := other[currency]
+		if !ok || o.Wallets != total.Wallets || o.Balance.Cmp(total.Balance) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// List возвращает итоги с десятичными суммами, упорядоченные по коду
+// валюты.
+func (t CurrencyTotals) List() []CurrencyTotal {
+	list := make([]CurrencyTotal, 0, len(t))
+	for _, total := range t {
+		total.BalanceDecimal = formatDecimal(total.Balance.String(), total.Currency.Exponent())
+		list = append(list, *total)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Currency < list[j].Currency
+	})
+	return list
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DiscrepancyKind — вид расхождения, найденного сверкой журнала.
+type DiscrepancyKind string
+
+const (
+	// BalanceMismatch — остаток кошелька не равен сумме его операций.
+	BalanceMismatch DiscrepancyKind = "BALANCE_MISMATCH"
+	// BalanceOutOfRange — остаток кошелька меньше нуля или больше его
+	// лимита; Expected — нарушенная граница.
+	BalanceOutOfRange DiscrepancyKind = "BALANCE_OUT_OF_RANGE"
+	// TransferUnbalanced — ноги перевода не дают в сумме ноль.
+	TransferUnbalanced DiscrepancyKind = "TRANSFER_UNBALANCED"
+	// TransferLegs — у перевода не две ноги: TRANSFER_OUT и TRANSFER_IN.
+	TransferLegs DiscrepancyKind = "TRANSFER_LEGS"
+)
+
+// DiscrepancyKinds — все виды расхождений в порядке проверки.
+var DiscrepancyKinds = []DiscrepancyKind{BalanceMismatch, BalanceOutOfRange, TransferUnbalanced, TransferLegs}
+
+// Discrepancy — расхождение, найденное сверкой. Задан либо WalletID, либо
+// TransferID.
+type Discrepancy struct {
+	Kind       DiscrepancyKind `json:"kind" example:"BALANCE_MISMATCH"`
+	WalletID   *uuid.UUID      `json:"walletId,omitempty"`
+	TransferID *uuid.UUID      `json:"transferId,omitempty"`
+	Expected   int64           `json:"expected" example:"1000"`
+	Actual     int64           `json:"actual" example:"1500"`
+}
+
+// MaxReportedDiscrepancies — сколько расхождений перечисляет отчёт сверки;
+// в таблице reconciliation_discrepancies записаны все.
+const MaxReportedDiscrepancies = 100
+
+// ReconciliationReport — отчёт прогона сверки: сколько кошельков и
+// переводов проверено и сколько расхождений каждого вида найдено.
+type ReconciliationReport struct {
+	ID            int64                     `json:"id"`
+	StartedAt     time.Time                 `json:"startedAt"`
+	FinishedAt    time.Time                 `json:"finishedAt"`
+	Wallets       int64                     `json:"wallets"`
+	Transfers     int64                     `json:"transfers"`
+	Counts        map[DiscrepancyKind]int64 `json:"counts"`
+	Discrepancies []Discrepancy             `json:"discrepancies"`
+}
+
+// Total возвращает число расхождений всех видов.
+func (r *ReconciliationReport) Total() int64 {
+	var total int64
+	for _, count := range r.Counts {
+		total += count
+	}
+	return total
+}
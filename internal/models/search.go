@@ -0,0 +1,217 @@
+package models
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MaxLabels           = 64
+	MaxLabelValueLength = 256
+	// MaxMetadataSize — наибольший размер метаданных кошелька в байтах JSON.
+	MaxMetadataSize = 16 << 10
+
+	DefaultSearchLimit = 50
+	MaxSearchLimit     = 500
+)
+
+// labelKeyPattern — ключ метки: латиница в нижнем регистре, цифры и
+// разделители, не длиннее 63 символов, например "crm/account-id".
+var labelKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._/-]{0,62}$`)
+
+// WalletMetadataRequest заменяет метки и метаданные кошелька. Метки —
+// короткие строки, по которым ищут кошельки; метаданные — произвольный
+// JSON-объект, например ссылки на счета во внешних системах.
+type WalletMetadataRequest struct {
+	Labels   map[string]string `json:"labels" example:"team:payments"`
+	Metadata json.RawMessage   `json:"metadata" swaggertype:"object"`
+}
+
+// Validate проверяет метки и метаданные и возвращает *ValidationError со
+// списком ошибок.
+func (r *WalletMetadataRequest) Validate() error {
+	var v ValidationError
+	if !validLabels(r.Labels) {
+		v.Add("labels", ErrInvalidLabel)
+	}
+	if !validMetadata(r.Metadata) {
+		v.Add("metadata", ErrInvalidMetadata)
+	}
+	return v.Err()
+}
+
+func validLabels(labels map[string]string) bool {
+	if len(labels) > MaxLabels {
+		return false
+	}
+	for key, value := range labels {
+		if !labelKeyPattern.MatchString(key) || len(value) > MaxLabelValueLength {
+			return false
+		}
+	}
+	return true
+}
+
+// validMetadata допускает пустые метаданные и JSON-объект не больше
+// MaxMetadataSize.
+func validMetadata(metadata json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(metadata)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return true
+	}
+	return len(trimmed) <= MaxMetadataSize && trimmed[0] == '{' && json.Valid(trimmed)
+}
+
+// WalletFilter — условия поиска кошельков. Нулевое поле не ограничивает
+// выборку. Кошельки упорядочены по времени создания и id; After продолжает
+// выдачу с места, где закончилась предыдущая страница.
+type WalletFilter struct {
+	Labels      map[string]string
+	Status      WalletStatus
+	MinBalance  *int64
+	MaxBalance  *int64
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	Limit       int
+	After       *WalletCursor
+}
+
+// Matches сообщает, подходит ли кошелёк под фильтр без учёта After и Limit.
+func (f *WalletFilter) Matches(wallet *Wallet) bool {
+	for key, value := range f.Labels {
+		if got, ok := wallet.Labels[key]; !ok || got != value {
+			return false
+		}
+	}
+	return (f.Status == "" || wallet.Status == f.Status) &&
+		(f.MinBalance == nil || wallet.Balance >= *f.MinBalance) &&
+		(f.MaxBalance == nil || wallet.Balance <= *f.MaxBalance) &&
+		(f.CreatedFrom.IsZero() || !wallet.CreatedAt.Before(f.CreatedFrom)) &&
+		(f.CreatedTo.IsZero() || wallet.CreatedAt.Before(f.CreatedTo))
+}
+
+// WalletCursor — позиция в выдаче поиска: последний отданный кошелёк.
+type WalletCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// String кодирует курсор в непрозрачную для клиента строку.
+func (c WalletCursor) String() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "," + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseWalletCursor разбирает строку из WalletCursor.String.
+func ParseWalletCursor(s string) (WalletCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return WalletCursor{}, ErrInvalidFilter
+	}
+	createdAt, id, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return WalletCursor{}, ErrInvalidFilter
+	}
+
+	var cursor WalletCursor
+	if cursor.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return WalletCursor{}, ErrInvalidFilter
+	}
+	if cursor.ID, err = uuid.Parse(id); err != nil {
+		return WalletCursor{}, ErrInvalidFilter
+	}
+	return cursor, nil
+}
+
+// WalletPage — страница результатов поиска. NextCursor пуст на последней
+// странице.
+type WalletPage struct {
+	Wallets    []Wallet `json:"wallets"`
+	NextCursor string   `json:"nextCursor,omitempty" example:"MjAyNi0xMC0xOFQxMjowMDowMFosNWY..."`
+}
+
+// ParseWalletFilter разбирает параметры поиска из query-строки:
+// label=key:value (можно повторять), status, minBalance, maxBalance,
+// createdFrom и createdTo (RFC 3339), limit и cursor. Ошибки всех
+// параметров возвращаются одной *ValidationError.
+func ParseWalletFilter(query url.Values) (WalletFilter, error) {
+	var (
+		v      ValidationError
+		filter = WalletFilter{Limit: DefaultSearchLimit}
+	)
+
+	for _, label := range query["label"] {
+		key, value, ok := strings.Cut(label, ":")
+		if !ok || !labelKeyPattern.MatchString(key) {
+			v.Add("label", ErrInvalidLabel)
+			continue
+		}
+		if filter.Labels == nil {
+			filter.Labels = make(map[string]string)
+		}
+		filter.Labels[key] = value
+	}
+
+	switch status := WalletStatus(query.Get("status")); status {
+	case "", StatusActive, StatusFrozen:
+		filter.Status = status
+	default:
+		v.Add("status", ErrInvalidFilter)
+	}
+
+	filter.MinBalance = parseBalanceParam(&v, query, "minBalance")
+	filter.MaxBalance = parseBalanceParam(&v, query, "maxBalance")
+	filter.CreatedFrom = parseTimeParam(&v, query, "createdFrom")
+	filter.CreatedTo = parseTimeParam(&v, query, "createdTo")
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > MaxSearchLimit {
+			v.Add("limit", ErrInvalidLimit)
+		}
+		filter.Limit = limit
+	}
+
+	if raw := query.Get("cursor"); raw != "" {
+		cursor, err := ParseWalletCursor(raw)
+		if err != nil {
+			v.Add("cursor", ErrInvalidFilter)
+		}
+		filter.After = &cursor
+	}
+
+	return filter, v.Err()
+}
+
+func parseBalanceParam(v *ValidationError, query url.Values, name string) *int64 {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil
+	}
+	balance, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		v.Add(name, ErrInvalidFilter)
+		return nil
+	}
+	return &balance
+}
+
+func parseTimeParam(v *ValidationError, query url.Values, name string) time.Time {
+	raw := query.Get(name)
+	if raw == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		v.Add(name, ErrInvalidFilter)
+	}
+	return t
+}
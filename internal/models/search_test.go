@@ -0,0 +1,112 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWalletFilter(t *testing.T) {
+	cursor := WalletCursor{CreatedAt: time.Date(2026, 10, 18, 12, 0, 0, 500, time.UTC), ID: uuid.New()}
+	query := url.Values{
+		"label":       {"team:payments", "crm/account-id:A-1"},
+		"status":      {"FROZEN"},
+		"minBalance":  {"100"},
+		"maxBalance":  {"500"},
+		"createdFrom": {"2026-01-01T00:00:00Z"},
+		"createdTo":   {"2026-02-01T00:00:00+03:00"},
+		"limit":       {"20"},
+		"cursor":      {cursor.String()},
+	}
+
+	filter, err := ParseWalletFilter(query)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "payments", "crm/account-id": "A-1"}, filter.Labels)
+	assert.Equal(t, StatusFrozen, filter.Status)
+	assert.Equal(t, int64(100), *filter.MinBalance)
+	assert.Equal(t, int64(500), *filter.MaxBalance)
+	assert.True(t, filter.CreatedFrom.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, filter.CreatedTo.Equal(time.Date(2026, 1, 31, 21, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 20, filter.Limit)
+	require.NotNil(t, filter.After)
+	assert.True(t, filter.After.CreatedAt.Equal(cursor.CreatedAt))
+	assert.Equal(t, cursor.ID, filter.After.ID)
+}
+
+func TestParseWalletFilter_Defaults(t *testing.T) {
+	filter, err := ParseWalletFilter(url.Values{})
+	require.NoError(t, err)
+	assert.Equal(t, WalletFilter{Limit: DefaultSearchLimit}, filter)
+}
+
+func TestParseWalletFilter_Invalid(t *testing.T) {
+	query := url.Values{
+		"label":       {"no-colon"},
+		"status":      {"DELETED"},
+		"minBalance":  {"1.5"},
+		"createdFrom": {"yesterday"},
+		"limit":       {"1000"},
+		"cursor":      {"???"},
+	}
+
+	_, err := ParseWalletFilter(query)
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	fields := make(map[string]Code)
+	for _, field := range validationErr.Fields {
+		fields[field.Field] = field.Code
+	}
+	assert.Equal(t, map[string]Code{
+		"label":       CodeInvalidLabel,
+		"status":      CodeInvalidFilter,
+		"minBalance":  CodeInvalidFilter,
+		"createdFrom": CodeInvalidFilter,
+		"limit":       CodeInvalidLimit,
+		"cursor":      CodeInvalidFilter,
+	}, fields)
+}
+
+func TestWalletFilter_Matches(t *testing.T) {
+	created := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	wallet := &Wallet{Balance: 100, Status: StatusActive, Labels: map[string]string{"team": "payments"}, CreatedAt: created}
+	low, high := int64(100), int64(99)
+
+	assert.True(t, (&WalletFilter{}).Matches(wallet))
+	assert.True(t, (&WalletFilter{Labels: map[string]string{"team": "payments"}, MinBalance: &low, CreatedFrom: created}).Matches(wallet))
+	assert.False(t, (&WalletFilter{Labels: map[string]string{"team": "risk"}}).Matches(wallet))
+	assert.False(t, (&WalletFilter{Status: StatusFrozen}).Matches(wallet))
+	assert.False(t, (&WalletFilter{MaxBalance: &high}).Matches(wallet))
+	assert.False(t, (&WalletFilter{CreatedTo: created}).Matches(wallet), "createdTo is exclusive")
+}
+
+func TestWalletMetadataRequest_Validate(t *testing.T) {
+	valid := WalletMetadataRequest{
+		Labels:   map[string]string{"team": "payments", "crm/account-id": "A-1"},
+		Metadata: json.RawMessage(`{"crm": {"account": "A-1"}}`),
+	}
+	assert.NoError(t, valid.Validate())
+	assert.NoError(t, (&WalletMetadataRequest{}).Validate())
+
+	tests := []struct {
+		name    string
+		req     WalletMetadataRequest
+		wantErr *Error
+	}{
+		{"uppercase key", WalletMetadataRequest{Labels: map[string]string{"Team": "x"}}, ErrInvalidLabel},
+		{"long value", WalletMetadataRequest{Labels: map[string]string{"team": strings.Repeat("x", MaxLabelValueLength+1)}}, ErrInvalidLabel},
+		{"metadata array", WalletMetadataRequest{Metadata: json.RawMessage(`[1, 2]`)}, ErrInvalidMetadata},
+		{"metadata too large", WalletMetadataRequest{Metadata: json.RawMessage(`{"x":"` + strings.Repeat("x", MaxMetadataSize) + `"}`)}, ErrInvalidMetadata},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.req.Validate(), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StatementFormat — формат выписки.
+type StatementFormat string
+
+const (
+	StatementCSV   StatementFormat = "csv"
+	StatementJSONL StatementFormat = "jsonl"
+)
+
+// StatementRequest — выписка по кошельку за период (From, To]: остаток на
+// момент From, операции с From до To включительно и остаток на момент To.
+type StatementRequest struct {
+	WalletID uuid.UUID
+	From     time.Time
+	To       time.Time
+	Format   StatementFormat
+}
+
+// Validate проверяет период и формат и возвращает *ValidationError со
+// списком ошибок. Конец периода не может быть в будущем: остаток на него
+// ещё не известен.
+func (r *StatementRequest) Validate() error {
+	var v ValidationError
+	validateWalletID(&v, "walletId", r.WalletID)
+	if r.From.IsZero() {
+		v.Add("from", ErrInvalidPeriod)
+	}
+	if r.To.IsZero() || r.To.After(time.Now()) || (!r.From.IsZero() && !r.From.Before(r.To)) {
+		v.Add("to", ErrInvalidPeriod)
+	}
+	if r.Format != StatementCSV && r.Format != StatementJSONL {
+		v.Add("format", ErrInvalidFormat)
+	}
+	return v.Err()
+}
+
+// ParseStatementQuery разбирает параметры выписки из query-строки: from и
+// to (RFC 3339) и format. Без to период заканчивается текущим моментом, без
+// format выписка пишется в CSV.
+func ParseStatementQuery(walletID uuid.UUID, query url.Values) (StatementRequest, error) {
+	var v ValidationError
+	req := StatementRequest{WalletID: walletID, To: time.Now(), Format: StatementCSV}
+
+	if raw := query.Get("from"); raw != "" {
+		from, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			v.Add("from", ErrInvalidPeriod)
+		}
+		req.From = from
+	}
+	if raw := query.Get("to"); raw != "" {
+		to, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			v.Add("to", ErrInvalidPeriod)
+		}
+		req.To = to
+	}
+	if raw := query.Get("format"); raw != "" {
+		req.Format = StatementFormat(raw)
+	}
+
+	if err := v.Err(); err != nil {
+		return req, err
+	}
+	return req, req.Validate()
+}
+
+// StatementRecord — вид строки выписки.
+type StatementRecord string
+
+const (
+	StatementOpening   StatementRecord = "OPENING"
+	StatementOperation StatementRecord = "OPERATION"
+	StatementClosing   StatementRecord = "CLOSING"
+)
+
+// StatementLine — строка выписки в формате JSON Lines. Balance — остаток
+// после строки: на начало периода, после операции или на конец периода.
+type StatementLine struct {
+	Record         StatementRecord `json:"record"`
+	Time           time.Time       `json:"time"`
+	WalletID       *uuid.UUID      `json:"walletId,omitempty"`
+	Currency       Currency        `json:"currency,omitempty"`
+	OperationID    int64           `json:"operationId,omitempty"`
+	OperationType  OperationType   `json:"operationType,omitempty"`
+	TransferID     *uuid.UUID      `json:"transferId,omitempty"`
+	Amount         *int64          `json:"amount,omitempty"`
+	AmountDecimal  string          `json:"amountDecimal,omitempty"`
+	Balance        int64           `json:"balance"`
+	BalanceDecimal string          `json:"balanceDecimal"`
+	// Operations — число операций за период, только в строке CLOSING.
+	Operations *int `json:"operations,omitempty"`
+}
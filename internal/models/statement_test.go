@@ -0,0 +1,81 @@
+package models
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStatementQuery(t *testing.T) {
+	walletID := uuid.New()
+	req, err := ParseStatementQuery(walletID, url.Values{
+		"from":   {"2026-09-01T00:00:00Z"},
+		"to":     {"2026-10-01T00:00:00+03:00"},
+		"format": {"jsonl"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, walletID, req.WalletID)
+	assert.True(t, req.From.Equal(time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, req.To.Equal(time.Date(2026, 9, 30, 21, 0, 0, 0, time.UTC)))
+	assert.Equal(t, StatementJSONL, req.Format)
+}
+
+func TestParseStatementQuery_Defaults(t *testing.T) {
+	before := time.Now()
+	req, err := ParseStatementQuery(uuid.New(), url.Values{"from": {"2026-09-01T00:00:00Z"}})
+	require.NoError(t, err)
+	assert.Equal(t, StatementCSV, req.Format)
+	assert.False(t, req.To.Before(before))
+}
+
+func TestParseStatementQuery_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  url.Values
+		fields map[string]Code
+	}{
+		{
+			name:   "no from",
+			query:  url.Values{},
+			fields: map[string]Code{"from": CodeInvalidPeriod},
+		},
+		{
+			name:   "malformed times and format",
+			query:  url.Values{"from": {"yesterday"}, "to": {"today"}, "format": {"pdf"}},
+			fields: map[string]Code{"from": CodeInvalidPeriod, "to": CodeInvalidPeriod},
+		},
+		{
+			name:   "empty period",
+			query:  url.Values{"from": {"2026-09-01T00:00:00Z"}, "to": {"2026-09-01T00:00:00Z"}},
+			fields: map[string]Code{"to": CodeInvalidPeriod},
+		},
+		{
+			name:   "to in the future",
+			query:  url.Values{"from": {"2026-09-01T00:00:00Z"}, "to": {time.Now().Add(time.Hour).Format(time.RFC3339)}},
+			fields: map[string]Code{"to": CodeInvalidPeriod},
+		},
+		{
+			name:   "unknown format",
+			query:  url.Values{"from": {"2026-09-01T00:00:00Z"}, "format": {"pdf"}},
+			fields: map[string]Code{"format": CodeInvalidFormat},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseStatementQuery(uuid.New(), tt.query)
+			var validationErr *ValidationError
+			require.True(t, errors.As(err, &validationErr))
+			fields := make(map[string]Code)
+			for _, field := range validationErr.Fields {
+				fields[field.Field] = field.Code
+			}
+			assert.Equal(t, tt.fields, fields)
+		})
+	}
+}
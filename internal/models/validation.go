@@ -0,0 +1,94 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// MaxAmount — наибольшая сумма одной операции в минимальных единицах
+// валюты. С ней balance + amount не переполняет BIGINT, пока баланс
+// меньше math.MaxInt64 - MaxAmount.
+const MaxAmount int64 = 1_000_000_000_000_000
+
+// FieldError — ошибка одного поля запроса. Field — имя поля в JSON.
+type FieldError struct {
+	Field   string `json:"field" example:"amount"`
+	Code    Code   `json:"code" example:"INVALID_AMOUNT"`
+	Message string `json:"message" example:"amount must be positive"`
+}
+
+// ValidationError перечисляет ошибки всех полей запроса, а не только
+// первого. errors.Is сопоставляет её с Err* любого из полей — по самой
+// ошибке, а не по коду: у ErrInvalidAmount и ErrAmountFormat код общий.
+type ValidationError struct {
+	Fields []FieldError
+
+	// errs — ошибки полей Fields в том же порядке.
+	errs []*Error
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + ": " + field.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	domainErr, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	for _, err := range e.errs {
+		if err == domainErr {
+			return true
+		}
+	}
+	return false
+}
+
+// Code возвращает код ошибки поля, если оно одно, и
+// CodeValidationFailed, если полей несколько.
+func (e *ValidationError) Code() Code {
+	if len(e.Fields) == 1 {
+		return e.Fields[0].Code
+	}
+	return CodeValidationFailed
+}
+
+// Add добавляет ошибку поля field.
+func (e *ValidationError) Add(field string, err *Error) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Code: err.Code, Message: err.Message})
+	e.errs = append(e.errs, err)
+}
+
+// Err возвращает e, если есть ошибки полей, и nil иначе.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func validateAmount(v *ValidationError, amount int64) {
+	switch {
+	case amount <= 0:
+		v.Add("amount", ErrInvalidAmount)
+	case amount > MaxAmount:
+		v.Add("amount", ErrAmountTooLarge)
+	}
+}
+
+func validateDecimalAmount(v *ValidationError, amount string) {
+	if !isDecimal(amount) {
+		v.Add("amount", ErrAmountFormat)
+	}
+}
+
+func validateWalletID(v *ValidationError, field string, id uuid.UUID) {
+	if id == uuid.Nil {
+		v.Add(field, ErrWalletIDRequired)
+	}
+}
@@ -1,36 +1,217 @@
 package models
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"time"
+
 	"github.com/google/uuid"
 )
 
-var (
-	ErrInvalidAmount     = errors.New("amount must be positive")
-	ErrInsufficientFunds = errors.New("insufficient funds")
+type OperationType string
+
+const (
+	Deposit     OperationType = "DEPOSIT"
+	Withdraw    OperationType = "WITHDRAW"
+	Opening     OperationType = "OPENING"
+	TransferIn  OperationType = "TRANSFER_IN"
+	TransferOut OperationType = "TRANSFER_OUT"
 )
 
-type OperationType string
+type WalletStatus string
 
 const (
-	Deposit  OperationType = "DEPOSIT"
-	Withdraw OperationType = "WITHDRAW"
+	StatusActive WalletStatus = "ACTIVE"
+	StatusFrozen WalletStatus = "FROZEN"
 )
 
+// Wallet — состояние кошелька. Version увеличивается при каждом изменении
+// кошелька и служит для оптимистичной блокировки.
 type Wallet struct {
-	ID      uuid.UUID `json:"walletId" db:"id"`
-	Balance int64     `json:"balance" db:"balance"`
+	ID        uuid.UUID    `json:"walletId" db:"id"`
+	Balance   int64        `json:"balance" db:"balance"`
+	Status    WalletStatus `json:"status" db:"status"`
+	Version   int64        `json:"version" db:"version"`
+	Currency  Currency     `json:"currency" db:"currency" swaggertype:"string" example:"RUB"`
+	// OwnerID пуст только у кошельков, созданных до появления владельцев.
+	OwnerID   *uuid.UUID   `json:"ownerId,omitempty" db:"owner_id"`
+	// Labels и Metadata заполняются только в административных ответах.
+	Labels    map[string]string `json:"labels,omitempty" db:"labels"`
+	Metadata  json.RawMessage   `json:"metadata,omitempty" db:"metadata" swaggertype:"object"`
+	// MaxBalance заполняется только при экспорте, в ответы API лимит не
+	// попадает.
+	MaxBalance *int64           `json:"-" db:"max_balance"`
+	CreatedAt time.Time    `json:"createdAt" db:"created_at"`
+}
+
+// Operation — запись журнала операций кошелька. Amount положителен для
+// зачислений и отрицателен для списаний.
+type Operation struct {
+	ID         int64         `json:"id" db:"id"`
+	WalletID   uuid.UUID     `json:"walletId" db:"wallet_id"`
+	Type       OperationType `json:"operationType" db:"operation_type"`
+	Amount     int64         `json:"amount" db:"amount"`
+	TransferID *uuid.UUID    `json:"transferId,omitempty" db:"transfer_id"`
+	CreatedAt  time.Time     `json:"createdAt" db:"created_at"`
 }
 
 type OperationRequest struct {
 	WalletID     uuid.UUID     `json:"walletId"`
 	OperationType OperationType `json:"operationType"`
+	// Сумма в минимальных единицах; в JSON её можно передать и десятичной
+	// строкой, см. OperationRequestDoc.
 	Amount       int64         `json:"amount"`
+
+	// decimalAmount — сумма, переданная десятичной строкой. Amount
+	// заполняется из неё в ResolveAmount, когда известна валюта кошелька.
+	decimalAmount string
+	currency      Currency
 }
 
-func (r *OperationRequest) Validate() error {
-	if r.Amount <= 0 {
-		return ErrInvalidAmount
+// OperationRequestDoc описывает тело OperationRequest в документации API.
+// В Swagger 2.0 нет oneOf, поэтому тип amount не задан: допустимы и целое
+// число, и строка, а форматы перечислены в описании поля.
+type OperationRequestDoc struct {
+	WalletID      uuid.UUID     `json:"walletId" example:"4f1c6a9e-0000-4000-8000-000000000001"`
+	OperationType OperationType `json:"operationType" example:"DEPOSIT"`
+	// Сумма: целое число минимальных единиц валюты кошелька (1250) или
+	// десятичная строка в основных единицах ("12.50"). Дробное число
+	// (12.5) не принимается.
+	Amount any `json:"amount"`
+}
+
+// UnmarshalJSON принимает сумму числом в минимальных единицах или
+// десятичной строкой. Строка только сохраняется: перевести её в
+// минимальные единицы можно, лишь зная валюту кошелька. Неизвестные поля
+// отклоняются, как и при разборе запроса без своего UnmarshalJSON в
+// decodeJSON.
+func (r *OperationRequest) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		WalletID      uuid.UUID       `json:"walletId"`
+		OperationType OperationType   `json:"operationType"`
+		Amount        json.RawMessage `json:"amount"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+
+	*r = OperationRequest{WalletID: raw.WalletID, OperationType: raw.OperationType}
+	if len(raw.Amount) > 0 && raw.Amount[0] == '"' {
+		return json.Unmarshal(raw.Amount, &r.decimalAmount)
+	}
+	if len(raw.Amount) > 0 {
+		if err := json.Unmarshal(raw.Amount, &r.Amount); err != nil {
+			var typeError *json.UnmarshalTypeError
+			if errors.As(err, &typeError) {
+				typeError.Field = "amount"
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// DecimalAmount сообщает, передана ли сумма десятичной строкой.
+func (r *OperationRequest) DecimalAmount() bool {
+	return r.decimalAmount != ""
+}
+
+// ResolveAmount переводит десятичную сумму в минимальные единицы валюты
+// currency, проверяет её и запоминает валюту для ответа. Ошибка —
+// *ValidationError с полем amount.
+func (r *OperationRequest) ResolveAmount(currency Currency) error {
+	var v ValidationError
+	amount, err := ParseAmount(r.decimalAmount, currency.Exponent())
+	if err != nil {
+		var domainErr *Error
+		if !errors.As(err, &domainErr) {
+			domainErr = ErrAmountFormat
+		}
+		v.Add("amount", domainErr)
+		return v.Err()
 	}
+	validateAmount(&v, amount)
+	if err := v.Err(); err != nil {
+		return err
+	}
+
+	r.Amount = amount
+	r.currency = currency
 	return nil
 }
+
+// Currency возвращает валюту, по которой разрешена десятичная сумма, и
+// пустую строку, если сумма передана в минимальных единицах.
+func (r *OperationRequest) Currency() Currency {
+	return r.currency
+}
+
+// Validate проверяет все поля запроса и возвращает *ValidationError со
+// списком ошибок. Десятичная сумма до ResolveAmount проверяется только на
+// формат.
+func (r *OperationRequest) Validate() error {
+	var v ValidationError
+	validateWalletID(&v, "walletId", r.WalletID)
+	if r.OperationType != Deposit && r.OperationType != Withdraw {
+		v.Add("operationType", ErrInvalidOperationType)
+	}
+	if r.DecimalAmount() && r.currency == "" {
+		validateDecimalAmount(&v, r.decimalAmount)
+	} else {
+		validateAmount(&v, r.Amount)
+	}
+	return v.Err()
+}
+
+// OperationResponse — ответ на успешную операцию с балансом после неё.
+// Если сумма передана десятичной строкой, баланс возвращается и в основных
+// единицах валюты кошелька.
+type OperationResponse struct {
+	Status         string   `json:"status"`
+	Balance        int64    `json:"balance"`
+	Currency       Currency `json:"currency,omitempty" swaggertype:"string" example:"RUB"`
+	BalanceDecimal string   `json:"balanceDecimal,omitempty" example:"12.50"`
+}
+
+// BalanceResponse — баланс кошелька в минимальных и в основных единицах
+// его валюты.
+type BalanceResponse struct {
+	Balance        int64    `json:"balance" example:"1250"`
+	Currency       Currency `json:"currency" swaggertype:"string" example:"RUB"`
+	BalanceDecimal string   `json:"balanceDecimal" example:"12.50"`
+	// AsOf задан, если баланс исторический.
+	AsOf *time.Time `json:"asOf,omitempty" example:"2026-09-30T23:59:59Z"`
+}
+
+// NewBalanceResponse возвращает баланс кошелька wallet.
+func NewBalanceResponse(wallet *Wallet) BalanceResponse {
+	return BalanceResponse{
+		Balance:        wallet.Balance,
+		Currency:       wallet.Currency,
+		BalanceDecimal: FormatAmount(wallet.Balance, wallet.Currency.Exponent()),
+	}
+}
+
+// TransferRequest — перевод между кошельками. В отличие от
+// OperationRequest, сумма принимается только в минимальных единицах.
+type TransferRequest struct {
+	FromWalletID uuid.UUID `json:"fromWalletId"`
+	ToWalletID   uuid.UUID `json:"toWalletId"`
+	Amount       int64     `json:"amount"`
+}
+
+// Validate проверяет все поля запроса и возвращает *ValidationError со
+// списком ошибок.
+func (r *TransferRequest) Validate() error {
+	var v ValidationError
+	validateWalletID(&v, "fromWalletId", r.FromWalletID)
+	validateWalletID(&v, "toWalletId", r.ToWalletID)
+	if r.FromWalletID != uuid.Nil && r.FromWalletID == r.ToWalletID {
+		v.Add("toWalletId", ErrSameWallet)
+	}
+	validateAmount(&v, r.Amount)
+	return v.Err()
+}
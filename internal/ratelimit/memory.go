@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// MemoryLimiter хранит вёдра в памяти процесса: лимит действует на каждый
+// инстанс отдельно.
+type MemoryLimiter struct {
+	rate Rate
+	now  func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryLimiter(rate Rate) *MemoryLimiter {
+	return &MemoryLimiter{
+		rate:    rate,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (l *MemoryLimiter) Take(_ context.Context, key string) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.rate.Burst, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.rate.Burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate.Rate)
+	b.updated = now
+
+	if b.tokens < 1 {
+		return false, l.rate.retryAfter(b.tokens), nil
+	}
+	b.tokens--
+	return true, 0, nil
+}
+
+// sweep раз в интервал полного пополнения удаляет вёдра, которые за это
+// время наполнились: они неотличимы от новых.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	refill := time.Duration(l.rate.Burst / l.rate.Rate * float64(time.Second))
+	if now.Sub(l.lastSweep) < refill {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLimiter(rate Rate) (*MemoryLimiter, *time.Time) {
+	l := NewMemoryLimiter(rate)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestMemoryLimiter_BurstThenRefill(t *testing.T) {
+	l, now := newTestLimiter(Rate{Rate: 2, Burst: 3})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		allowed, _, err := l.Take(ctx, "client")
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+	}
+
+	allowed, retryAfter, _ := l.Take(ctx, "client")
+	assert.False(t, allowed)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	*now = now.Add(250 * time.Millisecond)
+	allowed, retryAfter, _ = l.Take(ctx, "client")
+	assert.False(t, allowed)
+	assert.Equal(t, 250*time.Millisecond, retryAfter)
+
+	*now = now.Add(250 * time.Millisecond)
+	allowed, _, _ = l.Take(ctx, "client")
+	assert.True(t, allowed)
+}
+
+func TestMemoryLimiter_KeysAreIndependent(t *testing.T) {
+	l, _ := newTestLimiter(Rate{Rate: 1, Burst: 1})
+	ctx := context.Background()
+
+	allowed, _, _ := l.Take(ctx, "a")
+	assert.True(t, allowed)
+	allowed, _, _ = l.Take(ctx, "a")
+	assert.False(t, allowed)
+	allowed, _, _ = l.Take(ctx, "b")
+	assert.True(t, allowed)
+}
+
+func TestMemoryLimiter_RefillIsCappedAtBurst(t *testing.T) {
+	l, now := newTestLimiter(Rate{Rate: 10, Burst: 2})
+	ctx := context.Background()
+
+	l.Take(ctx, "client")
+	*now = now.Add(time.Hour)
+
+	for i := 0; i < 2; i++ {
+		allowed, _, _ := l.Take(ctx, "client")
+		assert.True(t, allowed)
+	}
+	allowed, _, _ := l.Take(ctx, "client")
+	assert.False(t, allowed)
+}
+
+func TestMemoryLimiter_SweepsFullBuckets(t *testing.T) {
+	l, now := newTestLimiter(Rate{Rate: 1, Burst: 5})
+	ctx := context.Background()
+
+	l.Take(ctx, "idle")
+	*now = now.Add(10 * time.Second)
+	l.Take(ctx, "active")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	assert.NotContains(t, l.buckets, "idle")
+	assert.Contains(t, l.buckets, "active")
+}
+
+func TestRate_Enabled(t *testing.T) {
+	assert.True(t, Rate{Rate: 1, Burst: 1}.Enabled())
+	assert.False(t, Rate{Rate: 0, Burst: 10}.Enabled())
+	assert.False(t, Rate{Rate: 5, Burst: 0}.Enabled())
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// PostgresLimiter хранит вёдра в таблице rate_limit_buckets, поэтому лимит
+// общий для всех инстансов сервиса. Каждый Take — один вызов функции
+// rate_limit_take, которая пополняет и списывает токены под блокировкой
+// строки ведра.
+type PostgresLimiter struct {
+	db     *sql.DB
+	rate   Rate
+	prefix string
+}
+
+// NewPostgresLimiter создаёт лимитер, ключи которого в общей таблице
+// отделены префиксом scope.
+func NewPostgresLimiter(db *sql.DB, scope string, rate Rate) *PostgresLimiter {
+	return &PostgresLimiter{db: db, rate: rate, prefix: scope + ":"}
+}
+
+func (l *PostgresLimiter) Take(ctx context.Context, key string) (bool, time.Duration, error) {
+	var wait float64
+	err := l.db.QueryRowContext(
+		ctx,
+		"SELECT rate_limit_take($1, $2, $3)",
+		l.prefix+key,
+		l.rate.Burst,
+		l.rate.Rate,
+	).Scan(&wait)
+	if err != nil {
+		return false, 0, err
+	}
+	if wait > 0 {
+		return false, time.Duration(wait * float64(time.Second)), nil
+	}
+	return true, 0, nil
+}
+
+// Cleanup удаляет вёдра, к которым не обращались дольше olderThan. Такие
+// вёдра давно наполнились и неотличимы от отсутствующих.
+func (l *PostgresLimiter) Cleanup(ctx context.Context, olderThan time.Duration) (int64, error) {
+	result, err := l.db.ExecContext(
+		ctx,
+		"DELETE FROM rate_limit_buckets WHERE starts_with(key, $1) AND updated_at < now() - make_interval(secs => $2)",
+		l.prefix,
+		olderThan.Seconds(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// CleanupLoop каждые interval удаляет вёдра, которые успели наполниться, до
+// отмены ctx.
+func (l *PostgresLimiter) CleanupLoop(ctx context.Context, interval time.Duration) {
+	refill := time.Duration(l.rate.Burst / l.rate.Rate * float64(time.Second))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := l.Cleanup(ctx, max(interval, refill)); err != nil && ctx.Err() == nil {
+				log.Printf("Rate limit cleanup failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/lib/pq"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		dsn = "host=localhost port=5433 user=wallet_user password=wallet_password dbname=wallet_db sslmode=disable"
+	}
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	return db
+}
+
+func TestPostgresLimiter_SharedAcrossInstances(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	rate := Rate{Rate: 0.1, Burst: 3}
+	scope := "test-" + uuid.NewString()
+
+	// Два лимитера с одной областью — как два инстанса сервиса.
+	first := NewPostgresLimiter(db, scope, rate)
+	second := NewPostgresLimiter(db, scope, rate)
+	t.Cleanup(func() { first.Cleanup(ctx, -time.Hour) })
+
+	for _, l := range []*PostgresLimiter{first, second, first} {
+		allowed, _, err := l.Take(ctx, "client")
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+
+	allowed, retryAfter, err := second.Take(ctx, "client")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.InDelta(t, 10*time.Second, retryAfter, float64(time.Second))
+
+	other := NewPostgresLimiter(db, scope+"-other", rate)
+	t.Cleanup(func() { other.Cleanup(ctx, -time.Hour) })
+	allowed, _, err = other.Take(ctx, "client")
+	require.NoError(t, err)
+	assert.True(t, allowed, "scopes must not share buckets")
+}
@@ -0,0 +1,35 @@
+// Package ratelimit ограничивает частоту запросов по ключу (API-ключ, IP,
+// кошелёк) алгоритмом token bucket: ведро на burst токенов пополняется со
+// скоростью rate токенов в секунду, каждый запрос забирает один токен.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limiter решает, можно ли выполнить ещё один запрос с ключом key.
+// Реализации безопасны для конкурентного вызова.
+type Limiter interface {
+	// Take забирает токен. Если токенов нет, возвращает false и время, через
+	// которое токен появится.
+	Take(ctx context.Context, key string) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// Rate — параметры ведра: Rate токенов в секунду, не больше Burst
+// накопленных.
+type Rate struct {
+	Rate  float64
+	Burst float64
+}
+
+// Enabled сообщает, задано ли ограничение: нулевая скорость его отключает.
+func (r Rate) Enabled() bool {
+	return r.Rate > 0 && r.Burst >= 1
+}
+
+// retryAfter возвращает время до появления целого токена при available
+// токенах в ведре.
+func (r Rate) retryAfter(available float64) time.Duration {
+	return time.Duration((1 - available) / r.Rate * float64(time.Second))
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// WalletAdmin — административные операции над кошельками: метки,
+// метаданные и поиск. Их реализуют хранилища, а не декораторы: поиск не
+// кэшируется и не занимает слоты LimitingRepository.
+type WalletAdmin interface {
+	// SetWalletMetadata заменяет метки и метаданные кошелька и возвращает
+	// кошелёк вместе с ними. Версия кошелька не меняется: метки не влияют
+	// на операции с балансом.
+	SetWalletMetadata(ctx context.Context, walletID uuid.UUID, labels map[string]string, metadata json.RawMessage) (*models.Wallet, error)
+	SearchWallets(ctx context.Context, filter models.WalletFilter) (*models.WalletPage, error)
+}
+
+const adminWalletColumns = "w.id, " + balanceExpr + ", w.status, " + versionExpr +
+	", w.currency, w.owner_id, w.labels, w.metadata, w.created_at"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanAdminWallet читает кошелёк из строки с колонками adminWalletColumns.
+func scanAdminWallet(row rowScanner) (models.Wallet, error) {
+	var (
+		wallet   models.Wallet
+		labels   []byte
+		metadata []byte
+	)
+	err := row.Scan(&wallet.ID, &wallet.Balance, &wallet.Status, &wallet.Version, &wallet.Currency, &wallet.OwnerID, &labels, &metadata, &wallet.CreatedAt)
+	if err != nil {
+		return wallet, err
+	}
+	if err := json.Unmarshal(labels, &wallet.Labels); err != nil {
+		return wallet, err
+	}
+	wallet.Metadata = metadata
+	return wallet, nil
+}
+
+// metadataArgs готовит метки и метаданные к записи в JSONB: пустые
+// значения становятся пустыми объектами.
+func metadataArgs(labels map[string]string, metadata json.RawMessage) (string, string, error) {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labelsJSON, err := json.Marshal(labels)
+	if err != nil {
+		return "", "", err
+	}
+	trimmed := strings.TrimSpace(string(metadata))
+	if trimmed == "" || trimmed == "null" {
+		trimmed = "{}"
+	}
+	return string(labelsJSON), trimmed, nil
+}
+
+const setMetadataQuery = "UPDATE wallets w SET labels = $2::jsonb, metadata = $3::jsonb WHERE w.id = $1 RETURNING " + adminWalletColumns
+
+// searchWalletsQuery строит запрос поиска по фильтру. Запрашивается на одну
+// строку больше Limit, чтобы узнать, есть ли следующая страница.
+func searchWalletsQuery(filter models.WalletFilter) (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if len(filter.Labels) > 0 {
+		labels, _ := json.Marshal(filter.Labels)
+		conditions = append(conditions, "w.labels @> "+arg(string(labels))+"::jsonb")
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "w.status = "+arg(string(filter.Status)))
+	}
+	if filter.MinBalance != nil {
+		conditions = append(conditions, balanceExpr+" >= "+arg(*filter.MinBalance))
+	}
+	if filter.MaxBalance != nil {
+		conditions = append(conditions, balanceExpr+" <= "+arg(*filter.MaxBalance))
+	}
+	if !filter.CreatedFrom.IsZero() {
+		conditions = append(conditions, "w.created_at >= "+arg(filter.CreatedFrom))
+	}
+	if !filter.CreatedTo.IsZero() {
+		conditions = append(conditions, "w.created_at < "+arg(filter.CreatedTo))
+	}
+	if filter.After != nil {
+		conditions = append(conditions, "(w.created_at, w.id) > ("+arg(filter.After.CreatedAt)+", "+arg(filter.After.ID)+")")
+	}
+
+	query := "SELECT " + adminWalletColumns + " FROM wallets w"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY w.created_at, w.id LIMIT " + arg(filter.Limit+1)
+	return query, args
+}
+
+// newWalletPage отрезает лишнюю строку поиска и ставит курсор на последний
+// отданный кошелёк.
+func newWalletPage(wallets []models.Wallet, limit int) *models.WalletPage {
+	page := &models.WalletPage{Wallets: wallets}
+	if len(wallets) > limit {
+		page.Wallets = wallets[:limit]
+		last := page.Wallets[limit-1]
+		page.NextCursor = models.WalletCursor{CreatedAt: last.CreatedAt, ID: last.ID}.String()
+	}
+	return page
+}
+
+func (r *PostgresRepository) SetWalletMetadata(ctx context.Context, walletID uuid.UUID, labels map[string]string, metadata json.RawMessage) (*models.Wallet, error) {
+	labelsArg, metadataArg, err := metadataArgs(labels, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := scanAdminWallet(r.db.QueryRowContext(ctx, setMetadataQuery, walletID, labelsArg, metadataArg))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWalletNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (r *PostgresRepository) SearchWallets(ctx context.Context, filter models.WalletFilter) (*models.WalletPage, error) {
+	query, args := searchWalletsQuery(filter)
+
+	var wallets []models.Wallet
+	err := r.read(ctx, func(db *sql.DB) error {
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		wallets = make([]models.Wallet, 0, filter.Limit+1)
+		for rows.Next() {
+			wallet, err := scanAdminWallet(rows)
+			if err != nil {
+				return err
+			}
+			wallets = append(wallets, wallet)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newWalletPage(wallets, filter.Limit), nil
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchWalletsQuery(t *testing.T) {
+	query, args := searchWalletsQuery(models.WalletFilter{Limit: 10})
+	assert.NotContains(t, query, "FROM wallets w WHERE")
+	assert.True(t, strings.HasSuffix(query, "ORDER BY w.created_at, w.id LIMIT $1"))
+	assert.Equal(t, []any{11}, args)
+
+	minBalance := int64(100)
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cursor := &models.WalletCursor{CreatedAt: from, ID: uuid.New()}
+	query, args = searchWalletsQuery(models.WalletFilter{
+		Labels:      map[string]string{"team": "payments"},
+		Status:      models.StatusFrozen,
+		MinBalance:  &minBalance,
+		CreatedFrom: from,
+		Limit:       5,
+		After:       cursor,
+	})
+	assert.Contains(t, query, "w.labels @> $1::jsonb AND w.status = $2 AND ")
+	assert.Contains(t, query, ">= $3 AND w.created_at >= $4 AND (w.created_at, w.id) > ($5, $6) ORDER BY")
+	assert.Equal(t, []any{`{"team":"payments"}`, "FROZEN", minBalance, from, from, cursor.ID, 6}, args)
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// BatchApplier применяет несколько изменений баланса одного кошелька в одной
+// транзакции. Ошибка уровня пакета (кошелёк не найден, заморожен, сбой базы)
+// относится ко всем изменениям; иначе results[i] — результат amounts[i].
+type BatchApplier interface {
+	ApplyBatch(ctx context.Context, walletID uuid.UUID, amounts []int64) (results []BatchResult, err error)
+}
+
+// BatchResult — результат одного изменения в пакете: баланс сразу после него
+// или причина отказа.
+type BatchResult struct {
+	Balance int64
+	Err     error
+}
+
+// planBatch проходит изменения в порядке следования и отклоняет те, что
+// увели бы баланс в минус или выше limit.
+func planBatch(balance, limit int64, amounts []int64) (results []BatchResult, accepted []int64, delta int64) {
+	results = make([]BatchResult, len(amounts))
+	accepted = make([]int64, 0, len(amounts))
+	for i, amount := range amounts {
+		next, err := models.ApplyAmount(balance, amount, limit)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		balance = next
+		delta += amount
+		accepted = append(accepted, amount)
+		results[i].Balance = balance
+	}
+	return results, accepted, delta
+}
+
+type batchRequest struct {
+	amount   int64
+	deadline time.Time
+	canceled bool
+	done     chan BatchResult
+}
+
+type batch struct {
+	requests []*batchRequest
+	taken    bool
+	full     chan struct{}
+}
+
+// BatchingRepository собирает конкурентные UpdateBalance по одному кошельку
+// в течение window и применяет их одной транзакцией. Каждый вызывающий
+// получает собственный результат. Дедлайн транзакции — самый ранний из
+// дедлайнов участников, а без них — applyTimeout. Остальные методы
+// передаются в Repository без изменений.
+type BatchingRepository struct {
+	Repository
+
+	applier      BatchApplier
+	window       time.Duration
+	maxSize      int
+	applyTimeout time.Duration
+
+	mu      sync.Mutex
+	pending map[uuid.UUID]*batch
+}
+
+func NewBatchingRepository(repo Repository, applier BatchApplier, window time.Duration, maxSize int) *BatchingRepository {
+	return &BatchingRepository{
+		Repository:   repo,
+		applier:      applier,
+		window:       window,
+		maxSize:      maxSize,
+		applyTimeout: 30 * time.Second,
+		pending:      make(map[uuid.UUID]*batch),
+	}
+}
+
+func (r *BatchingRepository) UpdateBalance(ctx context.Context, walletID uuid.UUID, amount int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	req := &batchRequest{amount: amount, done: make(chan BatchResult, 1)}
+	req.deadline, _ = ctx.Deadline()
+
+	r.mu.Lock()
+	b, ok := r.pending[walletID]
+	if !ok {
+		b = &batch{full: make(chan struct{})}
+		r.pending[walletID] = b
+		go r.flush(walletID, b)
+	}
+	b.requests = append(b.requests, req)
+	if len(b.requests) >= r.maxSize {
+		delete(r.pending, walletID)
+		close(b.full)
+	}
+	r.mu.Unlock()
+
+	select {
+	case result := <-req.done:
+		return result.Balance, result.Err
+	case <-ctx.Done():
+	}
+
+	// Пока пакет не ушёл в базу, запрос отзывается. После этого пакет
+	// досчитывается в фоне, а вызывающий всё равно получает ошибку
+	// контекста: исход операции для него неизвестен, как при обрыве
+	// соединения на COMMIT, и ждать его дольше своего дедлайна он не должен.
+	r.mu.Lock()
+	if !b.taken {
+		req.canceled = true
+	}
+	r.mu.Unlock()
+	return 0, ctx.Err()
+}
+
+func (r *BatchingRepository) flush(walletID uuid.UUID, b *batch) {
+	timer := time.NewTimer(r.window)
+	select {
+	case <-timer.C:
+	case <-b.full:
+		timer.Stop()
+	}
+
+	r.mu.Lock()
+	if r.pending[walletID] == b {
+		delete(r.pending, walletID)
+	}
+	b.taken = true
+	requests := make([]*batchRequest, 0, len(b.requests))
+	for _, req := range b.requests {
+		if !req.canceled {
+			requests = append(requests, req)
+		}
+	}
+	r.mu.Unlock()
+
+	if len(requests) == 0 {
+		return
+	}
+
+	amounts := make([]int64, len(requests))
+	deadline := time.Now().Add(r.applyTimeout)
+	for i, req := range requests {
+		amounts[i] = req.amount
+		if !req.deadline.IsZero() && req.deadline.Before(deadline) {
+			deadline = req.deadline
+		}
+	}
+
+	// Пакет не переживает дедлайн самого нетерпеливого участника: иначе
+	// его ответ пришёл бы позже, чем он готов ждать.
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	results, err := r.applier.ApplyBatch(ctx, walletID, amounts)
+	for i, req := range requests {
+		if err != nil {
+			req.done <- BatchResult{Err: err}
+		} else {
+			req.done <- results[i]
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingApplier struct {
+	mu        sync.Mutex
+	batches   [][]int64
+	deadlines []time.Time
+	err       error
+	release   chan struct{}
+}
+
+func (a *recordingApplier) ApplyBatch(ctx context.Context, walletID uuid.UUID, amounts []int64) ([]BatchResult, error) {
+	if a.release != nil {
+		<-a.release
+	}
+	deadline, _ := ctx.Deadline()
+	a.mu.Lock()
+	a.batches = append(a.batches, append([]int64(nil), amounts...))
+	a.deadlines = append(a.deadlines, deadline)
+	a.mu.Unlock()
+	if a.err != nil {
+		return nil, a.err
+	}
+	results, _, _ := planBatch(0, models.MaxBalance, amounts)
+	return results, nil
+}
+
+func TestPlanBatch(t *testing.T) {
+	results, accepted, delta := planBatch(100, models.MaxBalance, []int64{-50, -80, 30, -70})
+
+	assert.Equal(t, []BatchResult{
+		{Balance: 50},
+		{Err: models.ErrInsufficientFunds},
+		{Balance: 80},
+		{Balance: 10},
+	}, results)
+	assert.Equal(t, []int64{-50, 30, -70}, accepted)
+	assert.Equal(t, int64(-90), delta)
+}
+
+func TestPlanBatch_BalanceLimit(t *testing.T) {
+	results, accepted, delta := planBatch(100, 150, []int64{40, 20, -30, 20})
+
+	assert.Equal(t, []BatchResult{
+		{Balance: 140},
+		{Err: models.ErrBalanceLimitExceeded},
+		{Balance: 110},
+		{Balance: 130},
+	}, results)
+	assert.Equal(t, []int64{40, -30, 20}, accepted)
+	assert.Equal(t, int64(30), delta)
+}
+
+func TestBatchingRepository_CoalescesConcurrentUpdates(t *testing.T) {
+	applier := &recordingApplier{}
+	repo := NewBatchingRepository(NewMemoryRepository(), applier, 50*time.Millisecond, 100)
+	walletID := uuid.New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.UpdateBalance(context.Background(), walletID, 10)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, applier.batches, 1)
+	assert.Len(t, applier.batches[0], 10)
+}
+
+func TestBatchingRepository_FlushesWhenFull(t *testing.T) {
+	applier := &recordingApplier{}
+	repo := NewBatchingRepository(NewMemoryRepository(), applier, time.Hour, 3)
+	walletID := uuid.New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.UpdateBalance(context.Background(), walletID, 10)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, applier.batches, 1)
+	assert.Len(t, applier.batches[0], 3)
+}
+
+func TestBatchingRepository_PerRequestResults(t *testing.T) {
+	mem := NewMemoryRepository()
+	walletID := newMemoryWallet(t, mem, 100)
+	repo := NewBatchingRepository(mem, mem, time.Millisecond, 100)
+	ctx := context.Background()
+
+	balance, err := repo.UpdateBalance(ctx, walletID, -60)
+	require.NoError(t, err)
+	assert.Equal(t, int64(40), balance)
+
+	_, err = repo.UpdateBalance(ctx, walletID, -60)
+	assert.Equal(t, models.ErrInsufficientFunds, err)
+
+	balance, err = repo.GetBalance(ctx, walletID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(40), balance)
+}
+
+func TestBatchingRepository_BatchError(t *testing.T) {
+	applier := &recordingApplier{err: errors.New("connection reset")}
+	repo := NewBatchingRepository(NewMemoryRepository(), applier, time.Millisecond, 100)
+
+	_, err := repo.UpdateBalance(context.Background(), uuid.New(), 10)
+
+	assert.EqualError(t, err, "connection reset")
+}
+
+func TestBatchingRepository_CanceledBeforeFlush(t *testing.T) {
+	applier := &recordingApplier{}
+	repo := NewBatchingRepository(NewMemoryRepository(), applier, 50*time.Millisecond, 100)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+
+	_, err := repo.UpdateBalance(ctx, uuid.New(), 10)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	time.Sleep(100 * time.Millisecond)
+	applier.mu.Lock()
+	defer applier.mu.Unlock()
+	assert.Empty(t, applier.batches)
+}
+
+// TestBatchingRepository_CanceledAfterFlushReturns проверяет, что
+// вызывающий не ждёт ушедший в базу пакет дольше своего контекста, а пакет
+// всё равно применяется.
+func TestBatchingRepository_CanceledAfterFlushReturns(t *testing.T) {
+	applier := &recordingApplier{release: make(chan struct{})}
+	repo := NewBatchingRepository(NewMemoryRepository(), applier, time.Millisecond, 100)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := repo.UpdateBalance(ctx, uuid.New(), 10)
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("UpdateBalance waited for the batch after its context was canceled")
+	}
+
+	close(applier.release)
+	require.Eventually(t, func() bool {
+		applier.mu.Lock()
+		defer applier.mu.Unlock()
+		return len(applier.batches) == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestBatchingRepository_EarliestDeadline(t *testing.T) {
+	applier := &recordingApplier{}
+	repo := NewBatchingRepository(NewMemoryRepository(), applier, 20*time.Millisecond, 100)
+	walletID := uuid.New()
+
+	soon := time.Now().Add(time.Second)
+	later, cancelLater := context.WithDeadline(context.Background(), soon.Add(time.Minute))
+	defer cancelLater()
+	earliest, cancelEarliest := context.WithDeadline(context.Background(), soon)
+	defer cancelEarliest()
+
+	var wg sync.WaitGroup
+	for _, ctx := range []context.Context{later, earliest, context.Background()} {
+		wg.Add(1)
+		go func(ctx context.Context) {
+			defer wg.Done()
+			_, err := repo.UpdateBalance(ctx, walletID, 10)
+			assert.NoError(t, err)
+		}(ctx)
+	}
+	wg.Wait()
+
+	applier.mu.Lock()
+	defer applier.mu.Unlock()
+	require.Len(t, applier.batches, 1)
+	assert.True(t, soon.Equal(applier.deadlines[0]), "batch deadline %v, want %v", applier.deadlines[0], soon)
+}
@@ -0,0 +1,326 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/DisasterWoman/wallet-service/internal/metrics"
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// WalletSource отдаёт кошельки файла импорта по одному. Неверная запись
+// возвращается как *models.ImportError и не прерывает чтение, конец
+// файла — io.EOF, любая другая ошибка прерывает импорт.
+type WalletSource interface {
+	Next() (*models.ImportedWallet, error)
+}
+
+// WalletSink получает кошельки экспорта по мере чтения из хранилища.
+type WalletSink interface {
+	Write(wallet *models.Wallet) error
+}
+
+// WalletBulk — массовые импорт и экспорт кошельков. Как и WalletAdmin, их
+// реализуют хранилища, а не декораторы.
+type WalletBulk interface {
+	// ImportWallets загружает кошельки source вместе с операциями OPENING
+	// на начальный баланс одной транзакцией. Недостающие владельцы
+	// создаются. Транзакция фиксируется, только если в файле нет неверных
+	// записей и дубликатов, итоги загруженного сошлись с итогами файла и
+	// dryRun не задан; отчёт возвращается в любом случае.
+	ImportWallets(ctx context.Context, source WalletSource, dryRun bool) (*models.ImportReport, error)
+	// ExportWallets передаёт sink все кошельки в порядке создания из одного
+	// снимка базы.
+	ExportWallets(ctx context.Context, sink WalletSink) error
+}
+
+// errImportUnbalanced означает, что итоги загруженных кошельков не сошлись
+// с итогами файла, хотя ошибок в записях нет.
+var errImportUnbalanced = errors.New("imported totals do not match the file")
+
+// importColumns — колонки временной таблицы wallet_import, в которую
+// записи файла загружаются COPY перед проверкой.
+var importColumns = []string{"line", "id", "owner_id", "currency", "balance", "status", "max_balance", "labels", "metadata"}
+
+const (
+	createImportTableQuery = `CREATE TEMP TABLE wallet_import (
+	line BIGINT NOT NULL,
+	id UUID NOT NULL,
+	owner_id UUID NOT NULL,
+	currency TEXT NOT NULL,
+	balance BIGINT NOT NULL,
+	status TEXT NOT NULL,
+	max_balance BIGINT NOT NULL,
+	labels JSONB NOT NULL,
+	metadata JSONB NOT NULL
+) ON COMMIT DROP`
+
+	// importConflictsQuery находит повторы id внутри файла (все вхождения,
+	// кроме первого) и кошельки, которые уже есть в базе.
+	importConflictsQuery = `SELECT line, id, false FROM (
+	SELECT line, id, row_number() OVER (PARTITION BY id ORDER BY line) AS n FROM wallet_import
+) d WHERE n > 1
+UNION ALL
+SELECT i.line, i.id, true FROM wallet_import i JOIN wallets w ON w.id = i.id
+ORDER BY 1`
+
+	importOwnersQuery   = "INSERT INTO owners (id) SELECT DISTINCT owner_id FROM wallet_import ON CONFLICT (id) DO NOTHING"
+	importWalletsQuery  = "INSERT INTO wallets (id, balance, status, currency, owner_id, max_balance, labels, metadata) SELECT id, balance, status, currency, owner_id, max_balance, labels, metadata FROM wallet_import"
+	importOpeningsQuery = "INSERT INTO wallet_operations (wallet_id, operation_type, amount) SELECT id, 'OPENING', balance FROM wallet_import WHERE balance <> 0"
+
+	// importTotalsQuery читает итоги загруженных кошельков из wallets, а не
+	// из wallet_import, чтобы сверка проверяла то, что записано.
+	importTotalsQuery = "SELECT w.currency, count(*), SUM(" + balanceExpr + ")::TEXT FROM wallets w JOIN wallet_import i ON i.id = w.id GROUP BY w.currency"
+
+	exportWalletsQuery = "SELECT w.id, " + balanceExpr + ", w.status, " + versionExpr + ", w.currency, w.owner_id, w.max_balance, w.labels, w.metadata, w.created_at " +
+		"FROM wallets w ORDER BY w.created_at, w.id"
+)
+
+// nextImport возвращает следующий проверенный кошелёк source, учитывая в
+// report и его, и пропущенные неверные записи.
+func nextImport(source WalletSource, report *models.ImportReport) (*models.ImportedWallet, error) {
+	for {
+		wallet, err := source.Next()
+		var importErr *models.ImportError
+		if errors.As(err, &importErr) {
+			report.AddInvalid(importErr)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		report.AddRecord(wallet)
+		return wallet, nil
+	}
+}
+
+// importValues готовит запись к COPY в wallet_import. Кошелёк без
+// maxBalance получает лимит по умолчанию, как и созданный через API.
+func importValues(wallet *models.ImportedWallet) ([]any, error) {
+	labels, metadata, err := metadataArgs(wallet.Labels, wallet.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	maxBalance := models.MaxBalance
+	if wallet.MaxBalance != nil {
+		maxBalance = *wallet.MaxBalance
+	}
+	return []any{wallet.Line, wallet.ID, wallet.OwnerID, string(wallet.Currency), wallet.Balance, string(wallet.Status), maxBalance, labels, metadata}, nil
+}
+
+// importTx — общее для *sql.Tx и pgx.Tx, на котором импорт проверяет и
+// переносит записи из wallet_import.
+type importTx interface {
+	exec(ctx context.Context, query string) (int64, error)
+	query(ctx context.Context, query string, scan func(row rowScanner) error) error
+}
+
+// applyImport проверяет загруженные в wallet_import записи на дубликаты и,
+// если их нет, переносит записи в wallets и журнал и сверяет итоги.
+func applyImport(ctx context.Context, tx importTx, report *models.ImportReport) error {
+	if _, err := tx.exec(ctx, "ANALYZE wallet_import"); err != nil {
+		return err
+	}
+	err := tx.query(ctx, importConflictsQuery, func(row rowScanner) error {
+		var (
+			line   int64
+			id     uuid.UUID
+			exists bool
+		)
+		if err := row.Scan(&line, &id, &exists); err != nil {
+			return err
+		}
+		conflict := models.ErrDuplicateWallet
+		if exists {
+			conflict = models.ErrWalletExists
+		}
+		report.AddError(models.NewImportError(line, &id, conflict))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if report.ErrorCount > 0 {
+		report.Reconcile(models.CurrencyTotals{})
+		return nil
+	}
+
+	if report.OwnersCreated, err = tx.exec(ctx, importOwnersQuery); err != nil {
+		return err
+	}
+	if report.Wallets, err = tx.exec(ctx, importWalletsQuery); err != nil {
+		return err
+	}
+	if _, err := tx.exec(ctx, importOpeningsQuery); err != nil {
+		return err
+	}
+
+	loaded := make(models.CurrencyTotals)
+	err = tx.query(ctx, importTotalsQuery, func(row rowScanner) error {
+		var (
+			currency models.Currency
+			wallets  int
+			sum      string
+		)
+		if err := row.Scan(&currency, &wallets, &sum); err != nil {
+			return err
+		}
+		balance, ok := new(big.Int).SetString(sum, 10)
+		if !ok {
+			return fmt.Errorf("invalid balance total %q", sum)
+		}
+		loaded.Add(currency, wallets, balance)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	report.Reconcile(loaded)
+	if !report.Balanced {
+		return errImportUnbalanced
+	}
+	return nil
+}
+
+// ImportWallets загружает записи COPY во временную таблицу, а проверку и
+// перенос в wallets выполняет одним запросом на шаг. Транзакция не
+// повторяется: source уже прочитан.
+func (r *PostgresRepository) ImportWallets(ctx context.Context, source WalletSource, dryRun bool) (*models.ImportReport, error) {
+	report := models.NewImportReport(dryRun)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, createImportTableQuery); err != nil {
+		return nil, err
+	}
+	if err := copyImport(ctx, tx, source, report); err != nil {
+		return nil, err
+	}
+	if err := applyImport(ctx, sqlImportTx{tx}, report); err != nil {
+		return nil, err
+	}
+
+	if report.ErrorCount > 0 || dryRun {
+		return report, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	report.Committed = true
+	return report, nil
+}
+
+func copyImport(ctx context.Context, tx *sql.Tx, source WalletSource, report *models.ImportReport) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("wallet_import", importColumns...))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for {
+		wallet, err := nextImport(source, report)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		values, err := importValues(wallet)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return err
+		}
+	}
+	_, err = stmt.ExecContext(ctx)
+	return err
+}
+
+type sqlImportTx struct {
+	tx *sql.Tx
+}
+
+func (t sqlImportTx) exec(ctx context.Context, query string) (int64, error) {
+	result, err := t.tx.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (t sqlImportTx) query(ctx context.Context, query string, scan func(row rowScanner) error) error {
+	rows, err := t.tx.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ExportWallets читает кошельки одним запросом, поэтому все они — из
+// одного снимка. Экспорт идёт на реплику, если она достаточно свежая:
+// повторить его на основной базе после первой записи в sink уже нельзя.
+func (r *PostgresRepository) ExportWallets(ctx context.Context, sink WalletSink) error {
+	db := r.readDB(ctx)
+	if db != nil {
+		metrics.ReplicaReads.WithLabelValues("replica").Inc()
+	} else {
+		db = r.db
+		metrics.ReplicaReads.WithLabelValues("primary").Inc()
+	}
+
+	rows, err := db.QueryContext(ctx, exportWalletsQuery)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		wallet, err := scanExportWallet(rows)
+		if err != nil {
+			return err
+		}
+		if err := sink.Write(&wallet); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// scanExportWallet читает кошелёк из строки exportWalletsQuery вместе с
+// лимитом, метками и метаданными.
+func scanExportWallet(row rowScanner) (models.Wallet, error) {
+	var (
+		wallet     models.Wallet
+		maxBalance int64
+		labels     []byte
+		metadata   []byte
+	)
+	err := row.Scan(&wallet.ID, &wallet.Balance, &wallet.Status, &wallet.Version, &wallet.Currency, &wallet.OwnerID, &maxBalance, &labels, &metadata, &wallet.CreatedAt)
+	if err != nil {
+		return wallet, err
+	}
+	if err := json.Unmarshal(labels, &wallet.Labels); err != nil {
+		return wallet, err
+	}
+	wallet.MaxBalance = &maxBalance
+	wallet.Metadata = metadata
+	return wallet, nil
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/DisasterWoman/wallet-service/internal/cache"
+	"github.com/DisasterWoman/wallet-service/internal/metrics"
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// fill отслеживает чтения кошелька из Repository, которые идут мимо кэша.
+// Инвалидация увеличивает generation, и прочитанный до неё снимок уже не
+// попадает в кэш.
+type fill struct {
+	readers    int
+	generation uint64
+}
+
+// CachingRepository отдаёт GetWallet и GetBalance из cache.Cache (кроме
+// строгих чтений, см. WithStrongRead) и инвалидирует запись кошелька после
+// каждой операции, которая могла его изменить. Изменения, сделанные другими
+// инстансами, приходят через Invalidate (см. ListenInvalidations); остальное
+// ограничено временем жизни записей в кэше.
+type CachingRepository struct {
+	Repository
+
+	cache cache.Cache
+
+	mu    sync.Mutex
+	fills map[uuid.UUID]*fill
+}
+
+func NewCachingRepository(repo Repository, c cache.Cache) *CachingRepository {
+	return &CachingRepository{
+		Repository: repo,
+		cache:      c,
+		fills:      make(map[uuid.UUID]*fill),
+	}
+}
+
+func (r *CachingRepository) GetWallet(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error) {
+	if !IsStrongRead(ctx) {
+		if wallet, ok := r.cache.Get(ctx, walletID); ok {
+			metrics.CacheRequests.WithLabelValues("hit").Inc()
+			return &wallet, nil
+		}
+		metrics.CacheRequests.WithLabelValues("miss").Inc()
+	}
+
+	r.mu.Lock()
+	f, ok := r.fills[walletID]
+	if !ok {
+		f = &fill{}
+		r.fills[walletID] = f
+	}
+	f.readers++
+	generation := f.generation
+	r.mu.Unlock()
+
+	wallet, err := r.Repository.GetWallet(ctx, walletID)
+
+	r.mu.Lock()
+	// Set под мьютексом, чтобы инвалидация не проскочила между проверкой
+	// поколения и записью в кэш.
+	if err == nil && f.generation == generation {
+		r.cache.Set(ctx, *wallet)
+	}
+	f.readers--
+	if f.readers == 0 {
+		delete(r.fills, walletID)
+	}
+	r.mu.Unlock()
+
+	return wallet, err
+}
+
+func (r *CachingRepository) GetBalance(ctx context.Context, walletID uuid.UUID) (int64, error) {
+	wallet, err := r.GetWallet(ctx, walletID)
+	if err != nil {
+		return 0, err
+	}
+	return wallet.Balance, nil
+}
+
+// Операции ниже инвалидируют кэш и при ошибке: она не гарантирует, что
+// изменение не было применено (например, контекст отменён после COMMIT).
+
+func (r *CachingRepository) UpdateBalance(ctx context.Context, walletID uuid.UUID, amount int64) (int64, error) {
+	defer r.invalidate(walletID, "local")
+	return r.Repository.UpdateBalance(ctx, walletID, amount)
+}
+
+func (r *CachingRepository) UpdateBalanceIfVersion(ctx context.Context, walletID uuid.UUID, amount, version int64) (*models.Wallet, error) {
+	defer r.invalidate(walletID, "local")
+	return r.Repository.UpdateBalanceIfVersion(ctx, walletID, amount, version)
+}
+
+func (r *CachingRepository) Transfer(ctx context.Context, fromID, toID uuid.UUID, amount int64) (uuid.UUID, error) {
+	defer r.invalidate(toID, "local")
+	defer r.invalidate(fromID, "local")
+	return r.Repository.Transfer(ctx, fromID, toID, amount)
+}
+
+func (r *CachingRepository) SetStatus(ctx context.Context, walletID uuid.UUID, status models.WalletStatus) error {
+	defer r.invalidate(walletID, "local")
+	return r.Repository.SetStatus(ctx, walletID, status)
+}
+
+// Invalidate удаляет кошелёк из кэша после изменения в другом инстансе.
+func (r *CachingRepository) Invalidate(walletID uuid.UUID) {
+	r.invalidate(walletID, "notify")
+}
+
+// InvalidateAll очищает кэш, когда об изменениях больше нельзя узнать
+// точно, например после потери соединения с базой.
+func (r *CachingRepository) InvalidateAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, f := range r.fills {
+		f.generation++
+	}
+	r.cache.Purge(context.Background())
+	metrics.CacheInvalidations.WithLabelValues("reset").Inc()
+}
+
+func (r *CachingRepository) invalidate(walletID uuid.UUID, source string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if f, ok := r.fills[walletID]; ok {
+		f.generation++
+	}
+	r.cache.Delete(context.Background(), walletID)
+	metrics.CacheInvalidations.WithLabelValues(source).Inc()
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/cache"
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRepository считает чтения кошельков и может задержать чтение до
+// закрытия block.
+type countingRepository struct {
+	Repository
+	reads atomic.Int64
+	block chan struct{}
+	read  chan struct{}
+}
+
+func (r *countingRepository) GetWallet(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error) {
+	r.reads.Add(1)
+	wallet, err := r.Repository.GetWallet(ctx, walletID)
+	if r.block != nil {
+		r.read <- struct{}{}
+		<-r.block
+	}
+	return wallet, err
+}
+
+func newCachingFixture(t *testing.T, balance int64) (*CachingRepository, *countingRepository, uuid.UUID) {
+	t.Helper()
+
+	memory := NewMemoryRepository()
+	walletID := uuid.New()
+	_, err := memory.CreateWallet(context.Background(), walletID, newMemoryOwner(t, memory))
+	require.NoError(t, err)
+	if balance > 0 {
+		_, err = memory.UpdateBalance(context.Background(), walletID, balance)
+		require.NoError(t, err)
+	}
+
+	inner := &countingRepository{Repository: memory}
+	return NewCachingRepository(inner, cache.NewLRU(100, time.Minute)), inner, walletID
+}
+
+func TestCachingRepository_ServesRepeatedReadsFromCache(t *testing.T) {
+	repo, inner, walletID := newCachingFixture(t, 500)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		balance, err := repo.GetBalance(ctx, walletID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(500), balance)
+	}
+	wallet, err := repo.GetWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), wallet.Version)
+
+	assert.Equal(t, int64(1), inner.reads.Load())
+}
+
+func TestCachingRepository_NotFoundIsNotCached(t *testing.T) {
+	repo, inner, _ := newCachingFixture(t, 0)
+	missing := uuid.New()
+
+	for i := 0; i < 2; i++ {
+		_, err := repo.GetBalance(context.Background(), missing)
+		assert.Equal(t, ErrWalletNotFound, err)
+	}
+	assert.Equal(t, int64(2), inner.reads.Load())
+}
+
+func TestCachingRepository_WritesInvalidate(t *testing.T) {
+	repo, _, walletID := newCachingFixture(t, 500)
+	ctx := context.Background()
+	otherID, ownerID := uuid.New(), uuid.New()
+	_, err := repo.CreateOwner(ctx, ownerID, "")
+	require.NoError(t, err)
+	_, err = repo.CreateWallet(ctx, otherID, ownerID)
+	require.NoError(t, err)
+
+	read := func(id uuid.UUID) int64 {
+		balance, err := repo.GetBalance(ctx, id)
+		require.NoError(t, err)
+		return balance
+	}
+
+	read(walletID)
+	_, err = repo.UpdateBalance(ctx, walletID, -100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(400), read(walletID))
+
+	_, err = repo.UpdateBalanceIfVersion(ctx, walletID, 50, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(450), read(walletID))
+
+	read(otherID)
+	_, err = repo.Transfer(ctx, walletID, otherID, 200)
+	require.NoError(t, err)
+	assert.Equal(t, int64(250), read(walletID))
+	assert.Equal(t, int64(200), read(otherID))
+
+	require.NoError(t, repo.SetStatus(ctx, walletID, models.StatusFrozen))
+	wallet, err := repo.GetWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusFrozen, wallet.Status)
+}
+
+func TestCachingRepository_InvalidateFromOtherInstance(t *testing.T) {
+	repo, inner, walletID := newCachingFixture(t, 500)
+	ctx := context.Background()
+
+	repo.GetBalance(ctx, walletID)
+	// Изменение мимо CachingRepository, как из другого инстанса.
+	_, err := inner.Repository.UpdateBalance(ctx, walletID, 100)
+	require.NoError(t, err)
+
+	balance, _ := repo.GetBalance(ctx, walletID)
+	assert.Equal(t, int64(500), balance)
+
+	repo.Invalidate(walletID)
+	balance, _ = repo.GetBalance(ctx, walletID)
+	assert.Equal(t, int64(600), balance)
+
+	_, err = inner.Repository.UpdateBalance(ctx, walletID, 100)
+	require.NoError(t, err)
+	repo.InvalidateAll()
+	balance, _ = repo.GetBalance(ctx, walletID)
+	assert.Equal(t, int64(700), balance)
+}
+
+func TestCachingRepository_InvalidationDuringReadDiscardsSnapshot(t *testing.T) {
+	repo, inner, walletID := newCachingFixture(t, 500)
+	ctx := context.Background()
+	inner.block = make(chan struct{})
+	inner.read = make(chan struct{}, 1)
+
+	done := make(chan int64)
+	go func() {
+		balance, _ := repo.GetBalance(ctx, walletID)
+		done <- balance
+	}()
+
+	// Чтение уже получило старый баланс, а запись успела завершиться до
+	// того, как снимок попал в кэш.
+	<-inner.read
+	_, err := inner.Repository.UpdateBalance(ctx, walletID, 100)
+	require.NoError(t, err)
+	repo.Invalidate(walletID)
+	close(inner.block)
+	assert.Equal(t, int64(500), <-done)
+
+	inner.block = nil
+	balance, err := repo.GetBalance(ctx, walletID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(600), balance)
+	assert.Equal(t, int64(2), inner.reads.Load())
+}
+
+func TestCachingRepository_StrongReadBypassesCache(t *testing.T) {
+	repo, inner, walletID := newCachingFixture(t, 500)
+	ctx := context.Background()
+
+	repo.GetBalance(ctx, walletID)
+	_, err := inner.Repository.UpdateBalance(ctx, walletID, 100)
+	require.NoError(t, err)
+
+	balance, err := repo.GetBalance(WithStrongRead(ctx), walletID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(600), balance)
+
+	// Строгое чтение обновило запись в кэше.
+	balance, _ = repo.GetBalance(ctx, walletID)
+	assert.Equal(t, int64(600), balance)
+	assert.Equal(t, int64(2), inner.reads.Load())
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/metrics"
+	"github.com/google/uuid"
+)
+
+// balanceAsOfQuery — баланс кошелька $1 на момент $2: ближайшая контрольная
+// точка не позже $2 плюс операции журнала между ней и $2. Без точки
+// суммируется весь журнал кошелька до $2.
+const balanceAsOfQuery = `
+	SELECT (COALESCE(c.balance, 0) + COALESCE((
+		SELECT SUM(o.amount) FROM wallet_operations o
+		WHERE o.wallet_id = w.id AND o.created_at <= $2 AND o.created_at > COALESCE(c.as_of, '-infinity')
+	), 0))::BIGINT
+	FROM wallets w
+	LEFT JOIN LATERAL (
+		SELECT as_of, balance FROM wallet_balance_checkpoints
+		WHERE wallet_id = w.id AND as_of <= $2
+		ORDER BY as_of DESC LIMIT 1
+	) c ON true
+	WHERE w.id = $1`
+
+// createCheckpointsQuery записывает точки на момент $1 для кошельков с
+// операциями после последнего прогона. Остаток считается от последней
+// точки кошелька до $1 и операций после неё, а не после последнего
+// прогона: операция, зафиксированная задним числом между точкой кошелька и
+// прогоном, иначе выпала бы из новой точки. Параллельные прогоны с разными
+// $1 не мешают друг другу: каждый считает от точек, видимых в его снимке.
+const createCheckpointsQuery = `
+	WITH previous AS (
+		SELECT COALESCE(MAX(as_of), '-infinity') AS as_of FROM wallet_balance_checkpoints
+	), touched AS (
+		SELECT DISTINCT o.wallet_id
+		FROM wallet_operations o, previous p
+		WHERE o.created_at > p.as_of AND o.created_at <= $1
+	)
+	INSERT INTO wallet_balance_checkpoints (wallet_id, as_of, balance)
+	SELECT t.wallet_id, $1, (COALESCE(c.balance, 0) + COALESCE((
+		SELECT SUM(o.amount) FROM wallet_operations o
+		WHERE o.wallet_id = t.wallet_id AND o.created_at <= $1 AND o.created_at > COALESCE(c.as_of, '-infinity')
+	), 0))::BIGINT
+	FROM touched t
+	LEFT JOIN LATERAL (
+		SELECT as_of, balance FROM wallet_balance_checkpoints
+		WHERE wallet_id = t.wallet_id AND as_of < $1
+		ORDER BY as_of DESC LIMIT 1
+	) c ON true
+	ON CONFLICT (wallet_id, as_of) DO NOTHING`
+
+// BalanceAsOf возвращает баланс кошелька на момент asOf по журналу
+// операций и контрольным точкам.
+func (r *PostgresRepository) BalanceAsOf(ctx context.Context, walletID uuid.UUID, asOf time.Time) (int64, error) {
+	var balance int64
+	err := r.read(ctx, func(db *sql.DB) error {
+		return db.QueryRowContext(ctx, balanceAsOfQuery, walletID, asOf).Scan(&balance)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrWalletNotFound
+	}
+	return balance, err
+}
+
+// CreateCheckpoints записывает контрольные точки баланса на момент at и
+// возвращает их число.
+//
+// Операция получает created_at = now(), то есть время начала своей
+// транзакции, а видна становится только после фиксации. Операцию,
+// вставленную после фиксации точки, добавляет к ней триггер
+// wallet_operations_correct_checkpoints, но транзакцию, которая идёт во
+// время прогона, не видят ни прогон, ни триггер. Поэтому at должен
+// отставать от текущего времени больше, чем длится самая долгая транзакция.
+func CreateCheckpoints(ctx context.Context, db *sql.DB, at time.Time) (int64, error) {
+	result, err := db.ExecContext(ctx, createCheckpointsQuery, at)
+	if err != nil {
+		return 0, err
+	}
+	created, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	metrics.CheckpointsCreated.Add(float64(created))
+	metrics.CheckpointAsOf.Set(float64(at.Unix()))
+	return created, nil
+}
+
+// RunCheckpoints каждые interval записывает контрольные точки на момент,
+// отстоящий от текущего на delay и выровненный по interval, до отмены ctx.
+func RunCheckpoints(ctx context.Context, db *sql.DB, interval, delay time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		at := time.Now().Add(-delay).Truncate(interval)
+		created, err := CreateCheckpoints(ctx, db, at)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Printf("Balance checkpoint as of %s failed: %v", at.Format(time.RFC3339), err)
+		case created > 0:
+			log.Printf("Created %d balance checkpoint(s) as of %s", created, at.Format(time.RFC3339))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
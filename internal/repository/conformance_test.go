@@ -0,0 +1,165 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/cache"
+	"github.com/DisasterWoman/wallet-service/internal/repository"
+	"github.com/DisasterWoman/wallet-service/internal/repository/repotest"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	_ "github.com/lib/pq"
+)
+
+func TestMemoryRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.Repository {
+		return repository.NewMemoryRepository()
+	})
+}
+
+func TestBatchingRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.Repository {
+		repo := repository.NewMemoryRepository()
+		return repository.NewBatchingRepository(repo, repo, time.Millisecond, 16)
+	})
+}
+
+func TestCachingRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.Repository {
+		return repository.NewCachingRepository(repository.NewMemoryRepository(), cache.NewLRU(1000, time.Minute))
+	})
+}
+
+func TestPostgresRepository_Batching_Conformance(t *testing.T) {
+	db := openTestDB(t)
+
+	repotest.Run(t, func(t *testing.T) repository.Repository {
+		repo := repository.NewPostgresRepository(db)
+		return repository.NewBatchingRepository(repo, repo, time.Millisecond, 16)
+	})
+}
+
+func TestPostgresRepository_Conformance(t *testing.T) {
+	db := openTestDB(t)
+
+	repotest.Run(t, func(t *testing.T) repository.Repository {
+		return repository.NewPostgresRepository(db)
+	})
+}
+
+func TestPostgresRepository_Conditional_Conformance(t *testing.T) {
+	db := openTestDB(t)
+
+	repotest.Run(t, func(t *testing.T) repository.Repository {
+		return repository.NewPostgresRepository(db, repository.WithConditionalUpdate())
+	})
+}
+
+// С таймаутами условное обновление идёт в короткой транзакции.
+func TestPostgresRepository_ConditionalTimeouts_Conformance(t *testing.T) {
+	db := openTestDB(t)
+
+	repotest.Run(t, func(t *testing.T) repository.Repository {
+		return repository.NewPostgresRepository(
+			db,
+			repository.WithConditionalUpdate(),
+			repository.WithTimeouts(repository.Timeouts{Lock: 5 * time.Second, Statement: 30 * time.Second}),
+		)
+	})
+}
+
+func TestPostgresRepository_Sharding_Conformance(t *testing.T) {
+	db := openTestDB(t)
+
+	repotest.Run(t, func(t *testing.T) repository.Repository {
+		return repository.NewPostgresRepository(db, repository.WithSharding())
+	})
+}
+
+// Под SERIALIZABLE конкурентные операции регулярно получают 40001 и
+// проходят только благодаря повторам транзакций.
+func TestPostgresRepository_Serializable_Conformance(t *testing.T) {
+	db := openTestDB(t)
+
+	repotest.Run(t, func(t *testing.T) repository.Repository {
+		return repository.NewPostgresRepository(
+			db,
+			repository.WithIsolation(sql.LevelSerializable),
+			repository.WithRetryPolicy(repository.RetryPolicy{
+				MaxAttempts: 50,
+				BaseDelay:   time.Millisecond,
+				MaxDelay:    20 * time.Millisecond,
+			}),
+		)
+	})
+}
+
+func TestPgxRepository_Conformance(t *testing.T) {
+	pool := openTestPgxPool(t)
+
+	repotest.Run(t, func(t *testing.T) repository.Repository {
+		return repository.NewPgxRepository(pool)
+	})
+}
+
+func TestPgxRepository_Batching_Conformance(t *testing.T) {
+	pool := openTestPgxPool(t)
+
+	repotest.Run(t, func(t *testing.T) repository.Repository {
+		repo := repository.NewPgxRepository(pool)
+		return repository.NewBatchingRepository(repo, repo, time.Millisecond, 16)
+	})
+}
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("postgres", testDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	return db
+}
+
+func openTestPgxPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	config, err := pgxpool.ParseConfig(testDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.AfterConnect = repository.PreparePgxStatements
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	if err := pool.Ping(ctx); err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	return pool
+}
+
+func testDSN() string {
+	if dsn := os.Getenv("TEST_DATABASE_DSN"); dsn != "" {
+		return dsn
+	}
+	return "host=localhost port=5433 user=wallet_user password=wallet_password dbname=wallet_db sslmode=disable"
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// SetCurrency задаёт валюту кошелька. Суммы хранятся в минимальных
+// единицах, поэтому сменить валюту можно только у пустого кошелька: иначе
+// баланс молча поменял бы смысл. Смена валюты увеличивает версию кошелька.
+func (r *PostgresRepository) SetCurrency(ctx context.Context, walletID uuid.UUID, currency models.Currency) error {
+	if !currency.Valid() {
+		return models.ErrInvalidCurrency
+	}
+
+	return r.inTx(ctx, "set_currency", func(tx *sql.Tx) error {
+		wallet, err := lockWallet(ctx, tx, walletID)
+		if err != nil {
+			return err
+		}
+		if wallet.currency == currency {
+			return nil
+		}
+		if wallet.balance != 0 {
+			return models.ErrWalletNotEmpty
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE wallets SET currency = $1, version = version + 1 WHERE id = $2", currency, walletID)
+		return err
+	})
+}
+
+// CreateWalletInCurrency создаёт кошелёк владельца ownerID сразу в валюте
+// currency. Это одна вставка, поэтому, в отличие от CreateWallet и
+// следующего за ним SetCurrency, ошибка не оставляет кошелька в валюте по
+// умолчанию.
+func (r *PostgresRepository) CreateWalletInCurrency(ctx context.Context, walletID, ownerID uuid.UUID, currency models.Currency) (*models.Wallet, error) {
+	if !currency.Valid() {
+		return nil, models.ErrInvalidCurrency
+	}
+
+	wallet := models.Wallet{ID: walletID}
+	err := r.db.QueryRowContext(
+		ctx,
+		"INSERT INTO wallets (id, owner_id, currency) VALUES ($1, $2, $3) ON CONFLICT (id) DO NOTHING RETURNING balance, status, version, currency, owner_id, created_at",
+		walletID,
+		ownerID,
+		string(currency),
+	).Scan(&wallet.Balance, &wallet.Status, &wallet.Version, &wallet.Currency, &wallet.OwnerID, &wallet.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWalletExists
+	}
+	if err != nil {
+		return nil, ownerError(err)
+	}
+	return &wallet, nil
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+)
+
+const codeCheckViolation = "23514"
+
+// balanceLimitConstraints — ограничения из миграции 0007, нарушение которых
+// означает превышение потолка баланса.
+var balanceLimitConstraints = map[string]bool{
+	"wallets_balance_limit":       true,
+	"wallet_shards_balance_limit": true,
+}
+
+// SetMaxBalance задаёт потолок баланса кошелька. Лимит не может быть ниже
+// текущего баланса.
+func (r *PostgresRepository) SetMaxBalance(ctx context.Context, walletID uuid.UUID, limit int64) error {
+	if limit < 0 || limit > models.MaxBalance {
+		return models.ErrInvalidBalanceLimit
+	}
+
+	return r.inTx(ctx, "set_max_balance", func(tx *sql.Tx) error {
+		wallet, err := lockWallet(ctx, tx, walletID)
+		if err != nil {
+			return err
+		}
+		if wallet.balance > limit {
+			return models.ErrBalanceLimitExceeded
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE wallets SET max_balance = $1 WHERE id = $2", limit, walletID)
+		return err
+	})
+}
+
+// limitError переводит нарушение ограничений потолка баланса в
+// ErrBalanceLimitExceeded. До базы такие операции обычно не доходят:
+// ограничения страхуют проверки models.ApplyAmount.
+func limitError(err error) error {
+	var (
+		pqErr      *pq.Error
+		pgxErr     *pgconn.PgError
+		code       string
+		constraint string
+	)
+	switch {
+	case errors.As(err, &pqErr):
+		code, constraint = string(pqErr.Code), pqErr.Constraint
+	case errors.As(err, &pgxErr):
+		code, constraint = pgxErr.Code, pgxErr.ConstraintName
+	default:
+		return err
+	}
+
+	if code == codeCheckViolation && balanceLimitConstraints[constraint] {
+		return fmt.Errorf("%w: %w", models.ErrBalanceLimitExceeded, err)
+	}
+	return err
+}
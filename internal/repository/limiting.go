@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/metrics"
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrOverloaded означает, что операция не дождалась свободного слота под
+// транзакцию и отклонена без обращения к базе.
+var ErrOverloaded = errors.New("too many operations in flight")
+
+// LimitingRepository ограничивает число одновременных обращений к Repository,
+// чтобы всплеск запросов не занимал весь пул соединений. Операция ждёт слот
+// не дольше maxWait, а затем получает ErrOverloaded.
+//
+// Выписка держит транзакцию, пока клиент читает ответ, поэтому у неё свои
+// слоты: медленные загрузки не вытесняют короткие операции, а короткие
+// операции — выписки.
+type LimitingRepository struct {
+	repo       Repository
+	applier    BatchApplier
+	operations limiter
+	statements limiter
+	maxWait    time.Duration
+}
+
+// NewLimitingRepository допускает не больше maxInFlight операций и
+// maxStatements выписок одновременно; 0 снимает соответствующее
+// ограничение. Если repo реализует BatchApplier, LimitingRepository тоже его
+// реализует, и пакет BatchingRepository занимает один слот.
+func NewLimitingRepository(repo Repository, maxInFlight, maxStatements int, maxWait time.Duration) *LimitingRepository {
+	applier, _ := repo.(BatchApplier)
+	return &LimitingRepository{
+		repo:       repo,
+		applier:    applier,
+		operations: newLimiter(maxInFlight, metrics.InFlightOperations),
+		statements: newLimiter(maxStatements, metrics.InFlightStatements),
+		maxWait:    maxWait,
+	}
+}
+
+// limiter — семафор на slots слотов; nil slots означает «без ограничения».
+type limiter struct {
+	slots    chan struct{}
+	inFlight prometheus.Gauge
+}
+
+func newLimiter(size int, inFlight prometheus.Gauge) limiter {
+	if size <= 0 {
+		return limiter{}
+	}
+	return limiter{slots: make(chan struct{}, size), inFlight: inFlight}
+}
+
+func (l limiter) acquire(ctx context.Context, maxWait time.Duration) error {
+	if l.slots == nil {
+		return nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		l.inFlight.Inc()
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		l.inFlight.Inc()
+		return nil
+	case <-timer.C:
+		metrics.ShedOperations.Inc()
+		return ErrOverloaded
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l limiter) release() {
+	if l.slots == nil {
+		return
+	}
+	<-l.slots
+	l.inFlight.Dec()
+}
+
+func (r *LimitingRepository) acquire(ctx context.Context) error {
+	return r.operations.acquire(ctx, r.maxWait)
+}
+
+func (r *LimitingRepository) release() {
+	r.operations.release()
+}
+
+func (r *LimitingRepository) CreateOwner(ctx context.Context, ownerID uuid.UUID, name string) (*models.Owner, error) {
+	if err := r.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer r.release()
+	return r.repo.CreateOwner(ctx, ownerID, name)
+}
+
+func (r *LimitingRepository) CreateWallet(ctx context.Context, walletID, ownerID uuid.UUID) (*models.Wallet, error) {
+	if err := r.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer r.release()
+	return r.repo.CreateWallet(ctx, walletID, ownerID)
+}
+
+func (r *LimitingRepository) GetWallet(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error) {
+	if err := r.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer r.release()
+	return r.repo.GetWallet(ctx, walletID)
+}
+
+func (r *LimitingRepository) GetBalance(ctx context.Context, walletID uuid.UUID) (int64, error) {
+	if err := r.acquire(ctx); err != nil {
+		return 0, err
+	}
+	defer r.release()
+	return r.repo.GetBalance(ctx, walletID)
+}
+
+func (r *LimitingRepository) UpdateBalance(ctx context.Context, walletID uuid.UUID, amount int64) (int64, error) {
+	if err := r.acquire(ctx); err != nil {
+		return 0, err
+	}
+	defer r.release()
+	return r.repo.UpdateBalance(ctx, walletID, amount)
+}
+
+func (r *LimitingRepository) UpdateBalanceIfVersion(ctx context.Context, walletID uuid.UUID, amount, version int64) (*models.Wallet, error) {
+	if err := r.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer r.release()
+	return r.repo.UpdateBalanceIfVersion(ctx, walletID, amount, version)
+}
+
+func (r *LimitingRepository) Transfer(ctx context.Context, fromID, toID uuid.UUID, amount int64) (uuid.UUID, error) {
+	if err := r.acquire(ctx); err != nil {
+		return uuid.Nil, err
+	}
+	defer r.release()
+	return r.repo.Transfer(ctx, fromID, toID, amount)
+}
+
+func (r *LimitingRepository) SetStatus(ctx context.Context, walletID uuid.UUID, status models.WalletStatus) error {
+	if err := r.acquire(ctx); err != nil {
+		return err
+	}
+	defer r.release()
+	return r.repo.SetStatus(ctx, walletID, status)
+}
+
+func (r *LimitingRepository) History(ctx context.Context, walletID uuid.UUID, limit int) ([]models.Operation, error) {
+	if err := r.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer r.release()
+	return r.repo.History(ctx, walletID, limit)
+}
+
+func (r *LimitingRepository) ListOwnerWallets(ctx context.Context, ownerID uuid.UUID) ([]models.Wallet, error) {
+	if err := r.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer r.release()
+	return r.repo.ListOwnerWallets(ctx, ownerID)
+}
+
+func (r *LimitingRepository) BalanceAsOf(ctx context.Context, walletID uuid.UUID, asOf time.Time) (int64, error) {
+	if err := r.acquire(ctx); err != nil {
+		return 0, err
+	}
+	defer r.release()
+	return r.repo.BalanceAsOf(ctx, walletID, asOf)
+}
+
+// StreamStatement занимает слот выписки, а не операции, на всё время
+// чтения выписки.
+func (r *LimitingRepository) StreamStatement(ctx context.Context, walletID uuid.UUID, from, to time.Time, sink StatementSink) error {
+	if err := r.statements.acquire(ctx, r.maxWait); err != nil {
+		return err
+	}
+	defer r.statements.release()
+	return r.repo.StreamStatement(ctx, walletID, from, to, sink)
+}
+
+// ApplyBatch передаёт пакет в BatchApplier обёрнутого репозитория.
+func (r *LimitingRepository) ApplyBatch(ctx context.Context, walletID uuid.UUID, amounts []int64) ([]BatchResult, error) {
+	if r.applier == nil {
+		return nil, errors.New("wrapped repository does not support batching")
+	}
+	if err := r.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer r.release()
+	return r.applier.ApplyBatch(ctx, walletID, amounts)
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingRepository задерживает GetWallet и StreamStatement до закрытия
+// release.
+type blockingRepository struct {
+	Repository
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingRepository) GetWallet(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error) {
+	r.entered <- struct{}{}
+	<-r.release
+	return r.Repository.GetWallet(ctx, walletID)
+}
+
+func (r *blockingRepository) StreamStatement(ctx context.Context, walletID uuid.UUID, from, to time.Time, sink StatementSink) error {
+	r.entered <- struct{}{}
+	<-r.release
+	return r.Repository.StreamStatement(ctx, walletID, from, to, sink)
+}
+
+// discardSink отбрасывает записи выписки.
+type discardSink struct{}
+
+func (discardSink) Opening(*models.Wallet, int64) error { return nil }
+func (discardSink) Operation(*models.Operation) error   { return nil }
+
+func TestLimitingRepository_ShedsWhenFull(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemoryRepository()
+	walletID := uuid.New()
+	_, err := memory.CreateWallet(ctx, walletID, newMemoryOwner(t, memory))
+	require.NoError(t, err)
+
+	inner := &blockingRepository{Repository: memory, entered: make(chan struct{}), release: make(chan struct{})}
+	limited := NewLimitingRepository(inner, 1, 0, 10*time.Millisecond)
+
+	done := make(chan error)
+	go func() {
+		_, err := limited.GetWallet(ctx, walletID)
+		done <- err
+	}()
+	<-inner.entered
+
+	_, err = limited.GetBalance(ctx, walletID)
+	assert.ErrorIs(t, err, ErrOverloaded)
+
+	close(inner.release)
+	require.NoError(t, <-done)
+
+	// Слот освобождён: следующая операция проходит.
+	balance, err := limited.GetBalance(ctx, walletID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), balance)
+}
+
+func TestLimitingRepository_WaitsForSlot(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemoryRepository()
+	walletID := uuid.New()
+	_, err := memory.CreateWallet(ctx, walletID, newMemoryOwner(t, memory))
+	require.NoError(t, err)
+
+	inner := &blockingRepository{Repository: memory, entered: make(chan struct{}), release: make(chan struct{})}
+	limited := NewLimitingRepository(inner, 1, 0, time.Second)
+
+	go limited.GetWallet(ctx, walletID)
+	<-inner.entered
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(inner.release)
+	}()
+
+	_, err = limited.UpdateBalance(ctx, walletID, 10)
+	assert.NoError(t, err)
+}
+
+func TestLimitingRepository_ApplyBatch(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemoryRepository()
+	walletID := uuid.New()
+	_, err := memory.CreateWallet(ctx, walletID, newMemoryOwner(t, memory))
+	require.NoError(t, err)
+
+	limited := NewLimitingRepository(memory, 2, 0, time.Millisecond)
+	results, err := limited.ApplyBatch(ctx, walletID, []int64{50, -20})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	balance, err := limited.GetBalance(ctx, walletID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(30), balance)
+
+	_, err = NewLimitingRepository(&blockingRepository{Repository: memory}, 1, 0, 0).ApplyBatch(ctx, walletID, []int64{1})
+	assert.Error(t, err)
+}
+
+func TestLimitingRepository_StatementsHaveOwnSlots(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemoryRepository()
+	walletID := uuid.New()
+	_, err := memory.CreateWallet(ctx, walletID, newMemoryOwner(t, memory))
+	require.NoError(t, err)
+
+	inner := &blockingRepository{Repository: memory, entered: make(chan struct{}), release: make(chan struct{})}
+	limited := NewLimitingRepository(inner, 1, 1, 10*time.Millisecond)
+	var sink discardSink
+	from, to := time.Now().Add(-time.Hour), time.Now()
+
+	done := make(chan error)
+	go func() {
+		done <- limited.StreamStatement(ctx, walletID, from, to, sink)
+	}()
+	<-inner.entered
+
+	// Загрузка выписки не занимает слот операции.
+	_, err = limited.UpdateBalance(ctx, walletID, 10)
+	assert.NoError(t, err)
+
+	err = limited.StreamStatement(ctx, walletID, from, to, sink)
+	assert.ErrorIs(t, err, ErrOverloaded)
+
+	close(inner.release)
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,647 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+)
+
+type memoryWallet struct {
+	mu         sync.Mutex
+	wallet     models.Wallet
+	maxBalance int64
+	operations []models.Operation
+	// labels и metadata живут отдельно от wallet, чтобы, как и в
+	// PostgresRepository, попадать только в административные ответы.
+	labels   map[string]string
+	metadata json.RawMessage
+}
+
+// MemoryRepository хранит кошельки в памяти процесса. Каждый кошелёк
+// защищён собственным мьютексом, поэтому операции над разными кошельками
+// выполняются параллельно, а над одним — последовательно, как с
+// SELECT FOR UPDATE в PostgresRepository.
+type MemoryRepository struct {
+	mu           sync.RWMutex
+	owners       map[uuid.UUID]models.Owner
+	wallets      map[uuid.UUID]*memoryWallet
+	operationSeq atomic.Int64
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		owners:  make(map[uuid.UUID]models.Owner),
+		wallets: make(map[uuid.UUID]*memoryWallet),
+	}
+}
+
+func (r *MemoryRepository) CreateOwner(ctx context.Context, ownerID uuid.UUID, name string) (*models.Owner, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.owners[ownerID]; ok {
+		return nil, ErrOwnerExists
+	}
+	owner := models.Owner{ID: ownerID, Name: name, CreatedAt: time.Now().UTC()}
+	r.owners[ownerID] = owner
+	return &owner, nil
+}
+
+func (r *MemoryRepository) CreateWallet(ctx context.Context, walletID, ownerID uuid.UUID) (*models.Wallet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.wallets[walletID]; ok {
+		return nil, ErrWalletExists
+	}
+	if _, ok := r.owners[ownerID]; !ok {
+		return nil, models.ErrOwnerNotFound
+	}
+
+	w := &memoryWallet{
+		wallet: models.Wallet{
+			ID:        walletID,
+			Status:    models.StatusActive,
+			Version:   1,
+			Currency:  models.DefaultCurrency,
+			OwnerID:   &ownerID,
+			CreatedAt: time.Now().UTC(),
+		},
+		maxBalance: models.MaxBalance,
+	}
+	r.wallets[walletID] = w
+
+	wallet := w.wallet
+	return &wallet, nil
+}
+
+func (r *MemoryRepository) GetWallet(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error) {
+	w, err := r.lookup(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	wallet := w.wallet
+	return &wallet, nil
+}
+
+func (r *MemoryRepository) GetBalance(ctx context.Context, walletID uuid.UUID) (int64, error) {
+	wallet, err := r.GetWallet(ctx, walletID)
+	if err != nil {
+		return 0, err
+	}
+	return wallet.Balance, nil
+}
+
+func (r *MemoryRepository) UpdateBalance(ctx context.Context, walletID uuid.UUID, amount int64) (int64, error) {
+	w, err := r.lookup(ctx, walletID)
+	if err != nil {
+		return 0, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if w.wallet.Status == models.StatusFrozen {
+		return 0, models.ErrWalletFrozen
+	}
+	if _, err := models.ApplyAmount(w.wallet.Balance, amount, w.maxBalance); err != nil {
+		return 0, err
+	}
+
+	operationType := models.Deposit
+	if amount < 0 {
+		operationType = models.Withdraw
+	}
+	r.apply(w, operationType, amount, nil)
+	return w.wallet.Balance, nil
+}
+
+func (r *MemoryRepository) UpdateBalanceIfVersion(ctx context.Context, walletID uuid.UUID, amount, version int64) (*models.Wallet, error) {
+	w, err := r.lookup(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if w.wallet.Version != version {
+		return nil, models.ErrVersionMismatch
+	}
+	if w.wallet.Status == models.StatusFrozen {
+		return nil, models.ErrWalletFrozen
+	}
+	if _, err := models.ApplyAmount(w.wallet.Balance, amount, w.maxBalance); err != nil {
+		return nil, err
+	}
+
+	operationType := models.Deposit
+	if amount < 0 {
+		operationType = models.Withdraw
+	}
+	r.apply(w, operationType, amount, nil)
+
+	wallet := w.wallet
+	return &wallet, nil
+}
+
+func (r *MemoryRepository) ApplyBatch(ctx context.Context, walletID uuid.UUID, amounts []int64) ([]BatchResult, error) {
+	w, err := r.lookup(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if w.wallet.Status == models.StatusFrozen {
+		return nil, models.ErrWalletFrozen
+	}
+
+	results, accepted, _ := planBatch(w.wallet.Balance, w.maxBalance, amounts)
+	for _, amount := range accepted {
+		operationType := models.Deposit
+		if amount < 0 {
+			operationType = models.Withdraw
+		}
+		r.apply(w, operationType, amount, nil)
+	}
+	return results, nil
+}
+
+func (r *MemoryRepository) Transfer(ctx context.Context, fromID, toID uuid.UUID, amount int64) (uuid.UUID, error) {
+	// Иначе first и second ниже — один и тот же мьютекс.
+	if fromID == toID {
+		return uuid.Nil, models.ErrSameWallet
+	}
+	from, err := r.lookup(ctx, fromID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	to, err := r.lookup(ctx, toID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	first, second := from, to
+	if bytes.Compare(fromID[:], toID[:]) > 0 {
+		first, second = to, from
+	}
+	first.mu.Lock()
+	defer first.mu.Unlock()
+	second.mu.Lock()
+	defer second.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return uuid.Nil, err
+	}
+	if from.wallet.Status == models.StatusFrozen || to.wallet.Status == models.StatusFrozen {
+		return uuid.Nil, models.ErrWalletFrozen
+	}
+	if _, err := models.ApplyAmount(from.wallet.Balance, -amount, from.maxBalance); err != nil {
+		return uuid.Nil, err
+	}
+	if _, err := models.ApplyAmount(to.wallet.Balance, amount, to.maxBalance); err != nil {
+		return uuid.Nil, err
+	}
+
+	transferID := uuid.New()
+	r.apply(from, models.TransferOut, -amount, &transferID)
+	r.apply(to, models.TransferIn, amount, &transferID)
+	return transferID, nil
+}
+
+func (r *MemoryRepository) SetStatus(ctx context.Context, walletID uuid.UUID, status models.WalletStatus) error {
+	w, err := r.lookup(ctx, walletID)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.wallet.Status != status {
+		w.wallet.Status = status
+		w.wallet.Version++
+	}
+	return nil
+}
+
+// SetMaxBalance — аналог PostgresRepository.SetMaxBalance.
+func (r *MemoryRepository) SetMaxBalance(ctx context.Context, walletID uuid.UUID, limit int64) error {
+	if limit < 0 || limit > models.MaxBalance {
+		return models.ErrInvalidBalanceLimit
+	}
+
+	w, err := r.lookup(ctx, walletID)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.wallet.Balance > limit {
+		return models.ErrBalanceLimitExceeded
+	}
+	w.maxBalance = limit
+	return nil
+}
+
+// SetCurrency — аналог PostgresRepository.SetCurrency.
+func (r *MemoryRepository) SetCurrency(ctx context.Context, walletID uuid.UUID, currency models.Currency) error {
+	if !currency.Valid() {
+		return models.ErrInvalidCurrency
+	}
+
+	w, err := r.lookup(ctx, walletID)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.wallet.Currency == currency {
+		return nil
+	}
+	if w.wallet.Balance != 0 {
+		return models.ErrWalletNotEmpty
+	}
+	w.wallet.Currency = currency
+	w.wallet.Version++
+	return nil
+}
+
+func (r *MemoryRepository) History(ctx context.Context, walletID uuid.UUID, limit int) ([]models.Operation, error) {
+	w, err := r.lookup(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	operations := make([]models.Operation, 0, limit)
+	for i := len(w.operations) - 1; i >= 0 && len(operations) < limit; i-- {
+		operations = append(operations, w.operations[i])
+	}
+	return operations, nil
+}
+
+// ListOwnerWallets возвращает кошельки владельца в порядке создания.
+func (r *MemoryRepository) ListOwnerWallets(ctx context.Context, ownerID uuid.UUID) ([]models.Wallet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Владелец кошелька не меняется, поэтому его можно сравнить без
+	// блокировки кошелька, а копии снять уже после r.mu.
+	r.mu.RLock()
+	_, ok := r.owners[ownerID]
+	var owned []*memoryWallet
+	for _, w := range r.wallets {
+		if w.wallet.OwnerID != nil && *w.wallet.OwnerID == ownerID {
+			owned = append(owned, w)
+		}
+	}
+	r.mu.RUnlock()
+	if !ok {
+		return nil, models.ErrOwnerNotFound
+	}
+
+	wallets := make([]models.Wallet, 0, len(owned))
+	for _, w := range owned {
+		w.mu.Lock()
+		wallets = append(wallets, w.wallet)
+		w.mu.Unlock()
+	}
+	sort.Slice(wallets, func(i, j int) bool {
+		if !wallets[i].CreatedAt.Equal(wallets[j].CreatedAt) {
+			return wallets[i].CreatedAt.Before(wallets[j].CreatedAt)
+		}
+		return bytes.Compare(wallets[i].ID[:], wallets[j].ID[:]) < 0
+	})
+	return wallets, nil
+}
+
+// BalanceAsOf суммирует операции кошелька, созданные не позже asOf.
+// Контрольные точки в памяти не нужны: журнал короткий.
+func (r *MemoryRepository) BalanceAsOf(ctx context.Context, walletID uuid.UUID, asOf time.Time) (int64, error) {
+	w, err := r.lookup(ctx, walletID)
+	if err != nil {
+		return 0, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var balance int64
+	for _, op := range w.operations {
+		if !op.CreatedAt.After(asOf) {
+			balance += op.Amount
+		}
+	}
+	return balance, nil
+}
+
+// StreamStatement копирует операции периода под блокировкой кошелька и
+// передаёт их в sink уже без неё.
+func (r *MemoryRepository) StreamStatement(ctx context.Context, walletID uuid.UUID, from, to time.Time, sink StatementSink) error {
+	w, err := r.lookup(ctx, walletID)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	wallet := w.wallet
+	var (
+		opening    int64
+		operations []models.Operation
+	)
+	for _, op := range w.operations {
+		switch {
+		case !op.CreatedAt.After(from):
+			opening += op.Amount
+		case !op.CreatedAt.After(to):
+			operations = append(operations, op)
+		}
+	}
+	w.mu.Unlock()
+
+	if err := sink.Opening(&wallet, opening); err != nil {
+		return err
+	}
+	for i := range operations {
+		if err := sink.Operation(&operations[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetWalletMetadata — аналог PostgresRepository.SetWalletMetadata.
+func (r *MemoryRepository) SetWalletMetadata(ctx context.Context, walletID uuid.UUID, labels map[string]string, metadata json.RawMessage) (*models.Wallet, error) {
+	labelsArg, metadataArg, err := metadataArgs(labels, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := r.lookup(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.labels = nil
+	if err := json.Unmarshal([]byte(labelsArg), &w.labels); err != nil {
+		return nil, err
+	}
+	w.metadata = json.RawMessage(metadataArg)
+	wallet := w.adminWallet()
+	return &wallet, nil
+}
+
+// SearchWallets — аналог PostgresRepository.SearchWallets.
+func (r *MemoryRepository) SearchWallets(ctx context.Context, filter models.WalletFilter) (*models.WalletPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	all := make([]*memoryWallet, 0, len(r.wallets))
+	for _, w := range r.wallets {
+		all = append(all, w)
+	}
+	r.mu.RUnlock()
+
+	var wallets []models.Wallet
+	for _, w := range all {
+		w.mu.Lock()
+		wallet := w.adminWallet()
+		w.mu.Unlock()
+		if filter.Matches(&wallet) && (filter.After == nil || walletAfter(&wallet, filter.After)) {
+			wallets = append(wallets, wallet)
+		}
+	}
+	sort.Slice(wallets, func(i, j int) bool {
+		return walletAfter(&wallets[j], &models.WalletCursor{CreatedAt: wallets[i].CreatedAt, ID: wallets[i].ID})
+	})
+	if len(wallets) > filter.Limit+1 {
+		wallets = wallets[:filter.Limit+1]
+	}
+	if wallets == nil {
+		wallets = []models.Wallet{}
+	}
+	return newWalletPage(wallets, filter.Limit), nil
+}
+
+// ImportWallets — аналог PostgresRepository.ImportWallets. Хранилище
+// заблокировано на время импорта целиком.
+func (r *MemoryRepository) ImportWallets(ctx context.Context, source WalletSource, dryRun bool) (*models.ImportReport, error) {
+	report := models.NewImportReport(dryRun)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var wallets []*models.ImportedWallet
+	lines := make(map[uuid.UUID]int64)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		wallet, err := nextImport(source, report)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := lines[wallet.ID]; ok {
+			report.AddError(models.NewImportError(wallet.Line, &wallet.ID, models.ErrDuplicateWallet))
+		} else if _, ok := r.wallets[wallet.ID]; ok {
+			report.AddError(models.NewImportError(wallet.Line, &wallet.ID, models.ErrWalletExists))
+		}
+		lines[wallet.ID] = wallet.Line
+		wallets = append(wallets, wallet)
+	}
+	if report.ErrorCount > 0 {
+		report.Reconcile(models.CurrencyTotals{})
+		return report, nil
+	}
+
+	loaded := make(models.CurrencyTotals)
+	owners := make(map[uuid.UUID]bool)
+	for _, wallet := range wallets {
+		loaded.Add(wallet.Currency, 1, big.NewInt(wallet.Balance))
+		if _, ok := r.owners[wallet.OwnerID]; !ok {
+			owners[wallet.OwnerID] = true
+		}
+	}
+	report.OwnersCreated = int64(len(owners))
+	report.Wallets = int64(len(wallets))
+	report.Reconcile(loaded)
+	if dryRun {
+		return report, nil
+	}
+
+	now := time.Now().UTC()
+	for ownerID := range owners {
+		r.owners[ownerID] = models.Owner{ID: ownerID, CreatedAt: now}
+	}
+	for _, wallet := range wallets {
+		labels, metadata, err := metadataArgs(wallet.Labels, wallet.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		ownerID := wallet.OwnerID
+		w := &memoryWallet{
+			wallet: models.Wallet{
+				ID:        wallet.ID,
+				Balance:   wallet.Balance,
+				Status:    wallet.Status,
+				Version:   1,
+				Currency:  wallet.Currency,
+				OwnerID:   &ownerID,
+				CreatedAt: now,
+			},
+			maxBalance: models.MaxBalance,
+			metadata:   json.RawMessage(metadata),
+		}
+		if wallet.MaxBalance != nil {
+			w.maxBalance = *wallet.MaxBalance
+		}
+		if err := json.Unmarshal([]byte(labels), &w.labels); err != nil {
+			return nil, err
+		}
+		if wallet.Balance != 0 {
+			w.operations = append(w.operations, models.Operation{
+				ID:        r.operationSeq.Add(1),
+				WalletID:  wallet.ID,
+				Type:      models.Opening,
+				Amount:    wallet.Balance,
+				CreatedAt: now,
+			})
+		}
+		r.wallets[wallet.ID] = w
+	}
+	report.Committed = true
+	return report, nil
+}
+
+// ExportWallets — аналог PostgresRepository.ExportWallets. Кошельки
+// копируются под блокировкой, а передаются в sink после неё.
+func (r *MemoryRepository) ExportWallets(ctx context.Context, sink WalletSink) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	wallets := make([]models.Wallet, 0, len(r.wallets))
+	for _, w := range r.wallets {
+		w.mu.Lock()
+		wallet := w.adminWallet()
+		maxBalance := w.maxBalance
+		wallet.MaxBalance = &maxBalance
+		wallets = append(wallets, wallet)
+		w.mu.Unlock()
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(wallets, func(i, j int) bool {
+		return walletAfter(&wallets[j], &models.WalletCursor{CreatedAt: wallets[i].CreatedAt, ID: wallets[i].ID})
+	})
+	for i := range wallets {
+		if err := sink.Write(&wallets[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// adminWallet возвращает копию кошелька с метками и метаданными.
+// Вызывается под w.mu.
+func (w *memoryWallet) adminWallet() models.Wallet {
+	wallet := w.wallet
+	wallet.Labels = make(map[string]string, len(w.labels))
+	for key, value := range w.labels {
+		wallet.Labels[key] = value
+	}
+	wallet.Metadata = w.metadata
+	if wallet.Metadata == nil {
+		wallet.Metadata = json.RawMessage("{}")
+	}
+	return wallet
+}
+
+// walletAfter сообщает, идёт ли кошелёк после курсора в порядке
+// (created_at, id), как ORDER BY в PostgresRepository.SearchWallets.
+func walletAfter(wallet *models.Wallet, cursor *models.WalletCursor) bool {
+	if !wallet.CreatedAt.Equal(cursor.CreatedAt) {
+		return wallet.CreatedAt.After(cursor.CreatedAt)
+	}
+	return bytes.Compare(wallet.ID[:], cursor.ID[:]) > 0
+}
+
+func (r *MemoryRepository) lookup(ctx context.Context, walletID uuid.UUID) (*memoryWallet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w, ok := r.wallets[walletID]
+	if !ok {
+		return nil, ErrWalletNotFound
+	}
+	return w, nil
+}
+
+// apply изменяет баланс, увеличивает версию и пишет операцию в журнал.
+// Вызывается под w.mu.
+func (r *MemoryRepository) apply(w *memoryWallet, operationType models.OperationType, amount int64, transferID *uuid.UUID) {
+	w.wallet.Balance += amount
+	w.wallet.Version++
+	w.operations = append(w.operations, models.Operation{
+		ID:         r.operationSeq.Add(1),
+		WalletID:   w.wallet.ID,
+		Type:       operationType,
+		Amount:     amount,
+		TransferID: transferID,
+		CreatedAt:  time.Now().UTC(),
+	})
+}
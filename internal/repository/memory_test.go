@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMemoryOwner(t *testing.T, repo *MemoryRepository) uuid.UUID {
+	t.Helper()
+	ownerID := uuid.New()
+	_, err := repo.CreateOwner(context.Background(), ownerID, "")
+	require.NoError(t, err)
+	return ownerID
+}
+
+func newMemoryWallet(t *testing.T, repo *MemoryRepository, balance int64) uuid.UUID {
+	t.Helper()
+	walletID := uuid.New()
+	_, err := repo.CreateWallet(context.Background(), walletID, newMemoryOwner(t, repo))
+	require.NoError(t, err)
+	if balance > 0 {
+		_, err := repo.UpdateBalance(context.Background(), walletID, balance)
+		require.NoError(t, err)
+	}
+	return walletID
+}
+
+func TestMemoryRepository_CreateWallet_Duplicate(t *testing.T) {
+	repo := NewMemoryRepository()
+	walletID := newMemoryWallet(t, repo, 0)
+
+	_, err := repo.CreateWallet(context.Background(), walletID, newMemoryOwner(t, repo))
+
+	assert.Equal(t, ErrWalletExists, err)
+}
+
+func TestMemoryRepository_WalletNotFound(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	_, err := repo.GetBalance(ctx, uuid.New())
+	assert.Equal(t, ErrWalletNotFound, err)
+
+	_, err = repo.UpdateBalance(ctx, uuid.New(), 100)
+	assert.Equal(t, ErrWalletNotFound, err)
+
+	_, err = repo.History(ctx, uuid.New(), 10)
+	assert.Equal(t, ErrWalletNotFound, err)
+}
+
+func TestMemoryRepository_UpdateBalance_InsufficientFunds(t *testing.T) {
+	repo := NewMemoryRepository()
+	walletID := newMemoryWallet(t, repo, 500)
+
+	_, err := repo.UpdateBalance(context.Background(), walletID, -1000)
+
+	assert.Equal(t, models.ErrInsufficientFunds, err)
+	balance, _ := repo.GetBalance(context.Background(), walletID)
+	assert.Equal(t, int64(500), balance)
+}
+
+func TestMemoryRepository_UpdateBalance_Frozen(t *testing.T) {
+	repo := NewMemoryRepository()
+	walletID := newMemoryWallet(t, repo, 500)
+
+	require.NoError(t, repo.SetStatus(context.Background(), walletID, models.StatusFrozen))
+	_, err := repo.UpdateBalance(context.Background(), walletID, 100)
+
+	assert.Equal(t, models.ErrWalletFrozen, err)
+}
+
+func TestMemoryRepository_UpdateBalance_CanceledContext(t *testing.T) {
+	repo := NewMemoryRepository()
+	walletID := newMemoryWallet(t, repo, 500)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := repo.UpdateBalance(ctx, walletID, 100)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	balance, _ := repo.GetBalance(context.Background(), walletID)
+	assert.Equal(t, int64(500), balance)
+}
+
+func TestMemoryRepository_UpdateBalance_ConcurrentMixed(t *testing.T) {
+	repo := NewMemoryRepository()
+	walletID := newMemoryWallet(t, repo, 1000)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := repo.UpdateBalance(context.Background(), walletID, 20)
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := repo.UpdateBalance(context.Background(), walletID, -10)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	balance, _ := repo.GetBalance(context.Background(), walletID)
+	assert.Equal(t, int64(2000), balance)
+}
+
+func TestMemoryRepository_SetMaxBalance(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+	walletID := newMemoryWallet(t, repo, 100)
+
+	assert.ErrorIs(t, repo.SetMaxBalance(ctx, walletID, 99), models.ErrBalanceLimitExceeded)
+	assert.ErrorIs(t, repo.SetMaxBalance(ctx, walletID, models.MaxBalance+1), models.ErrInvalidBalanceLimit)
+	require.NoError(t, repo.SetMaxBalance(ctx, walletID, 150))
+
+	_, err := repo.UpdateBalance(ctx, walletID, 51)
+	assert.ErrorIs(t, err, models.ErrBalanceLimitExceeded)
+
+	balance, err := repo.UpdateBalance(ctx, walletID, 50)
+	require.NoError(t, err)
+	assert.Equal(t, int64(150), balance)
+}
+
+func TestMemoryRepository_SetCurrency(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+	emptyID := newMemoryWallet(t, repo, 0)
+	fundedID := newMemoryWallet(t, repo, 100)
+
+	assert.ErrorIs(t, repo.SetCurrency(ctx, emptyID, "XXX"), models.ErrInvalidCurrency)
+	assert.ErrorIs(t, repo.SetCurrency(ctx, fundedID, "USD"), models.ErrWalletNotEmpty)
+	require.NoError(t, repo.SetCurrency(ctx, fundedID, models.DefaultCurrency))
+	require.NoError(t, repo.SetCurrency(ctx, emptyID, "USD"))
+
+	wallet, err := repo.GetWallet(ctx, emptyID)
+	require.NoError(t, err)
+	assert.Equal(t, models.Currency("USD"), wallet.Currency)
+	assert.Equal(t, int64(2), wallet.Version)
+}
+
+func TestMemoryRepository_Transfer(t *testing.T) {
+	repo := NewMemoryRepository()
+	fromID := newMemoryWallet(t, repo, 1000)
+	toID := newMemoryWallet(t, repo, 0)
+
+	transferID, err := repo.Transfer(context.Background(), fromID, toID, 400)
+	require.NoError(t, err)
+
+	fromBalance, _ := repo.GetBalance(context.Background(), fromID)
+	toBalance, _ := repo.GetBalance(context.Background(), toID)
+	assert.Equal(t, int64(600), fromBalance)
+	assert.Equal(t, int64(400), toBalance)
+
+	history, err := repo.History(context.Background(), toID, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, models.TransferIn, history[0].Type)
+	assert.Equal(t, &transferID, history[0].TransferID)
+}
+
+func TestMemoryRepository_History_NewestFirst(t *testing.T) {
+	repo := NewMemoryRepository()
+	walletID := newMemoryWallet(t, repo, 500)
+	_, err := repo.UpdateBalance(context.Background(), walletID, -200)
+	require.NoError(t, err)
+	_, err = repo.UpdateBalance(context.Background(), walletID, 50)
+	require.NoError(t, err)
+
+	history, err := repo.History(context.Background(), walletID, 2)
+
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, int64(50), history[0].Amount)
+	assert.Equal(t, models.Withdraw, history[1].Type)
+	assert.Greater(t, history[0].ID, history[1].ID)
+}
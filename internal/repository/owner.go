@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+)
+
+const (
+	codeForeignKeyViolation = "23503"
+	walletOwnerConstraint   = "wallets_owner_id_fkey"
+)
+
+var ErrOwnerExists = errors.New("owner already exists")
+
+// ownerWalletsQuery — кошельки владельца в порядке создания.
+const ownerWalletsQuery = "SELECT w.id, " + balanceExpr + ", w.status, " + versionExpr + ", w.currency, w.owner_id, w.created_at " +
+	"FROM wallets w WHERE w.owner_id = $1 ORDER BY w.created_at, w.id"
+
+func (r *PostgresRepository) CreateOwner(ctx context.Context, ownerID uuid.UUID, name string) (*models.Owner, error) {
+	owner := models.Owner{ID: ownerID, Name: name}
+	err := r.db.QueryRowContext(
+		ctx,
+		"INSERT INTO owners (id, name) VALUES ($1, $2) ON CONFLICT (id) DO NOTHING RETURNING created_at",
+		ownerID,
+		name,
+	).Scan(&owner.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOwnerExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &owner, nil
+}
+
+// ListOwnerWallets возвращает кошельки владельца с полными балансами.
+func (r *PostgresRepository) ListOwnerWallets(ctx context.Context, ownerID uuid.UUID) ([]models.Wallet, error) {
+	var wallets []models.Wallet
+	err := r.read(ctx, func(db *sql.DB) error {
+		var exists bool
+		err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM owners WHERE id = $1)", ownerID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return models.ErrOwnerNotFound
+		}
+
+		rows, err := db.QueryContext(ctx, ownerWalletsQuery, ownerID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		wallets = make([]models.Wallet, 0)
+		for rows.Next() {
+			var wallet models.Wallet
+			err := rows.Scan(&wallet.ID, &wallet.Balance, &wallet.Status, &wallet.Version, &wallet.Currency, &wallet.OwnerID, &wallet.CreatedAt)
+			if err != nil {
+				return err
+			}
+			wallets = append(wallets, wallet)
+		}
+		return rows.Err()
+	})
+	return wallets, err
+}
+
+// ownerError переводит нарушение внешнего ключа на владельца при создании
+// кошелька в ErrOwnerNotFound.
+func ownerError(err error) error {
+	var (
+		pqErr      *pq.Error
+		pgxErr     *pgconn.PgError
+		code       string
+		constraint string
+	)
+	switch {
+	case errors.As(err, &pqErr):
+		code, constraint = string(pqErr.Code), pqErr.Constraint
+	case errors.As(err, &pgxErr):
+		code, constraint = pgxErr.Code, pgxErr.ConstraintName
+	default:
+		return err
+	}
+
+	if code == codeForeignKeyViolation && constraint == walletOwnerConstraint {
+		return fmt.Errorf("%w: %w", models.ErrOwnerNotFound, err)
+	}
+	return err
+}
@@ -0,0 +1,631 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Имена запросов, которые PreparePgxStatements готовит на каждом соединении
+// пула. pgx выполняет подготовленный запрос, если вместо SQL передано его
+// имя.
+const (
+	stmtCreateOwner     = "create_owner"
+	stmtOwnerExists     = "owner_exists"
+	stmtOwnerWallets    = "owner_wallets"
+	stmtCreateWallet    = "create_wallet"
+	stmtGetWallet       = "get_wallet"
+	stmtGetBalance      = "get_balance"
+	stmtLockWallet      = "lock_wallet"
+	stmtShardTotals     = "shard_totals"
+	stmtAddBalance      = "add_balance"
+	stmtClearBase       = "clear_base_balance"
+	stmtRebalanceShards = "rebalance_shards"
+	stmtInsertOperation = "insert_operation"
+	stmtInsertBatch     = "insert_operations"
+	stmtSetStatus       = "set_status"
+	stmtWalletExists    = "wallet_exists"
+	stmtHistory         = "history"
+	stmtBalanceAsOf     = "balance_as_of"
+	stmtStatementOps    = "statement_operations"
+	stmtSetTimeouts     = "set_timeouts"
+)
+
+var pgxStatements = map[string]string{
+	stmtCreateOwner:  "INSERT INTO owners (id, name) VALUES ($1, $2) ON CONFLICT (id) DO NOTHING RETURNING created_at",
+	stmtOwnerExists:  "SELECT EXISTS (SELECT 1 FROM owners WHERE id = $1)",
+	stmtOwnerWallets: ownerWalletsQuery,
+	stmtCreateWallet: "INSERT INTO wallets (id, owner_id) VALUES ($1, $2) ON CONFLICT (id) DO NOTHING RETURNING balance, status, version, currency, owner_id, created_at",
+	stmtGetWallet:    "SELECT " + balanceExpr + ", w.status, " + versionExpr + ", w.currency, w.owner_id, w.created_at FROM wallets w WHERE w.id = $1",
+	stmtGetBalance:   "SELECT " + balanceExpr + " FROM wallets w WHERE w.id = $1",
+	stmtLockWallet:   "SELECT balance, max_balance, status, shard_count, version, currency, owner_id, created_at FROM wallets WHERE id = $1 FOR UPDATE",
+	stmtShardTotals:  "SELECT COALESCE(SUM(balance), 0)::BIGINT, COALESCE(SUM(version), 0)::BIGINT FROM wallet_shards WHERE wallet_id = $1",
+	stmtAddBalance:   "UPDATE wallets SET balance = balance + $1, version = version + $2 WHERE id = $3",
+	stmtClearBase:    "UPDATE wallets SET balance = 0, version = version + $2 WHERE id = $1",
+	stmtRebalanceShards: `UPDATE wallet_shards
+		SET balance = $2::BIGINT / $3 + CASE WHEN shard_no < $2::BIGINT % $3 THEN 1 ELSE 0 END
+		WHERE wallet_id = $1`,
+	stmtInsertOperation: "INSERT INTO wallet_operations (wallet_id, operation_type, amount, transfer_id) VALUES ($1, $2, $3, $4)",
+	stmtInsertBatch: `INSERT INTO wallet_operations (wallet_id, operation_type, amount)
+		SELECT $1, CASE WHEN a.amount < 0 THEN $3 ELSE $4 END, a.amount
+		FROM unnest($2::BIGINT[]) WITH ORDINALITY AS a (amount, n)
+		ORDER BY a.n`,
+	stmtSetStatus:    "UPDATE wallets SET status = $1, version = version + CASE WHEN status = $1 THEN 0 ELSE 1 END WHERE id = $2",
+	stmtWalletExists: "SELECT EXISTS (SELECT 1 FROM wallets WHERE id = $1)",
+	stmtHistory: `SELECT id, wallet_id, operation_type, amount, transfer_id, created_at
+		FROM wallet_operations WHERE wallet_id = $1 ORDER BY id DESC LIMIT $2`,
+	stmtBalanceAsOf:  balanceAsOfQuery,
+	stmtStatementOps: statementOperationsQuery,
+	stmtSetTimeouts:  setTimeoutsQuery,
+}
+
+// PreparePgxStatements готовит запросы PgxRepository на новом соединении.
+// Передаётся в pgxpool.Config.AfterConnect.
+func PreparePgxStatements(ctx context.Context, conn *pgx.Conn) error {
+	for name, query := range pgxStatements {
+		if _, err := conn.Prepare(ctx, name, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var pgxIsolationLevels = map[sql.IsolationLevel]pgx.TxIsoLevel{
+	sql.LevelReadCommitted:  pgx.ReadCommitted,
+	sql.LevelRepeatableRead: pgx.RepeatableRead,
+	sql.LevelSerializable:   pgx.Serializable,
+}
+
+// PgxRepository — реализация Repository на pgxpool. Семантика совпадает с
+// PostgresRepository без опций: каждая операция выполняется под
+// эксклюзивной блокировкой кошелька, шардированные кошельки читаются и
+// изменяются корректно, но без быстрого пути.
+type PgxRepository struct {
+	pool        *pgxpool.Pool
+	isolation   pgx.TxIsoLevel
+	retryPolicy RetryPolicy
+	timeouts    Timeouts
+}
+
+type PgxOption func(*PgxRepository)
+
+// WithPgxIsolation — аналог WithIsolation для PgxRepository.
+func WithPgxIsolation(level sql.IsolationLevel) PgxOption {
+	return func(r *PgxRepository) {
+		r.isolation = pgxIsolationLevels[level]
+	}
+}
+
+// WithPgxRetryPolicy — аналог WithRetryPolicy для PgxRepository.
+func WithPgxRetryPolicy(policy RetryPolicy) PgxOption {
+	return func(r *PgxRepository) {
+		r.retryPolicy = policy
+	}
+}
+
+// NewPgxRepository ожидает пул, на соединениях которого выполнен
+// PreparePgxStatements.
+func NewPgxRepository(pool *pgxpool.Pool, opts ...PgxOption) *PgxRepository {
+	r := &PgxRepository{pool: pool, retryPolicy: DefaultRetryPolicy}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *PgxRepository) CreateOwner(ctx context.Context, ownerID uuid.UUID, name string) (*models.Owner, error) {
+	owner := models.Owner{ID: ownerID, Name: name}
+	err := r.pool.QueryRow(ctx, stmtCreateOwner, ownerID, name).Scan(&owner.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrOwnerExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &owner, nil
+}
+
+func (r *PgxRepository) CreateWallet(ctx context.Context, walletID, ownerID uuid.UUID) (*models.Wallet, error) {
+	wallet := models.Wallet{ID: walletID}
+	err := r.pool.QueryRow(ctx, stmtCreateWallet, walletID, ownerID).
+		Scan(&wallet.Balance, &wallet.Status, &wallet.Version, &wallet.Currency, &wallet.OwnerID, &wallet.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWalletExists
+	}
+	if err != nil {
+		return nil, ownerError(err)
+	}
+	return &wallet, nil
+}
+
+func (r *PgxRepository) GetWallet(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error) {
+	wallet := models.Wallet{ID: walletID}
+	err := r.pool.QueryRow(ctx, stmtGetWallet, walletID).
+		Scan(&wallet.Balance, &wallet.Status, &wallet.Version, &wallet.Currency, &wallet.OwnerID, &wallet.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWalletNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (r *PgxRepository) GetBalance(ctx context.Context, walletID uuid.UUID) (int64, error) {
+	var balance int64
+	err := r.pool.QueryRow(ctx, stmtGetBalance, walletID).Scan(&balance)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrWalletNotFound
+	}
+	return balance, err
+}
+
+func (r *PgxRepository) UpdateBalance(ctx context.Context, walletID uuid.UUID, amount int64) (int64, error) {
+	wallet, err := r.updateLocked(ctx, walletID, amount, 0)
+	if err != nil {
+		return 0, err
+	}
+	return wallet.balance, nil
+}
+
+func (r *PgxRepository) UpdateBalanceIfVersion(ctx context.Context, walletID uuid.UUID, amount, version int64) (*models.Wallet, error) {
+	wallet, err := r.updateLocked(ctx, walletID, amount, version)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.model(), nil
+}
+
+func (r *PgxRepository) updateLocked(ctx context.Context, walletID uuid.UUID, amount, version int64) (*lockedWallet, error) {
+	var wallet *lockedWallet
+	err := r.inTx(ctx, "update_balance", func(tx pgx.Tx) error {
+		var err error
+		wallet, err = pgxLockWallet(ctx, tx, walletID)
+		if err != nil {
+			return err
+		}
+
+		if version != 0 && wallet.version != version {
+			return models.ErrVersionMismatch
+		}
+		if wallet.status == models.StatusFrozen {
+			return models.ErrWalletFrozen
+		}
+		if _, err := models.ApplyAmount(wallet.balance, amount, wallet.maxBalance); err != nil {
+			return err
+		}
+
+		operationType := models.Deposit
+		if amount < 0 {
+			operationType = models.Withdraw
+		}
+		return pgxApplyOperation(ctx, tx, wallet, operationType, amount, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// ApplyBatch работает как PostgresRepository.ApplyBatch.
+func (r *PgxRepository) ApplyBatch(ctx context.Context, walletID uuid.UUID, amounts []int64) ([]BatchResult, error) {
+	var results []BatchResult
+	err := r.inTx(ctx, "apply_batch", func(tx pgx.Tx) error {
+		wallet, err := pgxLockActiveWallet(ctx, tx, walletID)
+		if err != nil {
+			return err
+		}
+
+		var (
+			accepted []int64
+			delta    int64
+		)
+		results, accepted, delta = planBatch(wallet.balance, wallet.maxBalance, amounts)
+		if len(accepted) == 0 {
+			return nil
+		}
+		if err := pgxAddToLockedBalance(ctx, tx, wallet, delta, int64(len(accepted))); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, stmtInsertBatch, walletID, accepted, models.Withdraw, models.Deposit)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (r *PgxRepository) Transfer(ctx context.Context, fromID, toID uuid.UUID, amount int64) (uuid.UUID, error) {
+	if fromID == toID {
+		return uuid.Nil, models.ErrSameWallet
+	}
+	first, second := fromID, toID
+	if bytes.Compare(first[:], second[:]) > 0 {
+		first, second = second, first
+	}
+
+	transferID := uuid.New()
+	err := r.inTx(ctx, "transfer", func(tx pgx.Tx) error {
+		wallets := make(map[uuid.UUID]*lockedWallet, 2)
+		for _, id := range []uuid.UUID{first, second} {
+			wallet, err := pgxLockActiveWallet(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			wallets[id] = wallet
+		}
+
+		from, to := wallets[fromID], wallets[toID]
+		if _, err := models.ApplyAmount(from.balance, -amount, from.maxBalance); err != nil {
+			return err
+		}
+		if _, err := models.ApplyAmount(to.balance, amount, to.maxBalance); err != nil {
+			return err
+		}
+
+		if err := pgxApplyOperation(ctx, tx, from, models.TransferOut, -amount, &transferID); err != nil {
+			return err
+		}
+		return pgxApplyOperation(ctx, tx, to, models.TransferIn, amount, &transferID)
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return transferID, nil
+}
+
+func (r *PgxRepository) SetStatus(ctx context.Context, walletID uuid.UUID, status models.WalletStatus) error {
+	tag, err := r.pool.Exec(ctx, stmtSetStatus, status, walletID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWalletNotFound
+	}
+	return nil
+}
+
+func (r *PgxRepository) BalanceAsOf(ctx context.Context, walletID uuid.UUID, asOf time.Time) (int64, error) {
+	var balance int64
+	err := r.pool.QueryRow(ctx, stmtBalanceAsOf, walletID, asOf).Scan(&balance)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrWalletNotFound
+	}
+	return balance, err
+}
+
+// StreamStatement — аналог PostgresRepository.StreamStatement на основной
+// базе.
+func (r *PgxRepository) StreamStatement(ctx context.Context, walletID uuid.UUID, from, to time.Time, sink StatementSink) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	wallet := models.Wallet{ID: walletID}
+	err = tx.QueryRow(ctx, stmtGetWallet, walletID).
+		Scan(&wallet.Balance, &wallet.Status, &wallet.Version, &wallet.Currency, &wallet.OwnerID, &wallet.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrWalletNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var opening int64
+	if err := tx.QueryRow(ctx, stmtBalanceAsOf, walletID, from).Scan(&opening); err != nil {
+		return err
+	}
+	if err := sink.Opening(&wallet, opening); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, stmtStatementOps, walletID, from, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		op, err := scanOperation(rows)
+		if err != nil {
+			return err
+		}
+		if err := sink.Operation(&op); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *PgxRepository) History(ctx context.Context, walletID uuid.UUID, limit int) ([]models.Operation, error) {
+	var exists bool
+	if err := r.pool.QueryRow(ctx, stmtWalletExists, walletID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrWalletNotFound
+	}
+
+	rows, err := r.pool.Query(ctx, stmtHistory, walletID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	operations := make([]models.Operation, 0)
+	for rows.Next() {
+		var (
+			op         models.Operation
+			transferID uuid.NullUUID
+		)
+		if err := rows.Scan(&op.ID, &op.WalletID, &op.Type, &op.Amount, &transferID, &op.CreatedAt); err != nil {
+			return nil, err
+		}
+		if transferID.Valid {
+			op.TransferID = &transferID.UUID
+		}
+		operations = append(operations, op)
+	}
+	return operations, rows.Err()
+}
+
+func (r *PgxRepository) ListOwnerWallets(ctx context.Context, ownerID uuid.UUID) ([]models.Wallet, error) {
+	var exists bool
+	if err := r.pool.QueryRow(ctx, stmtOwnerExists, ownerID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, models.ErrOwnerNotFound
+	}
+
+	rows, err := r.pool.Query(ctx, stmtOwnerWallets, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wallets := make([]models.Wallet, 0)
+	for rows.Next() {
+		var wallet models.Wallet
+		err := rows.Scan(&wallet.ID, &wallet.Balance, &wallet.Status, &wallet.Version, &wallet.Currency, &wallet.OwnerID, &wallet.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, wallet)
+	}
+	return wallets, rows.Err()
+}
+
+func (r *PgxRepository) SetWalletMetadata(ctx context.Context, walletID uuid.UUID, labels map[string]string, metadata json.RawMessage) (*models.Wallet, error) {
+	labelsArg, metadataArg, err := metadataArgs(labels, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := scanAdminWallet(r.pool.QueryRow(ctx, setMetadataQuery, walletID, labelsArg, metadataArg))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWalletNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (r *PgxRepository) SearchWallets(ctx context.Context, filter models.WalletFilter) (*models.WalletPage, error) {
+	query, args := searchWalletsQuery(filter)
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wallets := make([]models.Wallet, 0, filter.Limit+1)
+	for rows.Next() {
+		wallet, err := scanAdminWallet(rows)
+		if err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, wallet)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return newWalletPage(wallets, filter.Limit), nil
+}
+
+// ImportWallets — аналог PostgresRepository.ImportWallets.
+func (r *PgxRepository) ImportWallets(ctx context.Context, source WalletSource, dryRun bool) (*models.ImportReport, error) {
+	report := models.NewImportReport(dryRun)
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, createImportTableQuery); err != nil {
+		return nil, err
+	}
+	rows := &pgxImportRows{source: source, report: report}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"wallet_import"}, importColumns, rows); err != nil {
+		return nil, err
+	}
+	if err := applyImport(ctx, pgxImportTx{tx}, report); err != nil {
+		return nil, err
+	}
+
+	if report.ErrorCount > 0 || dryRun {
+		return report, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	report.Committed = true
+	return report, nil
+}
+
+// ExportWallets — аналог PostgresRepository.ExportWallets на основной
+// базе.
+func (r *PgxRepository) ExportWallets(ctx context.Context, sink WalletSink) error {
+	rows, err := r.pool.Query(ctx, exportWalletsQuery)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		wallet, err := scanExportWallet(rows)
+		if err != nil {
+			return err
+		}
+		if err := sink.Write(&wallet); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// pgxImportRows передаёт записи WalletSource в CopyFrom по одной.
+type pgxImportRows struct {
+	source WalletSource
+	report *models.ImportReport
+	values []any
+	err    error
+}
+
+func (s *pgxImportRows) Next() bool {
+	wallet, err := nextImport(s.source, s.report)
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			s.err = err
+		}
+		return false
+	}
+	if s.values, err = importValues(wallet); err != nil {
+		s.err = err
+		return false
+	}
+	return true
+}
+
+func (s *pgxImportRows) Values() ([]any, error) {
+	return s.values, nil
+}
+
+func (s *pgxImportRows) Err() error {
+	return s.err
+}
+
+type pgxImportTx struct {
+	tx pgx.Tx
+}
+
+func (t pgxImportTx) exec(ctx context.Context, query string) (int64, error) {
+	tag, err := t.tx.Exec(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (t pgxImportTx) query(ctx context.Context, query string, scan func(row rowScanner) error) error {
+	rows, err := t.tx.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// inTx — аналог PostgresRepository.inTx.
+func (r *PgxRepository) inTx(ctx context.Context, operation string, fn func(tx pgx.Tx) error) error {
+	err := r.retryPolicy.run(ctx, operation, func() error {
+		return pgx.BeginTxFunc(ctx, r.pool, pgx.TxOptions{IsoLevel: r.isolation}, func(tx pgx.Tx) error {
+			if r.timeouts.enabled() {
+				if _, err := tx.Exec(ctx, stmtSetTimeouts, r.timeouts.args()...); err != nil {
+					return err
+				}
+			}
+			return fn(tx)
+		})
+	})
+	return timeoutError(ctx, operation, limitError(err))
+}
+
+func pgxLockWallet(ctx context.Context, tx pgx.Tx, walletID uuid.UUID) (*lockedWallet, error) {
+	wallet := lockedWallet{id: walletID}
+	err := tx.QueryRow(ctx, stmtLockWallet, walletID).
+		Scan(&wallet.balance, &wallet.maxBalance, &wallet.status, &wallet.shards, &wallet.version, &wallet.currency, &wallet.ownerID, &wallet.createdAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWalletNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if wallet.shards > 0 {
+		var shardsBalance, shardsVersion int64
+		if err := tx.QueryRow(ctx, stmtShardTotals, walletID).Scan(&shardsBalance, &shardsVersion); err != nil {
+			return nil, err
+		}
+		wallet.balance += shardsBalance
+		wallet.version += shardsVersion
+	}
+	return &wallet, nil
+}
+
+func pgxLockActiveWallet(ctx context.Context, tx pgx.Tx, walletID uuid.UUID) (*lockedWallet, error) {
+	wallet, err := pgxLockWallet(ctx, tx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.status == models.StatusFrozen {
+		return nil, models.ErrWalletFrozen
+	}
+	return wallet, nil
+}
+
+func pgxApplyOperation(ctx context.Context, tx pgx.Tx, wallet *lockedWallet, operationType models.OperationType, amount int64, transferID *uuid.UUID) error {
+	if err := pgxAddToLockedBalance(ctx, tx, wallet, amount, 1); err != nil {
+		return err
+	}
+	var transfer uuid.NullUUID
+	if transferID != nil {
+		transfer = uuid.NullUUID{UUID: *transferID, Valid: true}
+	}
+	_, err := tx.Exec(ctx, stmtInsertOperation, wallet.id, operationType, amount, transfer)
+	return err
+}
+
+func pgxAddToLockedBalance(ctx context.Context, tx pgx.Tx, wallet *lockedWallet, delta, changes int64) error {
+	wallet.balance += delta
+	wallet.version += changes
+
+	if wallet.shards == 0 {
+		_, err := tx.Exec(ctx, stmtAddBalance, delta, changes, wallet.id)
+		return err
+	}
+	if _, err := tx.Exec(ctx, stmtClearBase, wallet.id, changes); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, stmtRebalanceShards, wallet.id, wallet.balance, wallet.shards)
+	return err
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/bulk"
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPgxRepository(t *testing.T) (*PgxRepository, *pgxpool.Pool) {
+	t.Helper()
+
+	config, err := pgxpool.ParseConfig(testDSN())
+	require.NoError(t, err)
+	config.AfterConnect = PreparePgxStatements
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	if err := pool.Ping(ctx); err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	return NewPgxRepository(pool), pool
+}
+
+func newPgxOwner(t *testing.T, repo *PgxRepository) uuid.UUID {
+	t.Helper()
+
+	ownerID := uuid.New()
+	_, err := repo.CreateOwner(context.Background(), ownerID, "test")
+	require.NoError(t, err)
+	return ownerID
+}
+
+func TestPgxRepository_StatementsPreparedPerConnection(t *testing.T) {
+	_, pool := newTestPgxRepository(t)
+
+	var prepared []string
+	err := pool.QueryRow(
+		context.Background(),
+		"SELECT array_agg(name ORDER BY name) FROM pg_prepared_statements WHERE NOT from_sql",
+	).Scan(&prepared)
+	require.NoError(t, err)
+
+	for name := range pgxStatements {
+		assert.Contains(t, prepared, name)
+	}
+}
+
+// TestPgxRepository_ImportWallets проверяет загрузку через CopyFrom: это
+// единственный путь массовой загрузки в pgx.
+func TestPgxRepository_ImportWallets(t *testing.T) {
+	repo, pool := newTestPgxRepository(t)
+	ctx := context.Background()
+
+	ownerID := newPgxOwner(t, repo)
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	t.Cleanup(func() {
+		pool.Exec(ctx, "DELETE FROM wallets WHERE id = ANY($1)", ids)
+	})
+	record := func(walletID uuid.UUID, fields string) string {
+		return `{"walletId": "` + walletID.String() + `", "ownerId": "` + ownerID.String() + `", ` + fields + "}\n"
+	}
+	importFile := func(file string) *models.ImportReport {
+		t.Helper()
+		reader, err := bulk.NewReader(strings.NewReader(file), models.BulkJSONL)
+		require.NoError(t, err)
+		report, err := repo.ImportWallets(ctx, reader, false)
+		require.NoError(t, err)
+		return report
+	}
+
+	report := importFile(record(ids[0], `"currency": "RUB", "balance": "15.00"`) +
+		record(ids[1], `"currency": "USD", "balance": "0", "status": "FROZEN", "maxBalance": "100", "labels": {"team": "payments"}`))
+	require.True(t, report.Committed)
+	assert.Equal(t, int64(2), report.Wallets)
+
+	first, err := repo.GetWallet(ctx, ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500), first.Balance)
+	assert.Equal(t, models.StatusActive, first.Status)
+	assert.Equal(t, int64(1), first.Version)
+
+	second, err := repo.GetWallet(ctx, ids[1])
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusFrozen, second.Status)
+	assert.Equal(t, models.Currency("USD"), second.Currency)
+
+	history, err := repo.History(ctx, ids[0], 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, models.Opening, history[0].Type)
+	assert.Equal(t, int64(1500), history[0].Amount)
+
+	// Уже существующий кошелёк отклоняет весь файл.
+	report = importFile(record(ids[2], `"currency": "RUB", "balance": "1"`) + record(ids[0], `"currency": "RUB", "balance": "1"`))
+	assert.False(t, report.Committed)
+	require.Len(t, report.Errors, 1)
+	assert.Equal(t, models.CodeWalletExists, report.Errors[0].Code)
+	_, err = repo.GetWallet(ctx, ids[2])
+	assert.ErrorIs(t, err, ErrWalletNotFound)
+}
+
+func TestPgxRepository_ShardedWallet(t *testing.T) {
+	repo, _ := newTestPgxRepository(t)
+	db, err := sql.Open("postgres", testDSN())
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	walletID := uuid.New()
+	_, err = db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 1000, newPgxOwner(t, repo))
+	require.NoError(t, err)
+	defer db.Exec("DELETE FROM wallets WHERE id = $1", walletID)
+	require.NoError(t, NewPostgresRepository(db).SetShardCount(ctx, walletID, 4))
+
+	balance, err := repo.UpdateBalance(ctx, walletID, -999)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), balance)
+
+	_, err = repo.UpdateBalance(ctx, walletID, -2)
+	assert.Equal(t, models.ErrInsufficientFunds, err)
+
+	var shardsTotal int64
+	require.NoError(t, db.QueryRow("SELECT SUM(balance) FROM wallet_shards WHERE wallet_id = $1", walletID).Scan(&shardsTotal))
+	assert.Equal(t, int64(1), shardsTotal)
+}
@@ -1,72 +1,426 @@
 package repository
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"errors"
+	"sync/atomic"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/DisasterWoman/wallet-service/internal/models"
-	
+	"github.com/lib/pq"
 )
 
 var (
-	ErrWalletNotFound = errors.New("wallet not found")
+	// ErrWalletNotFound и ErrWalletExists — псевдонимы ошибок models для
+	// кода, который сравнивает ошибки репозитория.
+	ErrWalletNotFound = models.ErrWalletNotFound
+	ErrWalletExists   = models.ErrWalletExists
+)
+
+// balanceExpr и versionExpr — полный баланс и версия кошелька w с учётом
+// шардов.
+const (
+	balanceExpr = "(w.balance + COALESCE((SELECT SUM(s.balance) FROM wallet_shards s WHERE s.wallet_id = w.id), 0))::BIGINT"
+	versionExpr = "(w.version + COALESCE((SELECT SUM(s.version) FROM wallet_shards s WHERE s.wallet_id = w.id), 0))::BIGINT"
 )
 
 type PostgresRepository struct {
-	db *sql.DB
+	db          *sql.DB
+	sharding    bool
+	conditional bool
+	isolation   sql.IsolationLevel
+	retryPolicy RetryPolicy
+	timeouts    Timeouts
+
+	replicas      []*replica
+	maxReplicaLag time.Duration
+	nextReplica   atomic.Uint32
 }
 
-func NewPostgresRepository(db *sql.DB) *PostgresRepository {
-	return &PostgresRepository{db: db}
+type Option func(*PostgresRepository)
+
+func NewPostgresRepository(db *sql.DB, opts ...Option) *PostgresRepository {
+	r := &PostgresRepository{db: db, retryPolicy: DefaultRetryPolicy}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
-func (r *PostgresRepository) GetBalance(ctx context.Context, walletID uuid.UUID) (int64, error) {
-	var balance int64
+func (r *PostgresRepository) CreateWallet(ctx context.Context, walletID, ownerID uuid.UUID) (*models.Wallet, error) {
+	wallet := models.Wallet{ID: walletID}
 	err := r.db.QueryRowContext(
 		ctx,
-		"SELECT balance FROM wallets WHERE id = $1",
+		"INSERT INTO wallets (id, owner_id) VALUES ($1, $2) ON CONFLICT (id) DO NOTHING RETURNING balance, status, version, currency, owner_id, created_at",
 		walletID,
-	).Scan(&balance)
+		ownerID,
+	).Scan(&wallet.Balance, &wallet.Status, &wallet.Version, &wallet.Currency, &wallet.OwnerID, &wallet.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWalletExists
+	}
+	if err != nil {
+		return nil, ownerError(err)
+	}
+	return &wallet, nil
+}
+
+func (r *PostgresRepository) GetWallet(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error) {
+	wallet := models.Wallet{ID: walletID}
+	err := r.read(ctx, func(db *sql.DB) error {
+		return db.QueryRowContext(
+			ctx,
+			"SELECT "+balanceExpr+", w.status, "+versionExpr+", w.currency, w.owner_id, w.created_at FROM wallets w WHERE w.id = $1",
+			walletID,
+		).Scan(&wallet.Balance, &wallet.Status, &wallet.Version, &wallet.Currency, &wallet.OwnerID, &wallet.CreatedAt)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWalletNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (r *PostgresRepository) GetBalance(ctx context.Context, walletID uuid.UUID) (int64, error) {
+	var balance int64
+	err := r.read(ctx, func(db *sql.DB) error {
+		return db.QueryRowContext(
+			ctx,
+			"SELECT "+balanceExpr+" FROM wallets w WHERE w.id = $1",
+			walletID,
+		).Scan(&balance)
+	})
 	if errors.Is(err, sql.ErrNoRows) {
 		return 0, ErrWalletNotFound
 	}
 	return balance, err
 }
 
-func (r *PostgresRepository) UpdateBalance(ctx context.Context, walletID uuid.UUID, amount int64) error {
-	tx, err := r.db.BeginTx(ctx, nil)
+func (r *PostgresRepository) UpdateBalance(ctx context.Context, walletID uuid.UUID, amount int64) (int64, error) {
+	if r.sharding {
+		balance, err := r.updateSharded(ctx, walletID, amount)
+		if !errors.Is(err, errNotSharded) && !errors.Is(err, errShardsExhausted) && !errors.Is(err, errNearLimit) {
+			return balance, err
+		}
+	}
+	if r.conditional {
+		balance, err := r.updateConditional(ctx, walletID, amount)
+		if !errors.Is(err, errNeedsLock) {
+			return balance, err
+		}
+	}
+	wallet, err := r.updateLocked(ctx, walletID, amount, 0)
+	if err != nil {
+		return 0, err
+	}
+	return wallet.balance, nil
+}
+
+func (r *PostgresRepository) UpdateBalanceIfVersion(ctx context.Context, walletID uuid.UUID, amount, version int64) (*models.Wallet, error) {
+	wallet, err := r.updateLocked(ctx, walletID, amount, version)
 	if err != nil {
+		return nil, err
+	}
+	return wallet.model(), nil
+}
+
+// updateLocked выполняет операцию под эксклюзивной блокировкой кошелька.
+// Ненулевой version требует, чтобы версия кошелька ему равнялась.
+func (r *PostgresRepository) updateLocked(ctx context.Context, walletID uuid.UUID, amount, version int64) (*lockedWallet, error) {
+	var wallet *lockedWallet
+	err := r.inTx(ctx, "update_balance", func(tx *sql.Tx) error {
+		var err error
+		wallet, err = lockWallet(ctx, tx, walletID)
+		if err != nil {
+			return err
+		}
+
+		if version != 0 && wallet.version != version {
+			return models.ErrVersionMismatch
+		}
+		if wallet.status == models.StatusFrozen {
+			return models.ErrWalletFrozen
+		}
+		if _, err := models.ApplyAmount(wallet.balance, amount, wallet.maxBalance); err != nil {
+			return err
+		}
+
+		operationType := models.Deposit
+		if amount < 0 {
+			operationType = models.Withdraw
+		}
+		return applyOperation(ctx, tx, wallet, operationType, amount, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// ApplyBatch применяет изменения баланса одного кошелька в одной транзакции в
+// порядке следования. Изменение, которое увело бы баланс в минус, получает
+// ErrInsufficientFunds, остальные применяются.
+func (r *PostgresRepository) ApplyBatch(ctx context.Context, walletID uuid.UUID, amounts []int64) ([]BatchResult, error) {
+	var results []BatchResult
+	err := r.inTx(ctx, "apply_batch", func(tx *sql.Tx) error {
+		wallet, err := lockActiveWallet(ctx, tx, walletID)
+		if err != nil {
+			return err
+		}
+
+		var (
+			accepted []int64
+			delta    int64
+		)
+		results, accepted, delta = planBatch(wallet.balance, wallet.maxBalance, amounts)
+		if len(accepted) == 0 {
+			return nil
+		}
+		if err := addToLockedBalance(ctx, tx, wallet, delta, int64(len(accepted))); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO wallet_operations (wallet_id, operation_type, amount)
+			SELECT $1, CASE WHEN a.amount < 0 THEN $3 ELSE $4 END, a.amount
+			FROM unnest($2::BIGINT[]) WITH ORDINALITY AS a (amount, n)
+			ORDER BY a.n`,
+			walletID,
+			pq.Array(accepted),
+			models.Withdraw,
+			models.Deposit,
+		)
 		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Transfer переводит amount между кошельками в одной транзакции. Строки
+// блокируются в порядке возрастания id, чтобы встречные переводы не
+// приводили к взаимной блокировке.
+func (r *PostgresRepository) Transfer(ctx context.Context, fromID, toID uuid.UUID, amount int64) (uuid.UUID, error) {
+	if fromID == toID {
+		return uuid.Nil, models.ErrSameWallet
+	}
+	first, second := fromID, toID
+	if bytes.Compare(first[:], second[:]) > 0 {
+		first, second = second, first
+	}
+
+	transferID := uuid.New()
+	err := r.inTx(ctx, "transfer", func(tx *sql.Tx) error {
+		wallets := make(map[uuid.UUID]*lockedWallet, 2)
+		for _, id := range []uuid.UUID{first, second} {
+			wallet, err := lockActiveWallet(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			wallets[id] = wallet
+		}
+
+		from, to := wallets[fromID], wallets[toID]
+		if _, err := models.ApplyAmount(from.balance, -amount, from.maxBalance); err != nil {
+			return err
+		}
+		if _, err := models.ApplyAmount(to.balance, amount, to.maxBalance); err != nil {
+			return err
+		}
+
+		if err := applyOperation(ctx, tx, from, models.TransferOut, -amount, &transferID); err != nil {
+			return err
+		}
+		return applyOperation(ctx, tx, to, models.TransferIn, amount, &transferID)
+	})
+	if err != nil {
+		return uuid.Nil, err
 	}
-	defer tx.Rollback() 
+	return transferID, nil
+}
 
-	var currentBalance int64
-	err = tx.QueryRowContext(
+func (r *PostgresRepository) SetStatus(ctx context.Context, walletID uuid.UUID, status models.WalletStatus) error {
+	result, err := r.db.ExecContext(
 		ctx,
-		"SELECT balance FROM wallets WHERE id = $1 FOR UPDATE", 
+		"UPDATE wallets SET status = $1, version = version + CASE WHEN status = $1 THEN 0 ELSE 1 END WHERE id = $2",
+		status,
 		walletID,
-	).Scan(&currentBalance)
-	if errors.Is(err, sql.ErrNoRows) {
-		return ErrWalletNotFound
+	)
+	if err != nil {
+		return err
 	}
+
+	affected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
+	if affected == 0 {
+		return ErrWalletNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) History(ctx context.Context, walletID uuid.UUID, limit int) ([]models.Operation, error) {
+	var operations []models.Operation
+	err := r.read(ctx, func(db *sql.DB) error {
+		var err error
+		operations, err = history(ctx, db, walletID, limit)
+		return err
+	})
+	return operations, err
+}
 
-	if amount < 0 && currentBalance+amount < 0 {
-		return models.ErrInsufficientFunds
+func history(ctx context.Context, db *sql.DB, walletID uuid.UUID, limit int) ([]models.Operation, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM wallets WHERE id = $1)", walletID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrWalletNotFound
 	}
 
-	_, err = tx.ExecContext(
+	rows, err := db.QueryContext(
 		ctx,
-		"UPDATE wallets SET balance = balance + $1 WHERE id = $2",
-		amount,
+		`SELECT id, wallet_id, operation_type, amount, transfer_id, created_at
+		FROM wallet_operations WHERE wallet_id = $1 ORDER BY id DESC LIMIT $2`,
 		walletID,
+		limit,
 	)
 	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	operations := make([]models.Operation, 0)
+	for rows.Next() {
+		var (
+			op         models.Operation
+			transferID uuid.NullUUID
+		)
+		if err := rows.Scan(&op.ID, &op.WalletID, &op.Type, &op.Amount, &transferID, &op.CreatedAt); err != nil {
+			return nil, err
+		}
+		if transferID.Valid {
+			op.TransferID = &transferID.UUID
+		}
+		operations = append(operations, op)
+	}
+	return operations, rows.Err()
+}
+
+type lockedWallet struct {
+	id         uuid.UUID
+	balance    int64
+	maxBalance int64
+	status     models.WalletStatus
+	shards     int
+	version    int64
+	currency   models.Currency
+	ownerID    *uuid.UUID
+	createdAt  time.Time
+}
+
+func (w *lockedWallet) model() *models.Wallet {
+	return &models.Wallet{
+		ID:        w.id,
+		Balance:   w.balance,
+		Status:    w.status,
+		Version:   w.version,
+		Currency:  w.currency,
+		OwnerID:   w.ownerID,
+		CreatedAt: w.createdAt,
+	}
+}
+
+// lockWallet блокирует строку кошелька до конца транзакции и возвращает его
+// полный баланс. Шардированные операции берут на строку FOR SHARE, поэтому
+// после FOR UPDATE шарды тоже не меняются до конца транзакции.
+func lockWallet(ctx context.Context, tx *sql.Tx, walletID uuid.UUID) (*lockedWallet, error) {
+	wallet := lockedWallet{id: walletID}
+	err := tx.QueryRowContext(
+		ctx,
+		"SELECT balance, max_balance, status, shard_count, version, currency, owner_id, created_at FROM wallets WHERE id = $1 FOR UPDATE", 
+		walletID,
+	).Scan(&wallet.balance, &wallet.maxBalance, &wallet.status, &wallet.shards, &wallet.version, &wallet.currency, &wallet.ownerID, &wallet.createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWalletNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if wallet.shards > 0 {
+		var shardsBalance, shardsVersion int64
+		err := tx.QueryRowContext(
+			ctx,
+			"SELECT COALESCE(SUM(balance), 0)::BIGINT, COALESCE(SUM(version), 0)::BIGINT FROM wallet_shards WHERE wallet_id = $1",
+			walletID,
+		).Scan(&shardsBalance, &shardsVersion)
+		if err != nil {
+			return nil, err
+		}
+		wallet.balance += shardsBalance
+		wallet.version += shardsVersion
+	}
+	return &wallet, nil
+}
+
+// lockActiveWallet работает как lockWallet, но отклоняет операцию по
+// замороженному кошельку.
+func lockActiveWallet(ctx context.Context, tx *sql.Tx, walletID uuid.UUID) (*lockedWallet, error) {
+	wallet, err := lockWallet(ctx, tx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.status == models.StatusFrozen {
+		return nil, models.ErrWalletFrozen
+	}
+	return wallet, nil
+}
+
+// applyOperation меняет баланс заблокированного кошелька и пишет операцию в
+// журнал.
+func applyOperation(ctx context.Context, tx *sql.Tx, wallet *lockedWallet, operationType models.OperationType, amount int64, transferID *uuid.UUID) error {
+	if err := addToLockedBalance(ctx, tx, wallet, amount, 1); err != nil {
 		return err
 	}
+	return insertOperation(ctx, tx, wallet.id, operationType, amount, transferID)
+}
+
+// addToLockedBalance прибавляет delta к балансу заблокированного кошелька и
+// changes к его версии. У шардированного кошелька новый баланс заново
+// распределяется по шардам.
+func addToLockedBalance(ctx context.Context, tx *sql.Tx, wallet *lockedWallet, delta, changes int64) error {
+	wallet.balance += delta
+	wallet.version += changes
+
+	if wallet.shards > 0 {
+		return rebalanceShards(ctx, tx, wallet, changes)
+	}
+	_, err := tx.ExecContext(
+		ctx,
+		"UPDATE wallets SET balance = balance + $1, version = version + $2 WHERE id = $3",
+		delta,
+		changes,
+		wallet.id,
+	)
+	return err
+}
 
-	return tx.Commit()
+func insertOperation(ctx context.Context, tx *sql.Tx, walletID uuid.UUID, operationType models.OperationType, amount int64, transferID *uuid.UUID) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO wallet_operations (wallet_id, operation_type, amount, transfer_id) VALUES ($1, $2, $3, $4)",
+		walletID,
+		operationType,
+		amount,
+		transferID,
+	)
+	return err
 }
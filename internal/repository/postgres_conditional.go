@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// errNeedsLock означает, что условное обновление не сработало по причине,
+// которую нужно перепроверить под эксклюзивной блокировкой.
+var errNeedsLock = errors.New("operation must be retried under the wallet lock")
+
+// conditionalUpdateQuery проверяет остаток, меняет баланс и пишет операцию в
+// журнал одной командой. Ноль строк в ответе означает, что кошелёк не найден,
+// заморожен, шардирован, средств недостаточно или баланс превысил бы
+// max_balance.
+const conditionalUpdateQuery = `WITH updated AS (
+	UPDATE wallets SET balance = balance + $1, version = version + 1
+	WHERE id = $2 AND status = $4 AND shard_count = 0 AND balance + $1 BETWEEN 0 AND max_balance
+	RETURNING balance
+), journal AS (
+	INSERT INTO wallet_operations (wallet_id, operation_type, amount)
+	SELECT $2, $3, $1 FROM updated
+)
+SELECT balance FROM updated`
+
+// WithConditionalUpdate переключает UpdateBalance на одну атомарную команду
+// без SELECT FOR UPDATE: один обмен с базой вместо четырёх. Если заданы
+// таймауты (WithTimeouts), команда выполняется в короткой транзакции после
+// setTimeoutsQuery, чтобы ожидание блокировки строки горячего кошелька
+// тоже заканчивалось ErrLockTimeout. Шардированные кошельки по-прежнему
+// обрабатываются под блокировкой.
+func WithConditionalUpdate() Option {
+	return func(r *PostgresRepository) {
+		r.conditional = true
+	}
+}
+
+func (r *PostgresRepository) updateConditional(ctx context.Context, walletID uuid.UUID, amount int64) (int64, error) {
+	// Сумма за пределами потолка баланса могла бы переполнить BIGINT в
+	// запросе; под блокировкой её проверит models.ApplyAmount.
+	if amount > models.MaxBalance || amount < -models.MaxBalance {
+		return 0, errNeedsLock
+	}
+
+	operationType := models.Deposit
+	if amount < 0 {
+		operationType = models.Withdraw
+	}
+
+	var balance int64
+	update := func(db interface {
+		QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	}) error {
+		return db.QueryRowContext(
+			ctx,
+			conditionalUpdateQuery,
+			amount,
+			walletID,
+			operationType,
+			models.StatusActive,
+		).Scan(&balance)
+	}
+
+	var err error
+	if r.timeouts.enabled() {
+		err = r.inTx(ctx, "update_conditional", func(tx *sql.Tx) error {
+			return update(tx)
+		})
+	} else {
+		err = r.retry(ctx, "update_conditional", func() error {
+			return update(r.db)
+		})
+		err = timeoutError(ctx, "update_conditional", limitError(err))
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return balance, err
+	}
+
+	// Условие не выполнилось. Причину определяем отдельным чтением: если
+	// состояние успело измениться так, что операция теперь проходит,
+	// повторяем её под блокировкой, а не сообщаем о нехватке средств.
+	var (
+		current    int64
+		maxBalance int64
+		status     models.WalletStatus
+		shards     int
+	)
+	err = r.db.QueryRowContext(
+		ctx,
+		"SELECT balance, max_balance, status, shard_count FROM wallets WHERE id = $1",
+		walletID,
+	).Scan(&current, &maxBalance, &status, &shards)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return 0, ErrWalletNotFound
+	case err != nil:
+		return 0, err
+	case status == models.StatusFrozen:
+		return 0, models.ErrWalletFrozen
+	case shards > 0:
+		return 0, errNeedsLock
+	}
+	if _, err := models.ApplyAmount(current, amount, maxBalance); err != nil {
+		return 0, err
+	}
+	return 0, errNeedsLock
+}
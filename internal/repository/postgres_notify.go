@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// InvalidationChannel — канал NOTIFY, в который триггеры на wallets и
+// wallet_shards пишут id изменённого кошелька.
+const InvalidationChannel = "wallet_changed"
+
+// Invalidator получает уведомления об изменённых кошельках.
+type Invalidator interface {
+	Invalidate(walletID uuid.UUID)
+	InvalidateAll()
+}
+
+// ListenInvalidations слушает InvalidationChannel отдельным соединением и
+// передаёт изменения в target до отмены ctx. Пока соединение потеряно,
+// уведомления пропадают, поэтому при разрыве и переподключении target
+// сбрасывается целиком.
+func ListenInvalidations(ctx context.Context, dsn string, target Invalidator) error {
+	listener := pq.NewListener(dsn, 100*time.Millisecond, 10*time.Second, func(event pq.ListenerEventType, _ error) {
+		if event == pq.ListenerEventDisconnected || event == pq.ListenerEventReconnected {
+			target.InvalidateAll()
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(InvalidationChannel); err != nil {
+		return err
+	}
+
+	// Без трафика разрыв соединения может долго оставаться незамеченным.
+	ping := time.NewTicker(30 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case n := <-listener.Notify:
+			if n == nil {
+				// Соединение восстановлено, часть уведомлений могла пропасть.
+				target.InvalidateAll()
+				continue
+			}
+			walletID, err := uuid.Parse(n.Extra)
+			if err != nil {
+				target.InvalidateAll()
+				continue
+			}
+			target.Invalidate(walletID)
+		case <-ping.C:
+			go listener.Ping()
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/metrics"
+)
+
+// replicaLagQuery возвращает отставание реплики в секундах. Реплика, которая
+// проиграла всё полученное, не отстаёт, даже если на основной базе давно не
+// было записей. NULL — отставание неизвестно.
+const replicaLagQuery = `SELECT CASE
+	WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+END`
+
+type replica struct {
+	db *sql.DB
+	// lag — последнее измеренное отставание; -1, пока оно неизвестно или
+	// реплика недоступна.
+	lag atomic.Int64
+}
+
+// WithReplicas отправляет GetWallet, GetBalance и History на реплики, чьё
+// отставание не больше maxLag. Пока MonitorReplicas не измерил отставание,
+// чтения идут на основную базу. Запись и блокирующие чтения всегда идут на
+// основную базу.
+func WithReplicas(maxLag time.Duration, dbs ...*sql.DB) Option {
+	return func(r *PostgresRepository) {
+		r.maxReplicaLag = maxLag
+		r.replicas = make([]*replica, len(dbs))
+		for i, db := range dbs {
+			r.replicas[i] = &replica{db: db}
+			r.replicas[i].lag.Store(-1)
+		}
+	}
+}
+
+// MonitorReplicas измеряет отставание реплик каждые interval до отмены ctx.
+func (r *PostgresRepository) MonitorReplicas(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for i, rep := range r.replicas {
+			lag := r.measureLag(ctx, rep, interval)
+			rep.lag.Store(int64(lag))
+			seconds := lag.Seconds()
+			if lag < 0 {
+				seconds = -1
+			}
+			metrics.ReplicaLag.WithLabelValues(strconv.Itoa(i)).Set(seconds)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *PostgresRepository) measureLag(ctx context.Context, rep *replica, timeout time.Duration) time.Duration {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var seconds sql.NullFloat64
+	if err := rep.db.QueryRowContext(ctx, replicaLagQuery).Scan(&seconds); err != nil || !seconds.Valid {
+		return -1
+	}
+	return time.Duration(seconds.Float64 * float64(time.Second))
+}
+
+// readDB выбирает реплику для чтения по кругу среди достаточно свежих или
+// возвращает nil, если читать нужно с основной базы.
+func (r *PostgresRepository) readDB(ctx context.Context) *sql.DB {
+	if len(r.replicas) == 0 || IsStrongRead(ctx) {
+		return nil
+	}
+
+	start := r.nextReplica.Add(1)
+	for i := range r.replicas {
+		rep := r.replicas[(int(start)+i)%len(r.replicas)]
+		if lag := rep.lag.Load(); lag >= 0 && time.Duration(lag) <= r.maxReplicaLag {
+			return rep.db
+		}
+	}
+	return nil
+}
+
+// read выполняет fn на реплике, а при любой ошибке там — ещё раз на
+// основной базе. Так кошелёк, который ещё не доехал до реплики, не выглядит
+// несуществующим.
+func (r *PostgresRepository) read(ctx context.Context, fn func(db *sql.DB) error) error {
+	if db := r.readDB(ctx); db != nil {
+		err := fn(db)
+		if err == nil || ctx.Err() != nil {
+			metrics.ReplicaReads.WithLabelValues("replica").Inc()
+			return err
+		}
+		metrics.ReplicaReads.WithLabelValues("fallback").Inc()
+	} else {
+		metrics.ReplicaReads.WithLabelValues("primary").Inc()
+	}
+	return fn(r.db)
+}
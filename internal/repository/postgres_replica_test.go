@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openUnreachable создаёт пул, который ни разу не подключается к базе:
+// readDB сравнивает только указатели.
+func openUnreachable(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=1 sslmode=disable")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestReplicaRouting_ReadDB(t *testing.T) {
+	first, second := openUnreachable(t), openUnreachable(t)
+	repo := NewPostgresRepository(openUnreachable(t), WithReplicas(time.Second, first, second))
+	ctx := context.Background()
+
+	// Отставание ещё не измерено.
+	assert.Nil(t, repo.readDB(ctx))
+
+	repo.replicas[0].lag.Store(int64(100 * time.Millisecond))
+	repo.replicas[1].lag.Store(int64(2 * time.Second))
+	for i := 0; i < 4; i++ {
+		assert.Same(t, first, repo.readDB(ctx))
+	}
+
+	repo.replicas[1].lag.Store(0)
+	used := map[*sql.DB]bool{}
+	for i := 0; i < 4; i++ {
+		used[repo.readDB(ctx)] = true
+	}
+	assert.Len(t, used, 2, "reads must be spread across fresh replicas")
+
+	assert.Nil(t, repo.readDB(WithStrongRead(ctx)))
+
+	repo.replicas[0].lag.Store(-1)
+	repo.replicas[1].lag.Store(int64(time.Second + 1))
+	assert.Nil(t, repo.readDB(ctx))
+}
+
+func TestReplicaRouting_NoReplicas(t *testing.T) {
+	repo := NewPostgresRepository(openUnreachable(t))
+	assert.Nil(t, repo.readDB(context.Background()))
+}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidShardCount = errors.New("shard count must be between 0 and 1024")
+
+	// errNotSharded и errShardsExhausted означают, что быстрый путь по шардам
+	// неприменим и операцию нужно выполнить под эксклюзивной блокировкой.
+	errNotSharded      = errors.New("wallet is not sharded")
+	errShardsExhausted = errors.New("no single shard can cover the withdrawal")
+	errNearLimit       = errors.New("deposit may exceed the balance limit")
+)
+
+const maxShards = 1024
+
+// WithSharding включает быстрый путь для шардированных кошельков: пополнение
+// попадает в случайный шард, а списание — в случайный шард с достаточным
+// остатком. Обе операции берут на строку кошелька только FOR SHARE и
+// не конкурируют между собой. Если ни один шард не покрывает списание,
+// операция повторяется под FOR UPDATE с перераспределением остатка.
+//
+// Без этой опции шардированные кошельки по-прежнему читаются и изменяются
+// корректно, но каждая операция блокирует кошелёк целиком.
+func WithSharding() Option {
+	return func(r *PostgresRepository) {
+		r.sharding = true
+	}
+}
+
+// SetShardCount разносит баланс кошелька по shards строкам. Ноль возвращает
+// весь баланс в wallets.balance.
+func (r *PostgresRepository) SetShardCount(ctx context.Context, walletID uuid.UUID, shards int) error {
+	if shards < 0 || shards > maxShards {
+		return ErrInvalidShardCount
+	}
+
+	return r.inTx(ctx, "set_shard_count", func(tx *sql.Tx) error {
+		wallet, err := lockWallet(ctx, tx, walletID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM wallet_shards WHERE wallet_id = $1", walletID); err != nil {
+			return err
+		}
+
+		base := wallet.balance
+		if shards > 0 {
+			base = 0
+			_, err := tx.ExecContext(
+				ctx,
+				"INSERT INTO wallet_shards (wallet_id, shard_no) SELECT $1, generate_series(0, $2 - 1)",
+				walletID,
+				shards,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Версии удалённых шардов переносятся в wallets.version, чтобы полная
+		// версия кошелька не уменьшилась.
+		wallet.version++
+		_, err = tx.ExecContext(
+			ctx,
+			"UPDATE wallets SET shard_count = $1, balance = $2, version = $3 WHERE id = $4",
+			shards,
+			base,
+			wallet.version,
+			walletID,
+		)
+		if err != nil {
+			return err
+		}
+
+		wallet.shards = shards
+		if shards > 0 {
+			return rebalanceShards(ctx, tx, wallet, 0)
+		}
+		return nil
+	})
+}
+
+// updateSharded выполняет операцию по шардированному кошельку без
+// эксклюзивной блокировки. Возвращаемый баланс учитывает только операции,
+// завершённые к моменту чтения: параллельные изменения других шардов в него
+// могут не попасть.
+//
+// Поэтому пополнение проходит быстрым путём, только если баланс остаётся
+// ниже max_balance с запасом на незавершённые пополнения остальных шардов
+// (не больше models.MaxAmount на шард). Ближе к потолку пополнение
+// возвращает errNearLimit и выполняется под блокировкой.
+func (r *PostgresRepository) updateSharded(ctx context.Context, walletID uuid.UUID, amount int64) (int64, error) {
+	if amount > models.MaxAmount || amount < -models.MaxBalance {
+		return 0, errNearLimit
+	}
+
+	var shards int
+	err := r.db.QueryRowContext(ctx, "SELECT shard_count FROM wallets WHERE id = $1", walletID).Scan(&shards)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrWalletNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if shards == 0 {
+		return 0, errNotSharded
+	}
+
+	var balance int64
+	err = r.inTx(ctx, "update_sharded", func(tx *sql.Tx) error {
+		var (
+			status     models.WalletStatus
+			maxBalance int64
+		)
+		err := tx.QueryRowContext(
+			ctx,
+			"SELECT status, shard_count, max_balance FROM wallets WHERE id = $1 FOR SHARE",
+			walletID,
+		).Scan(&status, &shards, &maxBalance)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrWalletNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if status == models.StatusFrozen {
+			return models.ErrWalletFrozen
+		}
+		if shards == 0 {
+			return errNotSharded
+		}
+
+		result, err := tx.ExecContext(
+			ctx,
+			"UPDATE wallet_shards SET balance = balance + $1, version = version + 1 WHERE wallet_id = $2 AND shard_no = $3 AND balance + $1 >= 0",
+			amount,
+			walletID,
+			rand.Intn(shards),
+		)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return errShardsExhausted
+		}
+
+		operationType := models.Deposit
+		if amount < 0 {
+			operationType = models.Withdraw
+		}
+		if err := insertOperation(ctx, tx, walletID, operationType, amount, nil); err != nil {
+			return err
+		}
+
+		err = tx.QueryRowContext(ctx, "SELECT "+balanceExpr+" FROM wallets w WHERE w.id = $1", walletID).Scan(&balance)
+		if err != nil {
+			return err
+		}
+		if amount > 0 && balance > maxBalance-int64(shards-1)*models.MaxAmount {
+			return errNearLimit
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return balance, nil
+}
+
+// rebalanceShards равномерно распределяет wallet.balance по шардам,
+// обнуляет wallets.balance и прибавляет changes к версии кошелька.
+// Вызывается только под FOR UPDATE на кошельке.
+func rebalanceShards(ctx context.Context, tx *sql.Tx, wallet *lockedWallet, changes int64) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"UPDATE wallets SET balance = 0, version = version + $2 WHERE id = $1",
+		wallet.id,
+		changes,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE wallet_shards
+		SET balance = $2::BIGINT / $3 + CASE WHEN shard_no < $2::BIGINT % $3 THEN 1 ELSE 0 END
+		WHERE wallet_id = $1`,
+		wallet.id,
+		wallet.balance,
+		wallet.shards,
+	)
+	return err
+}
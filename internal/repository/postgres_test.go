@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"os"
 	"sync"
 	"testing"
 	"time"
@@ -21,9 +22,17 @@ type PostgresRepositoryTestSuite struct {
 	repo *PostgresRepository
 }
 
+// testDSN возвращает строку подключения к тестовой базе из TEST_DATABASE_DSN
+// или к базе из docker-compose по умолчанию.
+func testDSN() string {
+	if dsn := os.Getenv("TEST_DATABASE_DSN"); dsn != "" {
+		return dsn
+	}
+	return "host=localhost port=5433 user=wallet_user password=wallet_password dbname=wallet_db sslmode=disable"
+}
+
 func (suite *PostgresRepositoryTestSuite) SetupSuite() {
-	connStr := "host=localhost port=5433 user=wallet_user password=wallet_password dbname=wallet_db sslmode=disable"
-	db, err := sql.Open("postgres", connStr)
+	db, err := sql.Open("postgres", testDSN())
 	if err != nil {
 		suite.T().Fatal(err)
 	}
@@ -54,7 +63,7 @@ func (suite *PostgresRepositoryTestSuite) SetupTest() {
 
 func (suite *PostgresRepositoryTestSuite) TestGetBalance_Success() {
 	walletID := uuid.New()
-	_, err := suite.db.Exec("INSERT INTO wallets (id, balance) VALUES ($1, $2)", walletID, 1500)
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 1500, suite.newOwner())
 	assert.NoError(suite.T(), err)
 
 	balance, err := suite.repo.GetBalance(context.Background(), walletID)
@@ -74,12 +83,13 @@ func (suite *PostgresRepositoryTestSuite) TestGetBalance_WalletNotFound() {
 
 func (suite *PostgresRepositoryTestSuite) TestUpdateBalance_Deposit() {
 	walletID := uuid.New()
-	_, err := suite.db.Exec("INSERT INTO wallets (id, balance) VALUES ($1, $2)", walletID, 1000)
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 1000, suite.newOwner())
 	assert.NoError(suite.T(), err)
 
-	err = suite.repo.UpdateBalance(context.Background(), walletID, 500)
+	newBalance, err := suite.repo.UpdateBalance(context.Background(), walletID, 500)
 
 	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1500), newBalance)
 
 	var balance int64
 	err = suite.db.QueryRow("SELECT balance FROM wallets WHERE id = $1", walletID).Scan(&balance)
@@ -89,12 +99,13 @@ func (suite *PostgresRepositoryTestSuite) TestUpdateBalance_Deposit() {
 
 func (suite *PostgresRepositoryTestSuite) TestUpdateBalance_Withdraw() {
 	walletID := uuid.New()
-	_, err := suite.db.Exec("INSERT INTO wallets (id, balance) VALUES ($1, $2)", walletID, 1000)
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 1000, suite.newOwner())
 	assert.NoError(suite.T(), err)
 
-	err = suite.repo.UpdateBalance(context.Background(), walletID, -300)
+	newBalance, err := suite.repo.UpdateBalance(context.Background(), walletID, -300)
 
 	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(700), newBalance)
 
 	var balance int64
 	err = suite.db.QueryRow("SELECT balance FROM wallets WHERE id = $1", walletID).Scan(&balance)
@@ -104,10 +115,10 @@ func (suite *PostgresRepositoryTestSuite) TestUpdateBalance_Withdraw() {
 
 func (suite *PostgresRepositoryTestSuite) TestUpdateBalance_InsufficientFunds() {
 	walletID := uuid.New()
-	_, err := suite.db.Exec("INSERT INTO wallets (id, balance) VALUES ($1, $2)", walletID, 500)
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 500, suite.newOwner())
 	assert.NoError(suite.T(), err)
 
-	err = suite.repo.UpdateBalance(context.Background(), walletID, -1000)
+	_, err = suite.repo.UpdateBalance(context.Background(), walletID, -1000)
 
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), models.ErrInsufficientFunds, err)
@@ -120,7 +131,7 @@ func (suite *PostgresRepositoryTestSuite) TestUpdateBalance_InsufficientFunds()
 
 func (suite *PostgresRepositoryTestSuite) TestUpdateBalance_WalletNotFound() {
 	nonExistentWallet := uuid.New()
-	err := suite.repo.UpdateBalance(context.Background(), nonExistentWallet, 1000)
+	_, err := suite.repo.UpdateBalance(context.Background(), nonExistentWallet, 1000)
 
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), ErrWalletNotFound, err)
@@ -128,7 +139,7 @@ func (suite *PostgresRepositoryTestSuite) TestUpdateBalance_WalletNotFound() {
 
 func (suite *PostgresRepositoryTestSuite) TestUpdateBalance_Concurrent() {
 	walletID := uuid.New()
-	_, err := suite.db.Exec("INSERT INTO wallets (id, balance) VALUES ($1, $2)", walletID, 1000)
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 1000, suite.newOwner())
 	assert.NoError(suite.T(), err)
 
 	iterations := 10
@@ -141,7 +152,8 @@ func (suite *PostgresRepositoryTestSuite) TestUpdateBalance_Concurrent() {
 			defer wg.Done()
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
-			errCh <- suite.repo.UpdateBalance(ctx, walletID, 100)
+			_, err := suite.repo.UpdateBalance(ctx, walletID, 100)
+			errCh <- err
 		}()
 	}
 
@@ -160,7 +172,7 @@ func (suite *PostgresRepositoryTestSuite) TestUpdateBalance_Concurrent() {
 
 func (suite *PostgresRepositoryTestSuite) TestUpdateBalance_ConcurrentMixed() {
 	walletID := uuid.New()
-	_, err := suite.db.Exec("INSERT INTO wallets (id, balance) VALUES ($1, $2)", walletID, 1000)
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 1000, suite.newOwner())
 	assert.NoError(suite.T(), err)
 
 	var wg sync.WaitGroup
@@ -170,7 +182,8 @@ func (suite *PostgresRepositoryTestSuite) TestUpdateBalance_ConcurrentMixed() {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			errCh <- suite.repo.UpdateBalance(context.Background(), walletID, 200)
+			_, err := suite.repo.UpdateBalance(context.Background(), walletID, 200)
+			errCh <- err
 		}()
 	}
 
@@ -178,7 +191,8 @@ func (suite *PostgresRepositoryTestSuite) TestUpdateBalance_ConcurrentMixed() {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			errCh <- suite.repo.UpdateBalance(context.Background(), walletID, -100)
+			_, err := suite.repo.UpdateBalance(context.Background(), walletID, -100)
+			errCh <- err
 		}()
 	}
 
@@ -195,6 +209,698 @@ func (suite *PostgresRepositoryTestSuite) TestUpdateBalance_ConcurrentMixed() {
 	assert.Equal(suite.T(), int64(1700), balance)
 }
 
+// newOwner создаёт владельца для кошельков теста.
+func (suite *PostgresRepositoryTestSuite) newOwner() uuid.UUID {
+	ownerID := uuid.New()
+	_, err := suite.repo.CreateOwner(context.Background(), ownerID, "test")
+	suite.Require().NoError(err)
+	return ownerID
+}
+
+func (suite *PostgresRepositoryTestSuite) TestCreateWallet() {
+	walletID := uuid.New()
+	ownerID := suite.newOwner()
+
+	wallet, err := suite.repo.CreateWallet(context.Background(), walletID, ownerID)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), walletID, wallet.ID)
+	assert.Equal(suite.T(), int64(0), wallet.Balance)
+	assert.Equal(suite.T(), models.StatusActive, wallet.Status)
+	assert.Equal(suite.T(), &ownerID, wallet.OwnerID)
+
+	_, err = suite.repo.CreateWallet(context.Background(), walletID, ownerID)
+	assert.Equal(suite.T(), ErrWalletExists, err)
+
+	_, err = suite.repo.CreateWallet(context.Background(), uuid.New(), uuid.New())
+	assert.ErrorIs(suite.T(), err, models.ErrOwnerNotFound)
+}
+
+func (suite *PostgresRepositoryTestSuite) TestCreateWalletInCurrency() {
+	walletID := uuid.New()
+	ownerID := suite.newOwner()
+
+	wallet, err := suite.repo.CreateWalletInCurrency(context.Background(), walletID, ownerID, "USD")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.Currency("USD"), wallet.Currency)
+	assert.Equal(suite.T(), int64(1), wallet.Version)
+
+	_, err = suite.repo.CreateWalletInCurrency(context.Background(), uuid.New(), ownerID, "XXX")
+	assert.Equal(suite.T(), models.ErrInvalidCurrency, err)
+
+	// Неизвестный владелец не оставляет кошелька ни в какой валюте.
+	orphanID := uuid.New()
+	_, err = suite.repo.CreateWalletInCurrency(context.Background(), orphanID, uuid.New(), "USD")
+	assert.ErrorIs(suite.T(), err, models.ErrOwnerNotFound)
+	_, err = suite.repo.GetWallet(context.Background(), orphanID)
+	assert.Equal(suite.T(), ErrWalletNotFound, err)
+}
+
+func (suite *PostgresRepositoryTestSuite) TestUpdateBalance_FrozenWallet() {
+	walletID := uuid.New()
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 1000, suite.newOwner())
+	assert.NoError(suite.T(), err)
+
+	err = suite.repo.SetStatus(context.Background(), walletID, models.StatusFrozen)
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.repo.UpdateBalance(context.Background(), walletID, 100)
+	assert.Equal(suite.T(), models.ErrWalletFrozen, err)
+
+	err = suite.repo.SetStatus(context.Background(), walletID, models.StatusActive)
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.repo.UpdateBalance(context.Background(), walletID, 100)
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *PostgresRepositoryTestSuite) TestTransfer() {
+	fromID, toID := uuid.New(), uuid.New()
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $5), ($3, $4, $5)", fromID, 1000, toID, 0, suite.newOwner())
+	assert.NoError(suite.T(), err)
+
+	transferID, err := suite.repo.Transfer(context.Background(), fromID, toID, 400)
+	assert.NoError(suite.T(), err)
+
+	fromBalance, _ := suite.repo.GetBalance(context.Background(), fromID)
+	toBalance, _ := suite.repo.GetBalance(context.Background(), toID)
+	assert.Equal(suite.T(), int64(600), fromBalance)
+	assert.Equal(suite.T(), int64(400), toBalance)
+
+	history, err := suite.repo.History(context.Background(), toID, 10)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), history, 1)
+	assert.Equal(suite.T(), models.TransferIn, history[0].Type)
+	assert.Equal(suite.T(), &transferID, history[0].TransferID)
+}
+
+func (suite *PostgresRepositoryTestSuite) TestTransfer_InsufficientFunds() {
+	fromID, toID := uuid.New(), uuid.New()
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $5), ($3, $4, $5)", fromID, 100, toID, 0, suite.newOwner())
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.repo.Transfer(context.Background(), fromID, toID, 400)
+	assert.Equal(suite.T(), models.ErrInsufficientFunds, err)
+
+	fromBalance, _ := suite.repo.GetBalance(context.Background(), fromID)
+	assert.Equal(suite.T(), int64(100), fromBalance)
+}
+
+func (suite *PostgresRepositoryTestSuite) TestHistory() {
+	walletID := uuid.New()
+	_, err := suite.repo.CreateWallet(context.Background(), walletID, suite.newOwner())
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.repo.UpdateBalance(context.Background(), walletID, 500)
+	assert.NoError(suite.T(), err)
+	_, err = suite.repo.UpdateBalance(context.Background(), walletID, -200)
+	assert.NoError(suite.T(), err)
+
+	history, err := suite.repo.History(context.Background(), walletID, 10)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), history, 2)
+	assert.Equal(suite.T(), models.Withdraw, history[0].Type)
+	assert.Equal(suite.T(), int64(-200), history[0].Amount)
+	assert.Equal(suite.T(), models.Deposit, history[1].Type)
+
+	_, err = suite.repo.History(context.Background(), uuid.New(), 10)
+	assert.Equal(suite.T(), ErrWalletNotFound, err)
+}
+
+func (suite *PostgresRepositoryTestSuite) TestSharding_SplitAndMerge() {
+	walletID := uuid.New()
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 1003, suite.newOwner())
+	assert.NoError(suite.T(), err)
+
+	err = suite.repo.SetShardCount(context.Background(), walletID, 4)
+	assert.NoError(suite.T(), err)
+
+	var base, shardsTotal, minShard int64
+	err = suite.db.QueryRow("SELECT balance FROM wallets WHERE id = $1", walletID).Scan(&base)
+	assert.NoError(suite.T(), err)
+	err = suite.db.QueryRow("SELECT SUM(balance), MIN(balance) FROM wallet_shards WHERE wallet_id = $1", walletID).Scan(&shardsTotal, &minShard)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(0), base)
+	assert.Equal(suite.T(), int64(1003), shardsTotal)
+	assert.Equal(suite.T(), int64(250), minShard)
+
+	balance, err := suite.repo.GetBalance(context.Background(), walletID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1003), balance)
+
+	err = suite.repo.SetShardCount(context.Background(), walletID, 0)
+	assert.NoError(suite.T(), err)
+
+	err = suite.db.QueryRow("SELECT balance FROM wallets WHERE id = $1", walletID).Scan(&base)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1003), base)
+}
+
+func (suite *PostgresRepositoryTestSuite) TestSharding_ConcurrentMixed() {
+	repo := NewPostgresRepository(suite.db, WithSharding())
+	walletID := uuid.New()
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 100, suite.newOwner())
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), repo.SetShardCount(context.Background(), walletID, 8))
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		withdrawn int64
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := repo.UpdateBalance(context.Background(), walletID, 10)
+			assert.NoError(suite.T(), err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := repo.UpdateBalance(context.Background(), walletID, -25)
+			if err == nil {
+				mu.Lock()
+				withdrawn += 25
+				mu.Unlock()
+				return
+			}
+			assert.Equal(suite.T(), models.ErrInsufficientFunds, err)
+		}()
+	}
+	wg.Wait()
+
+	balance, err := repo.GetBalance(context.Background(), walletID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(100+500)-withdrawn, balance)
+	assert.GreaterOrEqual(suite.T(), balance, int64(0))
+
+	var negative int
+	err = suite.db.QueryRow("SELECT COUNT(*) FROM wallet_shards WHERE wallet_id = $1 AND balance < 0", walletID).Scan(&negative)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, negative)
+}
+
+func (suite *PostgresRepositoryTestSuite) TestSharding_TransferFromShardedWallet() {
+	repo := NewPostgresRepository(suite.db, WithSharding())
+	fromID, toID := uuid.New(), uuid.New()
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $5), ($3, $4, $5)", fromID, 1000, toID, 0, suite.newOwner())
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), repo.SetShardCount(context.Background(), fromID, 4))
+
+	_, err = repo.Transfer(context.Background(), fromID, toID, 900)
+	assert.NoError(suite.T(), err)
+
+	fromBalance, _ := repo.GetBalance(context.Background(), fromID)
+	toBalance, _ := repo.GetBalance(context.Background(), toID)
+	assert.Equal(suite.T(), int64(100), fromBalance)
+	assert.Equal(suite.T(), int64(900), toBalance)
+}
+
+func (suite *PostgresRepositoryTestSuite) TestConditionalUpdate_Errors() {
+	repo := NewPostgresRepository(suite.db, WithConditionalUpdate())
+	walletID, frozenID := uuid.New(), uuid.New()
+	_, err := suite.db.Exec(
+		"INSERT INTO wallets (id, balance, status, owner_id) VALUES ($1, 500, 'ACTIVE', $3), ($2, 500, 'FROZEN', $3)",
+		walletID,
+		frozenID,
+		suite.newOwner(),
+	)
+	assert.NoError(suite.T(), err)
+
+	balance, err := repo.UpdateBalance(context.Background(), walletID, -200)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(300), balance)
+
+	_, err = repo.UpdateBalance(context.Background(), walletID, -301)
+	assert.Equal(suite.T(), models.ErrInsufficientFunds, err)
+
+	_, err = repo.UpdateBalance(context.Background(), uuid.New(), 100)
+	assert.Equal(suite.T(), ErrWalletNotFound, err)
+
+	_, err = repo.UpdateBalance(context.Background(), frozenID, 100)
+	assert.Equal(suite.T(), models.ErrWalletFrozen, err)
+
+	history, err := repo.History(context.Background(), walletID, 10)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), history, 2)
+	assert.Equal(suite.T(), int64(-200), history[0].Amount)
+	assert.Equal(suite.T(), models.Withdraw, history[0].Type)
+}
+
+func (suite *PostgresRepositoryTestSuite) TestConditionalUpdate_ShardedWalletFallsBackToLock() {
+	repo := NewPostgresRepository(suite.db, WithConditionalUpdate())
+	walletID := uuid.New()
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 1000, suite.newOwner())
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), repo.SetShardCount(context.Background(), walletID, 4))
+
+	balance, err := repo.UpdateBalance(context.Background(), walletID, -900)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(100), balance)
+
+	_, err = repo.UpdateBalance(context.Background(), walletID, -101)
+	assert.Equal(suite.T(), models.ErrInsufficientFunds, err)
+}
+
+func (suite *PostgresRepositoryTestSuite) TestConditionalUpdate_ConcurrentWithdrawals() {
+	repo := NewPostgresRepository(suite.db, WithConditionalUpdate())
+	walletID := uuid.New()
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 100, suite.newOwner())
+	assert.NoError(suite.T(), err)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+		balances  = make(map[int64]bool)
+	)
+	for i := 0; i < 150; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			balance, err := repo.UpdateBalance(context.Background(), walletID, -1)
+			if err != nil {
+				assert.Equal(suite.T(), models.ErrInsufficientFunds, err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			succeeded++
+			balances[balance] = true
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(suite.T(), 100, succeeded)
+	assert.Len(suite.T(), balances, 100, "every withdrawal must observe its own resulting balance")
+
+	balance, err := repo.GetBalance(context.Background(), walletID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(0), balance)
+}
+
+// brokenReplica — пул к несуществующему серверу: любое чтение с него
+// заканчивается ошибкой.
+func brokenReplica(t *testing.T) *sql.DB {
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func (suite *PostgresRepositoryTestSuite) TestReplicas_ReadsAndFallback() {
+	walletID := uuid.New()
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 700, suite.newOwner())
+	assert.NoError(suite.T(), err)
+
+	// Основная база в роли реплики: она не в режиме восстановления, и её
+	// отставание равно нулю.
+	repo := NewPostgresRepository(suite.db, WithReplicas(time.Second, suite.db, brokenReplica(suite.T())))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	monitorDone := make(chan struct{})
+	go func() {
+		repo.MonitorReplicas(ctx, 50*time.Millisecond)
+		close(monitorDone)
+	}()
+
+	assert.Eventually(suite.T(), func() bool {
+		return repo.replicas[0].lag.Load() == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(suite.T(), int64(-1), repo.replicas[1].lag.Load())
+
+	balance, err := repo.GetBalance(context.Background(), walletID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(700), balance)
+
+	history, err := repo.History(context.Background(), walletID, 10)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), history)
+
+	// Реплика, на которой чтение падает, подменяется основной базой.
+	cancel()
+	<-monitorDone
+	repo.replicas[1].lag.Store(0)
+	for i := 0; i < 4; i++ {
+		wallet, err := repo.GetWallet(context.Background(), walletID)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), int64(700), wallet.Balance)
+	}
+
+	_, err = repo.GetBalance(context.Background(), uuid.New())
+	assert.Equal(suite.T(), ErrWalletNotFound, err)
+}
+
+// notifyRecorder передаёт id из уведомлений в канал.
+type notifyRecorder chan uuid.UUID
+
+func (r notifyRecorder) Invalidate(walletID uuid.UUID) { r <- walletID }
+func (r notifyRecorder) InvalidateAll()                {}
+
+func (suite *PostgresRepositoryTestSuite) TestListenInvalidations() {
+	walletID := uuid.New()
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 1000, suite.newOwner())
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.repo.SetShardCount(context.Background(), walletID, 2))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	recorder := make(notifyRecorder, 100)
+	go ListenInvalidations(ctx, testDSN(), recorder)
+
+	// Слушатель подключается асинхронно: повторяем изменение, пока
+	// уведомление не дойдёт.
+	for attempt := 0; attempt < 50; attempt++ {
+		_, err := suite.repo.updateSharded(context.Background(), walletID, 1)
+		assert.NoError(suite.T(), err)
+
+		select {
+		case got := <-recorder:
+			assert.Equal(suite.T(), walletID, got)
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	suite.T().Fatal("no invalidation received for a shard update")
+}
+
+func (suite *PostgresRepositoryTestSuite) TestSetMaxBalance() {
+	ctx := context.Background()
+	walletID := uuid.New()
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 1000, suite.newOwner())
+	assert.NoError(suite.T(), err)
+
+	assert.ErrorIs(suite.T(), suite.repo.SetMaxBalance(ctx, walletID, 999), models.ErrBalanceLimitExceeded)
+	assert.NoError(suite.T(), suite.repo.SetMaxBalance(ctx, walletID, 1500))
+
+	_, err = suite.repo.UpdateBalance(ctx, walletID, 501)
+	assert.ErrorIs(suite.T(), err, models.ErrBalanceLimitExceeded)
+
+	conditional := NewPostgresRepository(suite.db, WithConditionalUpdate())
+	_, err = conditional.UpdateBalance(ctx, walletID, 501)
+	assert.ErrorIs(suite.T(), err, models.ErrBalanceLimitExceeded)
+
+	// Ограничение в базе страхует проверку в приложении.
+	_, err = suite.db.Exec("UPDATE wallets SET balance = 1501 WHERE id = $1", walletID)
+	assert.ErrorIs(suite.T(), limitError(err), models.ErrBalanceLimitExceeded)
+
+	balance, err := suite.repo.UpdateBalance(ctx, walletID, 500)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1500), balance)
+}
+
+func (suite *PostgresRepositoryTestSuite) TestSetCurrency() {
+	ctx := context.Background()
+	wallet, err := suite.repo.CreateWallet(ctx, uuid.New(), suite.newOwner())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.DefaultCurrency, wallet.Currency)
+
+	assert.ErrorIs(suite.T(), suite.repo.SetCurrency(ctx, wallet.ID, "XXX"), models.ErrInvalidCurrency)
+	assert.NoError(suite.T(), suite.repo.SetCurrency(ctx, wallet.ID, "JPY"))
+
+	got, err := suite.repo.GetWallet(ctx, wallet.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.Currency("JPY"), got.Currency)
+	assert.Equal(suite.T(), wallet.Version+1, got.Version)
+
+	_, err = suite.repo.UpdateBalance(ctx, wallet.ID, 100)
+	assert.NoError(suite.T(), err)
+	assert.ErrorIs(suite.T(), suite.repo.SetCurrency(ctx, wallet.ID, "USD"), models.ErrWalletNotEmpty)
+}
+
+func (suite *PostgresRepositoryTestSuite) TestBalanceAsOf_Checkpoints() {
+	ctx := context.Background()
+	wallet, err := suite.repo.CreateWallet(ctx, uuid.New(), suite.newOwner())
+	suite.Require().NoError(err)
+	for _, amount := range []int64{100, 50} {
+		_, err := suite.repo.UpdateBalance(ctx, wallet.ID, amount)
+		suite.Require().NoError(err)
+	}
+	operations, err := suite.repo.History(ctx, wallet.ID, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(operations, 2)
+	first, last := operations[1].CreatedAt, operations[0].CreatedAt
+
+	balance, err := suite.repo.BalanceAsOf(ctx, wallet.ID, first.Add(-time.Microsecond))
+	suite.NoError(err)
+	suite.Equal(int64(0), balance)
+	balance, err = suite.repo.BalanceAsOf(ctx, wallet.ID, first)
+	suite.NoError(err)
+	suite.Equal(int64(100), balance)
+
+	created, err := CreateCheckpoints(ctx, suite.db, first)
+	suite.NoError(err)
+	suite.Equal(int64(1), created)
+	created, err = CreateCheckpoints(ctx, suite.db, first)
+	suite.NoError(err)
+	suite.Equal(int64(0), created, "a repeated run must not write anything")
+
+	// Подменённая точка показывает, что баланс считается от неё, а не от
+	// начала журнала.
+	_, err = suite.db.Exec("UPDATE wallet_balance_checkpoints SET balance = 1000 WHERE wallet_id = $1", wallet.ID)
+	suite.Require().NoError(err)
+	balance, err = suite.repo.BalanceAsOf(ctx, wallet.ID, last)
+	suite.NoError(err)
+	suite.Equal(int64(1050), balance)
+
+	created, err = CreateCheckpoints(ctx, suite.db, last)
+	suite.NoError(err)
+	suite.Equal(int64(1), created)
+	var checkpoint int64
+	suite.NoError(suite.db.QueryRow("SELECT balance FROM wallet_balance_checkpoints WHERE wallet_id = $1 AND as_of = $2", wallet.ID, last).Scan(&checkpoint))
+	suite.Equal(int64(1050), checkpoint)
+
+	_, err = suite.repo.BalanceAsOf(ctx, uuid.New(), last)
+	suite.ErrorIs(err, ErrWalletNotFound)
+}
+
+func (suite *PostgresRepositoryTestSuite) TestBalanceAsOf_LateOperation() {
+	ctx := context.Background()
+	wallet, err := suite.repo.CreateWallet(ctx, uuid.New(), suite.newOwner())
+	suite.Require().NoError(err)
+	_, err = suite.repo.UpdateBalance(ctx, wallet.ID, 100)
+	suite.Require().NoError(err)
+	operations, err := suite.repo.History(ctx, wallet.ID, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(operations, 1)
+	at := operations[0].CreatedAt
+
+	created, err := CreateCheckpoints(ctx, suite.db, at)
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(1), created)
+
+	// Операция зафиксирована после точки, но время у неё раньше точки — как
+	// у долгого импорта, начатого до её момента.
+	late := func(amount int64, createdAt time.Time) {
+		tx, err := suite.db.Begin()
+		suite.Require().NoError(err)
+		defer tx.Rollback()
+		_, err = tx.Exec("INSERT INTO wallet_operations (wallet_id, operation_type, amount, created_at) VALUES ($1, $2, $3, $4)",
+			wallet.ID, models.Deposit, amount, createdAt)
+		suite.Require().NoError(err)
+		_, err = tx.Exec("UPDATE wallets SET balance = balance + $2 WHERE id = $1", wallet.ID, amount)
+		suite.Require().NoError(err)
+		suite.Require().NoError(tx.Commit())
+	}
+	late(30, at.Add(-time.Second))
+
+	balance, err := suite.repo.BalanceAsOf(ctx, wallet.ID, at)
+	suite.NoError(err)
+	suite.Equal(int64(130), balance)
+
+	// Следующий прогон считает от точки кошелька, а не от предыдущего
+	// прогона, и не теряет операцию между ними.
+	other, err := suite.repo.CreateWallet(ctx, uuid.New(), suite.newOwner())
+	suite.Require().NoError(err)
+	_, err = suite.repo.UpdateBalance(ctx, other.ID, 1)
+	suite.Require().NoError(err)
+	mid := time.Now()
+	created, err = CreateCheckpoints(ctx, suite.db, mid)
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(1), created)
+	late(20, at.Add(time.Microsecond))
+	next := mid.Add(time.Hour)
+	late(5, mid.Add(time.Minute))
+	created, err = CreateCheckpoints(ctx, suite.db, next)
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(1), created)
+
+	var checkpoint int64
+	suite.NoError(suite.db.QueryRow("SELECT balance FROM wallet_balance_checkpoints WHERE wallet_id = $1 AND as_of = $2", wallet.ID, next).Scan(&checkpoint))
+	suite.Equal(int64(155), checkpoint)
+	balance, err = suite.repo.BalanceAsOf(ctx, wallet.ID, next)
+	suite.NoError(err)
+	suite.Equal(int64(155), balance)
+}
+
+func (suite *PostgresRepositoryTestSuite) TestReconcile() {
+	ctx := context.Background()
+	ownerID := suite.newOwner()
+	var wallets [3]uuid.UUID
+	for i := range wallets {
+		wallet, err := suite.repo.CreateWallet(ctx, uuid.New(), ownerID)
+		suite.Require().NoError(err)
+		wallets[i] = wallet.ID
+	}
+	from, to, negative := wallets[0], wallets[1], wallets[2]
+	_, err := suite.repo.UpdateBalance(ctx, from, 1000)
+	suite.Require().NoError(err)
+	transferID, err := suite.repo.Transfer(ctx, from, to, 400)
+	suite.Require().NoError(err)
+
+	report, err := Reconcile(ctx, suite.db)
+	suite.Require().NoError(err)
+	suite.Equal(int64(3), report.Wallets)
+	suite.Equal(int64(1), report.Transfers)
+	suite.Zero(report.Total())
+	suite.Empty(report.Discrepancies)
+
+	// Правки в обход сервиса: остаток без операции, нога перевода на
+	// другую сумму и отрицательный остаток, проведённый через журнал.
+	_, err = suite.db.Exec("UPDATE wallets SET balance = balance + 500 WHERE id = $1", from)
+	suite.Require().NoError(err)
+	_, err = suite.db.Exec("UPDATE wallet_operations SET amount = 300 WHERE transfer_id = $1 AND operation_type = 'TRANSFER_IN'", transferID)
+	suite.Require().NoError(err)
+	_, err = suite.db.Exec("UPDATE wallets SET balance = -5 WHERE id = $1", negative)
+	suite.Require().NoError(err)
+	_, err = suite.db.Exec("INSERT INTO wallet_operations (wallet_id, operation_type, amount) VALUES ($1, 'WITHDRAW', -5)", negative)
+	suite.Require().NoError(err)
+
+	report, err = Reconcile(ctx, suite.db)
+	suite.Require().NoError(err)
+	suite.Equal(map[models.DiscrepancyKind]int64{
+		models.BalanceMismatch:    2,
+		models.BalanceOutOfRange:  1,
+		models.TransferUnbalanced: 1,
+	}, report.Counts)
+	suite.ElementsMatch([]models.Discrepancy{
+		{Kind: models.BalanceMismatch, WalletID: &from, Expected: 600, Actual: 1100},
+		{Kind: models.BalanceMismatch, WalletID: &to, Expected: 300, Actual: 400},
+		{Kind: models.BalanceOutOfRange, WalletID: &negative, Expected: 0, Actual: -5},
+		{Kind: models.TransferUnbalanced, TransferID: &transferID, Expected: 0, Actual: -100},
+	}, report.Discrepancies)
+
+	var stored int64
+	suite.NoError(suite.db.QueryRow("SELECT discrepancies FROM reconciliation_runs WHERE id = $1", report.ID).Scan(&stored))
+	suite.Equal(int64(4), stored)
+
+	// Удалённая нога перевода.
+	_, err = suite.db.Exec("DELETE FROM wallet_operations WHERE transfer_id = $1 AND operation_type = 'TRANSFER_IN'", transferID)
+	suite.Require().NoError(err)
+	report, err = Reconcile(ctx, suite.db)
+	suite.Require().NoError(err)
+	suite.Equal(int64(1), report.Counts[models.TransferLegs])
+}
+
+func (suite *PostgresRepositoryTestSuite) TestReconcile_Exclusive() {
+	ctx := context.Background()
+	tx, err := suite.db.BeginTx(ctx, nil)
+	suite.Require().NoError(err)
+	defer tx.Rollback()
+	_, err = tx.Exec("SELECT pg_advisory_xact_lock($1)", reconcileLockKey)
+	suite.Require().NoError(err)
+
+	_, err = Reconcile(ctx, suite.db)
+	suite.ErrorIs(err, ErrReconciliationRunning)
+}
+
+func (suite *PostgresRepositoryTestSuite) TestSharding_DepositNearLimitTakesLock() {
+	ctx := context.Background()
+	repo := NewPostgresRepository(suite.db, WithSharding())
+	walletID := uuid.New()
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 0, suite.newOwner())
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), repo.SetShardCount(ctx, walletID, 4))
+
+	// Запас на незавершённые пополнения трёх других шардов — 3 * MaxAmount:
+	// при лимите чуть больше него быстрый путь уже недоступен, и пополнение
+	// под блокировкой точно упирается в лимит.
+	limit := 3*models.MaxAmount + 20
+	assert.NoError(suite.T(), repo.SetMaxBalance(ctx, walletID, limit))
+
+	_, err = repo.updateSharded(ctx, walletID, 50)
+	assert.ErrorIs(suite.T(), err, errNearLimit)
+
+	balance, err := repo.UpdateBalance(ctx, walletID, limit)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), limit, balance)
+
+	_, err = repo.UpdateBalance(ctx, walletID, 1)
+	assert.ErrorIs(suite.T(), err, models.ErrBalanceLimitExceeded)
+}
+
+func (suite *PostgresRepositoryTestSuite) TestTimeouts_LockTimeout() {
+	walletID := uuid.New()
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 1000, suite.newOwner())
+	assert.NoError(suite.T(), err)
+
+	// Посторонняя транзакция держит блокировку кошелька.
+	holder, err := suite.db.Begin()
+	assert.NoError(suite.T(), err)
+	defer holder.Rollback()
+	_, err = holder.Exec("SELECT 1 FROM wallets WHERE id = $1 FOR UPDATE", walletID)
+	assert.NoError(suite.T(), err)
+
+	repo := NewPostgresRepository(suite.db, WithTimeouts(Timeouts{Lock: 50 * time.Millisecond, Statement: time.Second}))
+	start := time.Now()
+	_, err = repo.UpdateBalance(context.Background(), walletID, 100)
+
+	assert.ErrorIs(suite.T(), err, ErrLockTimeout)
+	assert.Less(suite.T(), time.Since(start), time.Second)
+
+	// Без опции операция ждёт блокировку до дедлайна запроса.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = suite.repo.UpdateBalance(ctx, walletID, 100)
+	assert.ErrorIs(suite.T(), err, context.DeadlineExceeded)
+
+	assert.NoError(suite.T(), holder.Rollback())
+	balance, err := repo.UpdateBalance(context.Background(), walletID, 100)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1100), balance)
+}
+
+func (suite *PostgresRepositoryTestSuite) TestTimeouts_ConditionalLockTimeout() {
+	walletID := uuid.New()
+	_, err := suite.db.Exec("INSERT INTO wallets (id, balance, owner_id) VALUES ($1, $2, $3)", walletID, 1000, suite.newOwner())
+	assert.NoError(suite.T(), err)
+
+	holder, err := suite.db.Begin()
+	assert.NoError(suite.T(), err)
+	defer holder.Rollback()
+	_, err = holder.Exec("SELECT 1 FROM wallets WHERE id = $1 FOR UPDATE", walletID)
+	assert.NoError(suite.T(), err)
+
+	repo := NewPostgresRepository(
+		suite.db,
+		WithConditionalUpdate(),
+		WithTimeouts(Timeouts{Lock: 50 * time.Millisecond, Statement: time.Second}),
+	)
+	start := time.Now()
+	_, err = repo.UpdateBalance(context.Background(), walletID, 100)
+
+	assert.ErrorIs(suite.T(), err, ErrLockTimeout)
+	assert.Less(suite.T(), time.Since(start), time.Second)
+
+	assert.NoError(suite.T(), holder.Rollback())
+	balance, err := repo.UpdateBalance(context.Background(), walletID, -100)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(900), balance)
+}
+
+func (suite *PostgresRepositoryTestSuite) TestTimeouts_StatementTimeout() {
+	repo := NewPostgresRepository(suite.db, WithTimeouts(Timeouts{Statement: 50 * time.Millisecond}))
+
+	err := repo.inTx(context.Background(), "test", func(tx *sql.Tx) error {
+		_, err := tx.Exec("SELECT pg_sleep(1)")
+		return err
+	})
+
+	assert.ErrorIs(suite.T(), err, ErrStatementTimeout)
+}
+
 func TestPostgresRepositoryTestSuite(t *testing.T) {
 	suite.Run(t, new(PostgresRepositoryTestSuite))
 }
\ No newline at end of file
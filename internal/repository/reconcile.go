@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/metrics"
+	"github.com/DisasterWoman/wallet-service/internal/models"
+)
+
+// reconcileLockKey — ключ advisory lock сверки: инстансы сервиса и
+// walletctl не выполняют её одновременно.
+const reconcileLockKey int64 = 7_426_150_302
+
+// ErrReconciliationRunning — сверку уже выполняет другой процесс.
+var ErrReconciliationRunning = errors.New("ledger reconciliation is already running")
+
+const (
+	startReconciliationQuery = "INSERT INTO reconciliation_runs (started_at, finished_at, wallets, transfers, discrepancies) " +
+		"VALUES (now(), now(), 0, 0, 0) RETURNING id, started_at"
+
+	// reconcileWalletsQuery сравнивает полный остаток каждого кошелька с
+	// суммой всего его журнала, а не с контрольными точками: точка сама
+	// может быть неверной. Возвращает число проверенных кошельков.
+	reconcileWalletsQuery = `
+	WITH journal AS (
+		SELECT wallet_id, SUM(amount)::BIGINT AS amount FROM wallet_operations GROUP BY wallet_id
+	), balances AS (
+		SELECT w.id, w.max_balance, ` + balanceExpr + ` AS balance, COALESCE(j.amount, 0) AS journal
+		FROM wallets w LEFT JOIN journal j ON j.wallet_id = w.id
+	), found AS (
+		INSERT INTO reconciliation_discrepancies (run_id, kind, wallet_id, expected, actual)
+		SELECT $1, 'BALANCE_MISMATCH', id, journal, balance FROM balances WHERE balance <> journal
+		UNION ALL
+		SELECT $1, 'BALANCE_OUT_OF_RANGE', id, CASE WHEN balance < 0 THEN 0 ELSE max_balance END, balance
+		FROM balances WHERE balance < 0 OR balance > max_balance
+		RETURNING 1
+	)
+	SELECT count(*) FROM balances`
+
+	// reconcileTransfersQuery проверяет двойную запись: у перевода ровно
+	// одна нога TRANSFER_OUT со списанием и одна TRANSFER_IN с зачислением,
+	// и в сумме они дают ноль. Возвращает число проверенных переводов.
+	reconcileTransfersQuery = `
+	WITH transfers AS (
+		SELECT transfer_id, SUM(amount)::BIGINT AS amount, count(*) AS legs,
+			count(*) FILTER (WHERE operation_type = 'TRANSFER_OUT' AND amount < 0) AS debits,
+			count(*) FILTER (WHERE operation_type = 'TRANSFER_IN' AND amount > 0) AS credits
+		FROM wallet_operations WHERE transfer_id IS NOT NULL GROUP BY transfer_id
+	), found AS (
+		INSERT INTO reconciliation_discrepancies (run_id, kind, transfer_id, expected, actual)
+		SELECT $1, 'TRANSFER_UNBALANCED', transfer_id, 0, amount FROM transfers WHERE amount <> 0
+		UNION ALL
+		SELECT $1, 'TRANSFER_LEGS', transfer_id, 2, legs FROM transfers WHERE legs <> 2 OR debits <> 1 OR credits <> 1
+		RETURNING 1
+	)
+	SELECT count(*) FROM transfers`
+
+	finishReconciliationQuery = `UPDATE reconciliation_runs SET finished_at = clock_timestamp(), wallets = $2, transfers = $3,
+		discrepancies = (SELECT count(*) FROM reconciliation_discrepancies WHERE run_id = $1)
+	WHERE id = $1 RETURNING finished_at`
+
+	discrepancyCountsQuery = "SELECT kind, count(*) FROM reconciliation_discrepancies WHERE run_id = $1 GROUP BY kind"
+	discrepanciesQuery     = "SELECT kind, wallet_id, transfer_id, expected, actual FROM reconciliation_discrepancies WHERE run_id = $1 ORDER BY id LIMIT $2"
+)
+
+// Reconcile сверяет остатки кошельков с журналом операций и переводы между
+// собой, записывает прогон и расхождения в reconciliation_runs и
+// reconciliation_discrepancies и возвращает отчёт. Все проверки читают один
+// снимок REPEATABLE READ, поэтому операции, идущие во время сверки, не
+// дают ложных расхождений. Если сверку уже выполняет другой процесс,
+// возвращается ErrReconciliationRunning.
+func Reconcile(ctx context.Context, db *sql.DB) (*models.ReconciliationReport, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock($1)", reconcileLockKey).Scan(&locked); err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, ErrReconciliationRunning
+	}
+
+	report := &models.ReconciliationReport{Counts: make(map[models.DiscrepancyKind]int64), Discrepancies: []models.Discrepancy{}}
+	if err := tx.QueryRowContext(ctx, startReconciliationQuery).Scan(&report.ID, &report.StartedAt); err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRowContext(ctx, reconcileWalletsQuery, report.ID).Scan(&report.Wallets); err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRowContext(ctx, reconcileTransfersQuery, report.ID).Scan(&report.Transfers); err != nil {
+		return nil, err
+	}
+	err = tx.QueryRowContext(ctx, finishReconciliationQuery, report.ID, report.Wallets, report.Transfers).Scan(&report.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := scanDiscrepancies(ctx, tx, report); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for _, kind := range models.DiscrepancyKinds {
+		metrics.LedgerDiscrepancies.WithLabelValues(string(kind)).Set(float64(report.Counts[kind]))
+	}
+	metrics.ReconciliationFinished.Set(float64(report.FinishedAt.Unix()))
+	return report, nil
+}
+
+// scanDiscrepancies читает в report число расхождений каждого вида и первые
+// models.MaxReportedDiscrepancies из них.
+func scanDiscrepancies(ctx context.Context, tx *sql.Tx, report *models.ReconciliationReport) error {
+	rows, err := tx.QueryContext(ctx, discrepancyCountsQuery, report.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			kind  models.DiscrepancyKind
+			count int64
+		)
+		if err := rows.Scan(&kind, &count); err != nil {
+			return err
+		}
+		report.Counts[kind] = count
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = tx.QueryContext(ctx, discrepanciesQuery, report.ID, models.MaxReportedDiscrepancies)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d models.Discrepancy
+		if err := rows.Scan(&d.Kind, &d.WalletID, &d.TransferID, &d.Expected, &d.Actual); err != nil {
+			return err
+		}
+		report.Discrepancies = append(report.Discrepancies, d)
+	}
+	return rows.Err()
+}
+
+// RunReconciliation выполняет сверку сразу и затем каждые interval до
+// отмены ctx. Прогон пропускается, если сверку выполняет другой инстанс.
+func RunReconciliation(ctx context.Context, db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := Reconcile(ctx, db)
+		switch {
+		case errors.Is(err, ErrReconciliationRunning):
+		case err != nil && ctx.Err() == nil:
+			metrics.ReconciliationFailures.Inc()
+			log.Printf("Ledger reconciliation failed: %v", err)
+		case err != nil:
+		case report.Total() > 0:
+			log.Printf("Ledger reconciliation run %d found %d discrepancies in %d wallets and %d transfers: %v",
+				report.ID, report.Total(), report.Wallets, report.Transfers, report.Counts)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
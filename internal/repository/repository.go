@@ -2,10 +2,52 @@ package repository
 
 import (
 	"context"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
 	"github.com/google/uuid"
 )
 
 type Repository interface {
+	CreateOwner(ctx context.Context, ownerID uuid.UUID, name string) (*models.Owner, error)
+	// CreateWallet создаёт пустой кошелёк владельца ownerID. Если владельца
+	// нет, возвращает models.ErrOwnerNotFound.
+	CreateWallet(ctx context.Context, walletID, ownerID uuid.UUID) (*models.Wallet, error)
+	GetWallet(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error)
 	GetBalance(ctx context.Context, walletID uuid.UUID) (int64, error)
-	UpdateBalance(ctx context.Context, walletID uuid.UUID, amount int64) error
-}
\ No newline at end of file
+	// UpdateBalance прибавляет amount к балансу (отрицательный amount —
+	// списание) и возвращает баланс после операции.
+	UpdateBalance(ctx context.Context, walletID uuid.UUID, amount int64) (int64, error)
+	// UpdateBalanceIfVersion работает как UpdateBalance, но применяет
+	// операцию, только если версия кошелька равна version, иначе возвращает
+	// models.ErrVersionMismatch. Возвращает кошелёк после операции.
+	UpdateBalanceIfVersion(ctx context.Context, walletID uuid.UUID, amount, version int64) (*models.Wallet, error)
+	// Transfer переводит amount с fromID на toID. Перевод на тот же кошелёк
+	// отклоняется с models.ErrSameWallet.
+	Transfer(ctx context.Context, fromID, toID uuid.UUID, amount int64) (uuid.UUID, error)
+	SetStatus(ctx context.Context, walletID uuid.UUID, status models.WalletStatus) error
+	History(ctx context.Context, walletID uuid.UUID, limit int) ([]models.Operation, error)
+	// ListOwnerWallets возвращает кошельки владельца или
+	// models.ErrOwnerNotFound, если владельца нет.
+	ListOwnerWallets(ctx context.Context, ownerID uuid.UUID) ([]models.Wallet, error)
+	// BalanceAsOf возвращает баланс кошелька на момент asOf — сумму
+	// операций журнала, созданных не позже asOf.
+	BalanceAsOf(ctx context.Context, walletID uuid.UUID, asOf time.Time) (int64, error)
+	// StreamStatement передаёт в sink остаток на момент from и операции с
+	// created_at в (from, to] из одного согласованного снимка.
+	StreamStatement(ctx context.Context, walletID uuid.UUID, from, to time.Time, sink StatementSink) error
+}
+
+type strongReadKey struct{}
+
+// WithStrongRead помечает контекст так, что чтения в нём видят все
+// подтверждённые записи: идут на основную базу в обход реплик и кэша.
+func WithStrongRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, strongReadKey{}, true)
+}
+
+// IsStrongRead сообщает, запрошено ли в ctx строгое чтение.
+func IsStrongRead(ctx context.Context) bool {
+	strong, _ := ctx.Value(strongReadKey{}).(bool)
+	return strong
+}
@@ -0,0 +1,1012 @@
+// Package repotest содержит набор проверок, которые должна проходить любая
+// реализация repository.Repository. Реализация подключается в своём _test.go:
+//
+//	func TestMyRepository_Conformance(t *testing.T) {
+//		repotest.Run(t, func(t *testing.T) repository.Repository {
+//			return NewMyRepository()
+//		})
+//	}
+package repotest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/bulk"
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/DisasterWoman/wallet-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory возвращает репозиторий для одного теста. Тесты создают кошельки
+// со случайными id, поэтому очищать хранилище между ними не обязательно.
+type Factory func(t *testing.T) repository.Repository
+
+func Run(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo repository.Repository)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"OwnerWallets", testOwnerWallets},
+		{"WalletSearch", testWalletSearch},
+		{"BalanceAsOf", testBalanceAsOf},
+		{"Statement", testStatement},
+		{"ImportExport", testImportExport},
+		{"ExportImportRoundTrip", testExportImportRoundTrip},
+		{"UnknownWallet", testUnknownWallet},
+		{"SameWalletTransfer", testSameWalletTransfer},
+		{"OverdraftRejected", testOverdraftRejected},
+		{"BalanceLimit", testBalanceLimit},
+		{"FrozenWallet", testFrozenWallet},
+		{"Versions", testVersions},
+		{"ConcurrentDeposits", testConcurrentDeposits},
+		{"ConcurrentWithdrawals", testConcurrentWithdrawals},
+		{"ConcurrentOpposingTransfers", testConcurrentOpposingTransfers},
+		{"CanceledContext", testCanceledContext},
+		{"CancellationMidTransaction", testCancellationMidTransaction},
+		{"SequentialModel", testSequentialModel},
+		{"Linearizability", testLinearizability},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+func createOwner(t *testing.T, repo repository.Repository) uuid.UUID {
+	t.Helper()
+	ownerID := uuid.New()
+	_, err := repo.CreateOwner(context.Background(), ownerID, "")
+	require.NoError(t, err)
+	return ownerID
+}
+
+func createWallet(t *testing.T, repo repository.Repository, balance int64) uuid.UUID {
+	t.Helper()
+	walletID := uuid.New()
+	_, err := repo.CreateWallet(context.Background(), walletID, createOwner(t, repo))
+	require.NoError(t, err)
+	if balance > 0 {
+		_, err := repo.UpdateBalance(context.Background(), walletID, balance)
+		require.NoError(t, err)
+	}
+	return walletID
+}
+
+func balanceOf(t *testing.T, repo repository.Repository, walletID uuid.UUID) int64 {
+	t.Helper()
+	balance, err := repo.GetBalance(context.Background(), walletID)
+	require.NoError(t, err)
+	return balance
+}
+
+// journalSum складывает суммы всех операций кошелька из журнала.
+func journalSum(t *testing.T, repo repository.Repository, walletID uuid.UUID) int64 {
+	t.Helper()
+	operations, err := repo.History(context.Background(), walletID, 1000)
+	require.NoError(t, err)
+	require.Less(t, len(operations), 1000, "journal is too long to be summed in one page")
+
+	var sum int64
+	for _, op := range operations {
+		sum += op.Amount
+	}
+	return sum
+}
+
+func testCreateAndGet(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	walletID := uuid.New()
+	ownerID := createOwner(t, repo)
+
+	created, err := repo.CreateWallet(ctx, walletID, ownerID)
+	require.NoError(t, err)
+	assert.Equal(t, walletID, created.ID)
+	assert.Equal(t, int64(0), created.Balance)
+	assert.Equal(t, models.StatusActive, created.Status)
+	assert.Equal(t, &ownerID, created.OwnerID)
+
+	_, err = repo.CreateWallet(ctx, walletID, ownerID)
+	assert.ErrorIs(t, err, repository.ErrWalletExists)
+
+	wallet, err := repo.GetWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.Equal(t, walletID, wallet.ID)
+	assert.Equal(t, models.StatusActive, wallet.Status)
+	assert.Equal(t, &ownerID, wallet.OwnerID)
+}
+
+func testOwnerWallets(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	ownerID := createOwner(t, repo)
+
+	_, err := repo.CreateOwner(ctx, ownerID, "")
+	assert.ErrorIs(t, err, repository.ErrOwnerExists)
+
+	wallets, err := repo.ListOwnerWallets(ctx, ownerID)
+	require.NoError(t, err)
+	assert.Empty(t, wallets)
+
+	first, second := uuid.New(), uuid.New()
+	for _, walletID := range []uuid.UUID{first, second} {
+		_, err := repo.CreateWallet(ctx, walletID, ownerID)
+		require.NoError(t, err)
+	}
+	_, err = repo.UpdateBalance(ctx, second, 250)
+	require.NoError(t, err)
+	createWallet(t, repo, 100) // кошелёк другого владельца
+
+	wallets, err = repo.ListOwnerWallets(ctx, ownerID)
+	require.NoError(t, err)
+	ids := make([]uuid.UUID, len(wallets))
+	var total int64
+	for i, wallet := range wallets {
+		ids[i] = wallet.ID
+		total += wallet.Balance
+	}
+	assert.ElementsMatch(t, []uuid.UUID{first, second}, ids)
+	assert.Equal(t, int64(250), total)
+
+	_, err = repo.ListOwnerWallets(ctx, uuid.New())
+	assert.ErrorIs(t, err, models.ErrOwnerNotFound)
+
+	_, err = repo.CreateWallet(ctx, uuid.New(), uuid.New())
+	assert.ErrorIs(t, err, models.ErrOwnerNotFound)
+}
+
+// testWalletSearch проверяет метки и поиск у хранилищ, реализующих
+// repository.WalletAdmin. Кошельки теста помечены уникальной меткой run,
+// чтобы поиск не видел кошельки соседних тестов в общей базе.
+func testWalletSearch(t *testing.T, repo repository.Repository) {
+	admin, ok := repo.(repository.WalletAdmin)
+	if !ok {
+		t.Skip("repository does not implement WalletAdmin")
+	}
+	ctx := context.Background()
+	run := uuid.NewString()
+
+	ids := make([]uuid.UUID, 3)
+	for i, balance := range []int64{0, 100, 500} {
+		ids[i] = createWallet(t, repo, balance)
+		labels := map[string]string{"run": run, "tier": "basic"}
+		if i > 0 {
+			labels["tier"] = "gold"
+		}
+		_, err := admin.SetWalletMetadata(ctx, ids[i], labels, json.RawMessage(`{"crm": {"account": "A-1"}}`))
+		require.NoError(t, err)
+	}
+	require.NoError(t, repo.SetStatus(ctx, ids[2], models.StatusFrozen))
+
+	version := versionOf(t, repo, ids[0])
+	wallet, err := admin.SetWalletMetadata(ctx, ids[0], map[string]string{"run": run, "tier": "basic"}, json.RawMessage(`{"note":"vip"}`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"run": run, "tier": "basic"}, wallet.Labels)
+	assert.JSONEq(t, `{"note":"vip"}`, string(wallet.Metadata))
+	assert.Equal(t, version, versionOf(t, repo, ids[0]), "metadata must not bump the version")
+
+	search := func(filter models.WalletFilter) []uuid.UUID {
+		t.Helper()
+		if filter.Limit == 0 {
+			filter.Limit = 10
+		}
+		filter.Labels["run"] = run
+		page, err := admin.SearchWallets(ctx, filter)
+		require.NoError(t, err)
+		found := make([]uuid.UUID, len(page.Wallets))
+		for i, wallet := range page.Wallets {
+			found[i] = wallet.ID
+		}
+		return found
+	}
+	minBalance, maxBalance := int64(100), int64(100)
+
+	assert.ElementsMatch(t, ids, search(models.WalletFilter{Labels: map[string]string{}}))
+	assert.ElementsMatch(t, ids[1:], search(models.WalletFilter{Labels: map[string]string{"tier": "gold"}}))
+	assert.ElementsMatch(t, ids[2:], search(models.WalletFilter{Labels: map[string]string{}, Status: models.StatusFrozen}))
+	assert.ElementsMatch(t, ids[1:2], search(models.WalletFilter{Labels: map[string]string{}, MinBalance: &minBalance, MaxBalance: &maxBalance}))
+	assert.Empty(t, search(models.WalletFilter{Labels: map[string]string{}, CreatedFrom: time.Now().Add(time.Hour)}))
+
+	first, err := admin.SearchWallets(ctx, models.WalletFilter{Labels: map[string]string{"run": run}, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, first.Wallets, 2)
+	require.NotEmpty(t, first.NextCursor)
+	cursor, err := models.ParseWalletCursor(first.NextCursor)
+	require.NoError(t, err)
+	second, err := admin.SearchWallets(ctx, models.WalletFilter{Labels: map[string]string{"run": run}, Limit: 2, After: &cursor})
+	require.NoError(t, err)
+	require.Len(t, second.Wallets, 1)
+	assert.Empty(t, second.NextCursor)
+	assert.ElementsMatch(t, ids, []uuid.UUID{first.Wallets[0].ID, first.Wallets[1].ID, second.Wallets[0].ID})
+
+	_, err = admin.SetWalletMetadata(ctx, uuid.New(), nil, nil)
+	assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+}
+
+// testBalanceAsOf берёт моменты из журнала, а не из часов теста: время
+// операций ставит хранилище.
+func testBalanceAsOf(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	walletID := createWallet(t, repo, 0)
+	for _, amount := range []int64{100, -30, 50} {
+		_, err := repo.UpdateBalance(ctx, walletID, amount)
+		require.NoError(t, err)
+	}
+	operations, err := repo.History(ctx, walletID, 10)
+	require.NoError(t, err)
+	require.Len(t, operations, 3)
+
+	tests := []struct {
+		asOf time.Time
+		want int64
+	}{
+		{operations[2].CreatedAt.Add(-time.Microsecond), 0},
+		{operations[2].CreatedAt, 100},
+		{operations[1].CreatedAt, 70},
+		{operations[0].CreatedAt, 120},
+		{time.Now().Add(time.Hour), 120},
+	}
+	for _, tt := range tests {
+		balance, err := repo.BalanceAsOf(ctx, walletID, tt.asOf)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, balance, "as of %s", tt.asOf)
+	}
+
+	_, err = repo.BalanceAsOf(ctx, uuid.New(), time.Now())
+	assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+}
+
+// statementSink собирает выписку в память.
+type statementSink struct {
+	wallet     *models.Wallet
+	opening    int64
+	operations []models.Operation
+}
+
+func (s *statementSink) Opening(wallet *models.Wallet, balance int64) error {
+	s.wallet, s.opening = wallet, balance
+	return nil
+}
+
+func (s *statementSink) Operation(op *models.Operation) error {
+	s.operations = append(s.operations, *op)
+	return nil
+}
+
+func testStatement(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	walletID := createWallet(t, repo, 0)
+	for _, amount := range []int64{100, -30, 50, -20} {
+		_, err := repo.UpdateBalance(ctx, walletID, amount)
+		require.NoError(t, err)
+	}
+	operations, err := repo.History(ctx, walletID, 10)
+	require.NoError(t, err)
+	require.Len(t, operations, 4)
+
+	// Период (from, to] исключает первую и последнюю операции.
+	var sink statementSink
+	err = repo.StreamStatement(ctx, walletID, operations[3].CreatedAt, operations[1].CreatedAt, &sink)
+	require.NoError(t, err)
+	require.NotNil(t, sink.wallet)
+	assert.Equal(t, walletID, sink.wallet.ID)
+	assert.Equal(t, int64(100), sink.opening)
+	require.Len(t, sink.operations, 2)
+	assert.Equal(t, operations[2].ID, sink.operations[0].ID)
+	assert.Equal(t, int64(-30), sink.operations[0].Amount)
+	assert.Equal(t, operations[1].ID, sink.operations[1].ID)
+	assert.Equal(t, int64(50), sink.operations[1].Amount)
+
+	sink = statementSink{}
+	err = repo.StreamStatement(ctx, uuid.New(), operations[3].CreatedAt, time.Now(), &sink)
+	assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+	assert.Nil(t, sink.wallet)
+}
+
+// sliceSource отдаёт записи импорта из среза; *models.ImportError в errs
+// по тому же индексу заменяет запись.
+type sliceSource struct {
+	wallets []models.ImportedWallet
+	errs    map[int]*models.ImportError
+	next    int
+}
+
+func (s *sliceSource) Next() (*models.ImportedWallet, error) {
+	if s.next == len(s.wallets) {
+		return nil, io.EOF
+	}
+	i := s.next
+	s.next++
+	if err, ok := s.errs[i]; ok {
+		return nil, err
+	}
+	return &s.wallets[i], nil
+}
+
+// walletSink собирает кошельки экспорта.
+type walletSink map[uuid.UUID]models.Wallet
+
+func (s walletSink) Write(wallet *models.Wallet) error {
+	s[wallet.ID] = *wallet
+	return nil
+}
+
+func testImportExport(t *testing.T, repo repository.Repository) {
+	store, ok := repo.(repository.WalletBulk)
+	if !ok {
+		t.Skip("repository does not implement WalletBulk")
+	}
+	ctx := context.Background()
+	existing := createWallet(t, repo, 300)
+	ownerID := uuid.New()
+	limit := int64(5000)
+	a := models.ImportedWallet{Line: 2, ID: uuid.New(), OwnerID: ownerID, Currency: "RUB", Balance: 1250, Status: models.StatusActive,
+		MaxBalance: &limit, Labels: map[string]string{"team": "payments"}, Metadata: json.RawMessage(`{"crm": "A-1"}`)}
+	b := models.ImportedWallet{Line: 3, ID: uuid.New(), OwnerID: ownerID, Currency: "JPY", Balance: 0, Status: models.StatusFrozen}
+	assertAbsent := func() {
+		t.Helper()
+		_, err := repo.GetWallet(ctx, a.ID)
+		assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+	}
+
+	// Неверная запись отклоняет весь импорт.
+	invalid := &models.ImportError{Line: 4, Code: models.CodeInvalidCurrency, Message: "currency is not supported"}
+	report, err := store.ImportWallets(ctx, &sliceSource{wallets: []models.ImportedWallet{a, b, {}}, errs: map[int]*models.ImportError{2: invalid}}, false)
+	require.NoError(t, err)
+	assert.False(t, report.Committed)
+	assert.Equal(t, int64(3), report.Records)
+	assert.Equal(t, []models.ImportError{*invalid}, report.Errors)
+	assertAbsent()
+
+	// Дубликат в файле и уже существующий кошелёк.
+	dup := a
+	dup.Line = 3
+	exists := b
+	exists.Line, exists.ID = 4, existing
+	report, err = store.ImportWallets(ctx, &sliceSource{wallets: []models.ImportedWallet{a, dup, exists}}, false)
+	require.NoError(t, err)
+	assert.False(t, report.Committed)
+	require.Len(t, report.Errors, 2)
+	assert.Equal(t, int64(3), report.Errors[0].Line)
+	assert.Equal(t, models.CodeDuplicateWallet, report.Errors[0].Code)
+	assert.Equal(t, int64(4), report.Errors[1].Line)
+	assert.Equal(t, models.CodeWalletExists, report.Errors[1].Code)
+	assertAbsent()
+
+	// Пробный запуск сверяет итоги, но ничего не записывает.
+	report, err = store.ImportWallets(ctx, &sliceSource{wallets: []models.ImportedWallet{a, b}}, true)
+	require.NoError(t, err)
+	assert.False(t, report.Committed)
+	assert.True(t, report.Balanced)
+	assert.Equal(t, int64(2), report.Wallets)
+	assert.Equal(t, int64(1), report.OwnersCreated)
+	assertAbsent()
+
+	report, err = store.ImportWallets(ctx, &sliceSource{wallets: []models.ImportedWallet{a, b}}, false)
+	require.NoError(t, err)
+	assert.True(t, report.Committed)
+	assert.True(t, report.Balanced)
+	assert.Zero(t, report.ErrorCount)
+	assert.Equal(t, report.Input, report.Loaded)
+	require.Len(t, report.Loaded, 2)
+	assert.Equal(t, "12.50", report.Loaded[1].BalanceDecimal)
+
+	wallet, err := repo.GetWallet(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1250), wallet.Balance)
+	assert.Equal(t, int64(1250), journalSum(t, repo, a.ID))
+	wallet, err = repo.GetWallet(ctx, b.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusFrozen, wallet.Status)
+	assert.Equal(t, models.Currency("JPY"), wallet.Currency)
+	owned, err := repo.ListOwnerWallets(ctx, ownerID)
+	require.NoError(t, err)
+	assert.Len(t, owned, 2)
+
+	sink := make(walletSink)
+	require.NoError(t, store.ExportWallets(ctx, sink))
+	assert.Equal(t, int64(1250), sink[a.ID].Balance)
+	assert.Equal(t, models.StatusFrozen, sink[b.ID].Status)
+	assert.Equal(t, int64(300), sink[existing].Balance)
+	assert.Equal(t, &ownerID, sink[a.ID].OwnerID)
+	require.NotNil(t, sink[a.ID].MaxBalance)
+	assert.Equal(t, limit, *sink[a.ID].MaxBalance)
+	assert.Equal(t, map[string]string{"team": "payments"}, sink[a.ID].Labels)
+	assert.JSONEq(t, `{"crm": "A-1"}`, string(sink[a.ID].Metadata))
+	require.NotNil(t, sink[b.ID].MaxBalance)
+	assert.Equal(t, models.MaxBalance, *sink[b.ID].MaxBalance)
+	assert.Empty(t, sink[b.ID].Labels)
+}
+
+// testExportImportRoundTrip выгружает хранилище файлом экспорта и
+// загружает файл в пустой MemoryRepository: кошельки должны совпасть,
+// а кошельки без владельца — получить владельца для старых кошельков.
+func testExportImportRoundTrip(t *testing.T, repo repository.Repository) {
+	source, ok := repo.(repository.WalletBulk)
+	if !ok {
+		t.Skip("repository does not implement WalletBulk")
+	}
+	ctx := context.Background()
+	labeled := createWallet(t, repo, 1250)
+	if admin, ok := repo.(repository.WalletAdmin); ok {
+		_, err := admin.SetWalletMetadata(ctx, labeled, map[string]string{"team": "payments"}, json.RawMessage(`{"crm": {"account": "A-1"}}`))
+		require.NoError(t, err)
+	}
+	if limiter, ok := repo.(interface {
+		SetMaxBalance(ctx context.Context, walletID uuid.UUID, limit int64) error
+	}); ok {
+		require.NoError(t, limiter.SetMaxBalance(ctx, labeled, 5000))
+	}
+	frozen := createWallet(t, repo, 0)
+	require.NoError(t, repo.SetStatus(ctx, frozen, models.StatusFrozen))
+
+	original := make(walletSink)
+	require.NoError(t, source.ExportWallets(ctx, original))
+
+	for _, format := range []models.BulkFormat{models.BulkCSV, models.BulkJSONL} {
+		t.Run(string(format), func(t *testing.T) {
+			var file bytes.Buffer
+			writer, err := bulk.NewWriter(&file, format)
+			require.NoError(t, err)
+			require.NoError(t, source.ExportWallets(ctx, writer))
+			require.NoError(t, writer.Close())
+
+			legacyOwner := uuid.New()
+			reader, err := bulk.NewReader(&file, format, bulk.WithLegacyOwner(legacyOwner))
+			require.NoError(t, err)
+			target := repository.NewMemoryRepository()
+			report, err := target.ImportWallets(ctx, reader, false)
+			require.NoError(t, err)
+			require.Empty(t, report.Errors)
+			require.True(t, report.Committed)
+
+			copied := make(walletSink)
+			require.NoError(t, target.ExportWallets(ctx, copied))
+			// Кошельки других тестов могли появиться после первой выгрузки.
+			for id, want := range original {
+				got, ok := copied[id]
+				require.True(t, ok, "wallet %s is missing after import", id)
+				assert.Equal(t, want.Balance, got.Balance)
+				assert.Equal(t, want.Status, got.Status)
+				assert.Equal(t, want.Currency, got.Currency)
+				assert.Equal(t, *want.MaxBalance, *got.MaxBalance)
+				assert.Equal(t, len(want.Labels), len(got.Labels))
+				for key, value := range want.Labels {
+					assert.Equal(t, value, got.Labels[key])
+				}
+				assert.JSONEq(t, string(want.Metadata), string(got.Metadata))
+				if want.OwnerID == nil {
+					assert.Equal(t, &legacyOwner, got.OwnerID)
+				} else {
+					assert.Equal(t, want.OwnerID, got.OwnerID)
+				}
+			}
+			assert.Equal(t, models.StatusFrozen, copied[frozen].Status)
+		})
+	}
+}
+
+func testUnknownWallet(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	unknown := uuid.New()
+	existing := createWallet(t, repo, 100)
+
+	_, err := repo.GetWallet(ctx, unknown)
+	assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+
+	_, err = repo.GetBalance(ctx, unknown)
+	assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+
+	_, err = repo.UpdateBalance(ctx, unknown, 100)
+	assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+
+	err = repo.SetStatus(ctx, unknown, models.StatusFrozen)
+	assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+
+	_, err = repo.History(ctx, unknown, 10)
+	assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+
+	_, err = repo.Transfer(ctx, existing, unknown, 10)
+	assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+	assert.Equal(t, int64(100), balanceOf(t, repo, existing))
+}
+
+func testSameWalletTransfer(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	walletID := createWallet(t, repo, 100)
+
+	_, err := repo.Transfer(ctx, walletID, walletID, 10)
+	assert.ErrorIs(t, err, models.ErrSameWallet)
+
+	assert.Equal(t, int64(100), balanceOf(t, repo, walletID))
+	operations, err := repo.History(ctx, walletID, 10)
+	require.NoError(t, err)
+	assert.Len(t, operations, 1, "only the opening deposit")
+}
+
+func testOverdraftRejected(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	walletID := createWallet(t, repo, 500)
+	otherID := createWallet(t, repo, 0)
+
+	_, err := repo.UpdateBalance(ctx, walletID, -501)
+	assert.ErrorIs(t, err, models.ErrInsufficientFunds)
+
+	_, err = repo.Transfer(ctx, walletID, otherID, 501)
+	assert.ErrorIs(t, err, models.ErrInsufficientFunds)
+
+	assert.Equal(t, int64(500), balanceOf(t, repo, walletID))
+	assert.Equal(t, int64(0), balanceOf(t, repo, otherID))
+	assert.Equal(t, int64(500), journalSum(t, repo, walletID))
+
+	balance, err := repo.UpdateBalance(ctx, walletID, -500)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), balance)
+	assert.Equal(t, int64(0), balanceOf(t, repo, walletID))
+}
+
+func testBalanceLimit(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	walletID := createWallet(t, repo, models.MaxBalance-10)
+	otherID := createWallet(t, repo, 100)
+
+	_, err := repo.UpdateBalance(ctx, walletID, 11)
+	assert.ErrorIs(t, err, models.ErrBalanceLimitExceeded)
+
+	// Сумма, на которой balance + amount переполнил бы int64.
+	_, err = repo.UpdateBalance(ctx, walletID, math.MaxInt64)
+	assert.ErrorIs(t, err, models.ErrBalanceLimitExceeded)
+
+	_, err = repo.Transfer(ctx, otherID, walletID, 11)
+	assert.ErrorIs(t, err, models.ErrBalanceLimitExceeded)
+
+	assert.Equal(t, models.MaxBalance-10, balanceOf(t, repo, walletID))
+	assert.Equal(t, int64(100), balanceOf(t, repo, otherID))
+
+	balance, err := repo.UpdateBalance(ctx, walletID, 10)
+	require.NoError(t, err)
+	assert.Equal(t, models.MaxBalance, balance)
+}
+
+func testFrozenWallet(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	walletID := createWallet(t, repo, 500)
+	otherID := createWallet(t, repo, 500)
+
+	require.NoError(t, repo.SetStatus(ctx, walletID, models.StatusFrozen))
+
+	_, err := repo.UpdateBalance(ctx, walletID, 100)
+	assert.ErrorIs(t, err, models.ErrWalletFrozen)
+
+	_, err = repo.Transfer(ctx, otherID, walletID, 100)
+	assert.ErrorIs(t, err, models.ErrWalletFrozen)
+
+	wallet, err := repo.GetWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusFrozen, wallet.Status)
+	assert.Equal(t, int64(500), wallet.Balance)
+	assert.Equal(t, int64(500), balanceOf(t, repo, otherID))
+
+	require.NoError(t, repo.SetStatus(ctx, walletID, models.StatusActive))
+	balance, err := repo.UpdateBalance(ctx, walletID, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(600), balance)
+}
+
+func versionOf(t *testing.T, repo repository.Repository, walletID uuid.UUID) int64 {
+	t.Helper()
+	wallet, err := repo.GetWallet(context.Background(), walletID)
+	require.NoError(t, err)
+	return wallet.Version
+}
+
+// testVersions проверяет, что версия растёт при каждом изменении кошелька и
+// что UpdateBalanceIfVersion отклоняет операцию по устаревшей версии.
+func testVersions(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	walletID := createWallet(t, repo, 0)
+	otherID := createWallet(t, repo, 0)
+
+	created := versionOf(t, repo, walletID)
+	assert.Positive(t, created)
+
+	_, err := repo.UpdateBalance(ctx, walletID, 500)
+	require.NoError(t, err)
+	read := versionOf(t, repo, walletID)
+	assert.Greater(t, read, created)
+
+	wallet, err := repo.UpdateBalanceIfVersion(ctx, walletID, -100, read)
+	require.NoError(t, err)
+	assert.Equal(t, int64(400), wallet.Balance)
+	assert.Greater(t, wallet.Version, read)
+	assert.Equal(t, wallet.Version, versionOf(t, repo, walletID))
+
+	_, err = repo.UpdateBalanceIfVersion(ctx, walletID, -100, read)
+	assert.ErrorIs(t, err, models.ErrVersionMismatch)
+	assert.Equal(t, int64(400), balanceOf(t, repo, walletID))
+
+	_, err = repo.UpdateBalanceIfVersion(ctx, uuid.New(), 100, read)
+	assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+
+	before, otherBefore := versionOf(t, repo, walletID), versionOf(t, repo, otherID)
+	_, err = repo.Transfer(ctx, walletID, otherID, 100)
+	require.NoError(t, err)
+	assert.Greater(t, versionOf(t, repo, walletID), before)
+	assert.Greater(t, versionOf(t, repo, otherID), otherBefore)
+
+	before = versionOf(t, repo, walletID)
+	require.NoError(t, repo.SetStatus(ctx, walletID, models.StatusFrozen))
+	frozen := versionOf(t, repo, walletID)
+	assert.Greater(t, frozen, before)
+
+	_, err = repo.UpdateBalanceIfVersion(ctx, walletID, 100, frozen)
+	assert.ErrorIs(t, err, models.ErrWalletFrozen)
+	assert.Equal(t, frozen, versionOf(t, repo, walletID))
+}
+
+func testConcurrentDeposits(t *testing.T, repo repository.Repository) {
+	walletID := createWallet(t, repo, 0)
+
+	const workers, perWorker = 20, 10
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				_, err := repo.UpdateBalance(context.Background(), walletID, 3)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(workers*perWorker*3), balanceOf(t, repo, walletID))
+	assert.Equal(t, int64(workers*perWorker*3), journalSum(t, repo, walletID))
+}
+
+func testConcurrentWithdrawals(t *testing.T, repo repository.Repository) {
+	walletID := createWallet(t, repo, 100)
+
+	const attempts = 150
+	var (
+		wg           sync.WaitGroup
+		mu           sync.Mutex
+		succeeded    int
+		insufficient int
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.UpdateBalance(context.Background(), walletID, -1)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.Is(err, models.ErrInsufficientFunds):
+				insufficient++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 100, succeeded)
+	assert.Equal(t, attempts-100, insufficient)
+	assert.Equal(t, int64(0), balanceOf(t, repo, walletID))
+}
+
+func testConcurrentOpposingTransfers(t *testing.T, repo repository.Repository) {
+	firstID := createWallet(t, repo, 1000)
+	secondID := createWallet(t, repo, 1000)
+
+	const transfers = 50
+	var wg sync.WaitGroup
+	for i := 0; i < transfers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := repo.Transfer(context.Background(), firstID, secondID, 7)
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := repo.Transfer(context.Background(), secondID, firstID, 5)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(1000-transfers*2), balanceOf(t, repo, firstID))
+	assert.Equal(t, int64(1000+transfers*2), balanceOf(t, repo, secondID))
+	assert.Equal(t, balanceOf(t, repo, firstID), journalSum(t, repo, firstID))
+	assert.Equal(t, balanceOf(t, repo, secondID), journalSum(t, repo, secondID))
+}
+
+func testCanceledContext(t *testing.T, repo repository.Repository) {
+	walletID := createWallet(t, repo, 500)
+	otherID := createWallet(t, repo, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.UpdateBalance(ctx, walletID, -100)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = repo.Transfer(ctx, walletID, otherID, 100)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	assert.Equal(t, int64(500), balanceOf(t, repo, walletID))
+	assert.Equal(t, int64(0), balanceOf(t, repo, otherID))
+}
+
+// testCancellationMidTransaction отменяет контексты в случайные моменты, пока
+// операции конкурируют за кошелёк. Каждая операция должна либо примениться
+// целиком, либо не примениться вовсе: баланс обязан совпадать с журналом.
+func testCancellationMidTransaction(t *testing.T, repo repository.Repository) {
+	walletID := createWallet(t, repo, 0)
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	const operations = 200
+	delays := make([]time.Duration, operations)
+	for i := range delays {
+		delays[i] = time.Duration(rng.Intn(2000)) * time.Microsecond
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int64
+		failed    int64
+	)
+	for i := 0; i < operations; i++ {
+		wg.Add(1)
+		go func(delay time.Duration) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), delay)
+			defer cancel()
+
+			_, err := repo.UpdateBalance(ctx, walletID, 1)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				succeeded++
+			} else {
+				failed++
+			}
+		}(delays[i])
+	}
+	wg.Wait()
+
+	// Отменённый участник пакета BatchingRepository возвращается, не
+	// дожидаясь пакета, и тот может ещё применяться: ждём, пока баланс и
+	// журнал сойдутся.
+	var balance int64
+	assert.Eventually(t, func() bool {
+		balance = balanceOf(t, repo, walletID)
+		return balance == journalSum(t, repo, walletID)
+	}, 5*time.Second, 10*time.Millisecond, "balance must match the journal")
+	// Операция, чей контекст отменился во время COMMIT, может успеть
+	// примениться, поэтому ошибки допускают только превышение снизу.
+	assert.GreaterOrEqual(t, balance, succeeded)
+	assert.LessOrEqual(t, balance, succeeded+failed)
+}
+
+// testSequentialModel сравнивает случайную последовательность операций над
+// несколькими кошельками с простой последовательной моделью.
+func testSequentialModel(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	seed := time.Now().UnixNano()
+	rng := rand.New(rand.NewSource(seed))
+	t.Logf("seed: %d", seed)
+
+	ids := make([]uuid.UUID, 3)
+	balances := make(map[uuid.UUID]int64, len(ids))
+	frozen := make(map[uuid.UUID]bool, len(ids))
+	for i := range ids {
+		ids[i] = createWallet(t, repo, 0)
+	}
+
+	for step := 0; step < 300; step++ {
+		id := ids[rng.Intn(len(ids))]
+		amount := int64(rng.Intn(100) + 1)
+
+		switch op := rng.Intn(10); {
+		case op < 4:
+			balance, err := repo.UpdateBalance(ctx, id, amount)
+			if frozen[id] {
+				require.ErrorIs(t, err, models.ErrWalletFrozen, "step %d", step)
+				continue
+			}
+			require.NoError(t, err, "step %d", step)
+			balances[id] += amount
+			require.Equal(t, balances[id], balance, "step %d: returned balance", step)
+		case op < 7:
+			balance, err := repo.UpdateBalance(ctx, id, -amount)
+			switch {
+			case frozen[id]:
+				require.ErrorIs(t, err, models.ErrWalletFrozen, "step %d", step)
+			case balances[id] < amount:
+				require.ErrorIs(t, err, models.ErrInsufficientFunds, "step %d", step)
+			default:
+				require.NoError(t, err, "step %d", step)
+				balances[id] -= amount
+				require.Equal(t, balances[id], balance, "step %d: returned balance", step)
+			}
+		case op < 9:
+			to := ids[(indexOf(ids, id)+1+rng.Intn(len(ids)-1))%len(ids)]
+			_, err := repo.Transfer(ctx, id, to, amount)
+			switch {
+			case frozen[id] || frozen[to]:
+				require.ErrorIs(t, err, models.ErrWalletFrozen, "step %d", step)
+			case balances[id] < amount:
+				require.ErrorIs(t, err, models.ErrInsufficientFunds, "step %d", step)
+			default:
+				require.NoError(t, err, "step %d", step)
+				balances[id] -= amount
+				balances[to] += amount
+			}
+		default:
+			status := models.StatusFrozen
+			if frozen[id] {
+				status = models.StatusActive
+			}
+			require.NoError(t, repo.SetStatus(ctx, id, status), "step %d", step)
+			frozen[id] = !frozen[id]
+		}
+
+		require.Equal(t, balances[id], balanceOf(t, repo, id), "step %d", step)
+	}
+
+	for _, id := range ids {
+		assert.Equal(t, balances[id], balanceOf(t, repo, id))
+		assert.Equal(t, balances[id], journalSum(t, repo, id))
+	}
+}
+
+func indexOf(ids []uuid.UUID, id uuid.UUID) int {
+	for i := range ids {
+		if ids[i] == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// testLinearizability выполняет случайные пополнения и списания из
+// нескольких горутин, записывая интервалы вызовов, и проверяет, что
+// результаты объяснимы каким-либо последовательным порядком, согласованным
+// с реальным временем.
+func testLinearizability(t *testing.T, repo repository.Repository) {
+	seed := time.Now().UnixNano()
+	t.Logf("seed: %d", seed)
+
+	const (
+		rounds    = 5
+		workers   = 4
+		perWorker = 4
+		initial   = 50
+		maxAmount = 40
+	)
+
+	for round := 0; round < rounds; round++ {
+		walletID := createWallet(t, repo, initial)
+		rng := rand.New(rand.NewSource(seed + int64(round)))
+		start := time.Now()
+
+		amounts := make([][]int64, workers)
+		for w := range amounts {
+			amounts[w] = make([]int64, perWorker)
+			for i := range amounts[w] {
+				amount := int64(rng.Intn(maxAmount) + 1)
+				if rng.Intn(2) == 0 {
+					amount = -amount
+				}
+				amounts[w][i] = amount
+			}
+		}
+
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			history []timedOp
+		)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(amounts []int64) {
+				defer wg.Done()
+				for _, amount := range amounts {
+					call := time.Since(start)
+					_, err := repo.UpdateBalance(context.Background(), walletID, amount)
+					ret := time.Since(start)
+
+					if err != nil && !errors.Is(err, models.ErrInsufficientFunds) {
+						t.Errorf("unexpected error: %v", err)
+						return
+					}
+
+					mu.Lock()
+					history = append(history, timedOp{amount: amount, ok: err == nil, call: call, ret: ret})
+					mu.Unlock()
+				}
+			}(amounts[w])
+		}
+		wg.Wait()
+
+		final := balanceOf(t, repo, walletID)
+		require.True(t, linearizable(initial, final, history),
+			"round %d: history is not linearizable, final balance %d: %+v", round, final, history)
+	}
+}
+
+type timedOp struct {
+	amount int64
+	ok     bool
+	call   time.Duration
+	ret    time.Duration
+}
+
+// linearizable ищет порядок операций (алгоритм Wing–Gong с мемоизацией),
+// при котором модель кошелька даёт те же результаты и итоговый баланс.
+func linearizable(initial, final int64, ops []timedOp) bool {
+	type state struct {
+		done    uint64
+		balance int64
+	}
+	full := uint64(1)<<len(ops) - 1
+	visited := make(map[state]bool)
+
+	var search func(done uint64, balance int64) bool
+	search = func(done uint64, balance int64) bool {
+		if done == full {
+			return balance == final
+		}
+		key := state{done, balance}
+		if visited[key] {
+			return false
+		}
+		visited[key] = true
+
+		// Следующей может быть только операция, вызванная раньше, чем
+		// завершилась любая из оставшихся.
+		earliestReturn := time.Duration(1<<63 - 1)
+		for i, op := range ops {
+			if done&(1<<i) == 0 && op.ret < earliestReturn {
+				earliestReturn = op.ret
+			}
+		}
+
+		for i, op := range ops {
+			if done&(1<<i) != 0 || op.call > earliestReturn {
+				continue
+			}
+			next, ok := balance+op.amount, true
+			if op.amount < 0 && next < 0 {
+				next, ok = balance, false
+			}
+			if ok == op.ok && search(done|1<<i, next) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return search(0, initial)
+}
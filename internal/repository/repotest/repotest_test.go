@@ -0,0 +1,56 @@
+package repotest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinearizable_SequentialHistory(t *testing.T) {
+	ops := []timedOp{
+		{amount: -40, ok: true, call: 0, ret: 1},
+		{amount: -40, ok: false, call: 2, ret: 3},
+		{amount: 30, ok: true, call: 4, ret: 5},
+	}
+
+	assert.True(t, linearizable(50, 40, ops))
+}
+
+func TestLinearizable_LostUpdate(t *testing.T) {
+	ops := []timedOp{
+		{amount: -40, ok: true, call: 0, ret: 1},
+		{amount: -40, ok: true, call: 2, ret: 3},
+	}
+
+	assert.False(t, linearizable(50, 10, ops))
+}
+
+func TestLinearizable_ConcurrentReordering(t *testing.T) {
+	// Списание завершилось успешно только потому, что пересекающееся по
+	// времени пополнение было применено раньше.
+	ops := []timedOp{
+		{amount: -60, ok: true, call: 0, ret: 10},
+		{amount: 20, ok: true, call: 1, ret: 5},
+	}
+
+	assert.True(t, linearizable(50, 10, ops))
+}
+
+func TestLinearizable_RealTimeOrderViolation(t *testing.T) {
+	// Пополнение началось уже после завершения списания, поэтому не могло
+	// его обеспечить.
+	ops := []timedOp{
+		{amount: -60, ok: true, call: 0, ret: 1},
+		{amount: 20, ok: true, call: 2, ret: 3},
+	}
+
+	assert.False(t, linearizable(50, 10, ops))
+}
+
+func TestLinearizable_FinalBalanceMismatch(t *testing.T) {
+	ops := []timedOp{
+		{amount: 20, ok: true, call: 0, ret: 1},
+	}
+
+	assert.False(t, linearizable(50, 60, ops))
+}
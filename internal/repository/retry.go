@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/metrics"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+)
+
+// ErrRetriesExhausted оборачивает ошибку конфликта, которая повторялась на
+// каждой из отведённых попыток.
+var ErrRetriesExhausted = errors.New("transaction conflict persisted after retries")
+
+// SQLSTATE ошибок, после которых транзакцию безопасно повторить целиком.
+const (
+	codeSerializationFailure = "40001"
+	codeDeadlockDetected     = "40P01"
+)
+
+// RetryPolicy задаёт повтор транзакций после конфликтов: не больше
+// MaxAttempts попыток, между ними — случайная пауза от нуля до
+// min(MaxDelay, BaseDelay * 2^n).
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   5 * time.Millisecond,
+	MaxDelay:    200 * time.Millisecond,
+}
+
+// WithRetryPolicy заменяет DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(r *PostgresRepository) {
+		r.retryPolicy = policy
+	}
+}
+
+// WithIsolation задаёт уровень изоляции транзакций репозитория. На
+// REPEATABLE READ и SERIALIZABLE конкурентные операции чаще завершаются
+// ошибкой 40001 и повторяются по RetryPolicy.
+func WithIsolation(level sql.IsolationLevel) Option {
+	return func(r *PostgresRepository) {
+		r.isolation = level
+	}
+}
+
+// inTx выполняет fn в транзакции и повторяет её целиком после конфликта
+// сериализации или взаимной блокировки. fn может выполниться несколько раз и
+// не должна оставлять побочных эффектов вне транзакции. Прерывание по
+// таймауту не повторяется и возвращается как ErrLockTimeout,
+// ErrStatementTimeout или ошибка контекста.
+func (r *PostgresRepository) inTx(ctx context.Context, operation string, fn func(tx *sql.Tx) error) error {
+	err := r.retry(ctx, operation, func() error {
+		tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: r.isolation})
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if r.timeouts.enabled() {
+			if _, err := tx.ExecContext(ctx, setTimeoutsQuery, r.timeouts.args()...); err != nil {
+				return err
+			}
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	return timeoutError(ctx, operation, limitError(err))
+}
+
+// retry повторяет fn по политике репозитория.
+func (r *PostgresRepository) retry(ctx context.Context, operation string, fn func() error) error {
+	return r.retryPolicy.run(ctx, operation, fn)
+}
+
+// run повторяет fn после конфликтов. Пауза не начинается, если дедлайн
+// контекста наступит раньше её окончания: тогда возвращается последняя
+// ошибка.
+func (p RetryPolicy) run(ctx context.Context, operation string, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		reason, retryable := retryReason(err)
+		if !retryable {
+			return err
+		}
+		if attempt >= p.MaxAttempts {
+			metrics.TxRetriesExhausted.WithLabelValues(operation).Inc()
+			return fmt.Errorf("%w: %w", ErrRetriesExhausted, err)
+		}
+
+		delay := p.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+
+		metrics.TxRetries.WithLabelValues(operation, reason).Inc()
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.MaxDelay
+	if shift := attempt - 1; shift < 32 && p.BaseDelay<<shift < ceiling {
+		ceiling = p.BaseDelay << shift
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// retryReason сообщает, можно ли повторить операцию после err, и причину
+// повтора для метрик.
+func retryReason(err error) (string, bool) {
+	var (
+		pqErr  *pq.Error
+		pgxErr *pgconn.PgError
+		code   string
+	)
+	switch {
+	case errors.As(err, &pqErr):
+		code = string(pqErr.Code)
+	case errors.As(err, &pgxErr):
+		code = pgxErr.Code
+	default:
+		return "", false
+	}
+	switch code {
+	case codeSerializationFailure:
+		return "serialization_failure", true
+	case codeDeadlockDetected:
+		return "deadlock", true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func retryRepo(attempts int) *PostgresRepository {
+	return &PostgresRepository{retryPolicy: RetryPolicy{
+		MaxAttempts: attempts,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    2 * time.Millisecond,
+	}}
+}
+
+func TestRetry_SucceedsAfterConflicts(t *testing.T) {
+	repo := retryRepo(5)
+
+	calls := 0
+	err := repo.retry(context.Background(), "test", func() error {
+		calls++
+		switch calls {
+		case 1:
+			return &pq.Error{Code: codeSerializationFailure}
+		case 2:
+			return &pq.Error{Code: codeDeadlockDetected}
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetry_PgxErrors(t *testing.T) {
+	repo := retryRepo(5)
+
+	calls := 0
+	err := repo.retry(context.Background(), "test", func() error {
+		calls++
+		if calls == 1 {
+			return fmt.Errorf("commit: %w", &pgconn.PgError{Code: codeSerializationFailure})
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	reason, retryable := retryReason(&pgconn.PgError{Code: codeDeadlockDetected})
+	assert.True(t, retryable)
+	assert.Equal(t, "deadlock", reason)
+}
+
+func TestRetry_Exhausted(t *testing.T) {
+	repo := retryRepo(3)
+
+	calls := 0
+	err := repo.retry(context.Background(), "test", func() error {
+		calls++
+		return &pq.Error{Code: codeSerializationFailure}
+	})
+
+	assert.ErrorIs(t, err, ErrRetriesExhausted)
+	var pqErr *pq.Error
+	assert.True(t, errors.As(err, &pqErr))
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetry_NonRetryableError(t *testing.T) {
+	repo := retryRepo(5)
+
+	for _, want := range []error{
+		ErrWalletNotFound,
+		&pq.Error{Code: "23514"},
+	} {
+		calls := 0
+		err := repo.retry(context.Background(), "test", func() error {
+			calls++
+			return want
+		})
+		assert.Equal(t, want, err)
+		assert.Equal(t, 1, calls)
+	}
+}
+
+func TestRetry_StopsBeforeDeadline(t *testing.T) {
+	repo := &PostgresRepository{retryPolicy: RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   time.Second,
+		MaxDelay:    time.Second,
+	}}
+	// Пауза случайна от нуля до секунды; проверяем, что за 10ms дедлайна
+	// повтор либо успевает, либо не начинается вовсе.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	conflict := &pq.Error{Code: codeSerializationFailure}
+	start := time.Now()
+	err := repo.retry(ctx, "test", func() error { return conflict })
+
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrRetriesExhausted)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestRetry_ContextCanceled(t *testing.T) {
+	repo := &PostgresRepository{retryPolicy: RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   time.Hour,
+		MaxDelay:    time.Hour,
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	err := repo.retry(ctx, "test", func() error {
+		calls++
+		if calls == 1 {
+			time.AfterFunc(5*time.Millisecond, cancel)
+		}
+		return &pq.Error{Code: codeDeadlockDetected}
+	})
+
+	// Пауза до часа может оказаться нулевой, поэтому допускаем лишнюю попытку.
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.LessOrEqual(t, calls, 5)
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	for attempt, ceiling := range map[int]time.Duration{
+		1:  10 * time.Millisecond,
+		2:  20 * time.Millisecond,
+		3:  40 * time.Millisecond,
+		4:  50 * time.Millisecond,
+		40: 50 * time.Millisecond,
+	} {
+		for i := 0; i < 100; i++ {
+			delay := policy.backoff(attempt)
+			assert.GreaterOrEqual(t, delay, time.Duration(0))
+			assert.LessOrEqual(t, delay, ceiling)
+		}
+	}
+
+	assert.Zero(t, RetryPolicy{}.backoff(1))
+}
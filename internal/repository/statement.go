@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/metrics"
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// StatementSink получает выписку по мере чтения из хранилища: сначала
+// кошелёк и остаток на начало периода, затем операции периода по порядку.
+// Ошибка sink прерывает чтение и возвращается из StreamStatement.
+type StatementSink interface {
+	Opening(wallet *models.Wallet, balance int64) error
+	Operation(op *models.Operation) error
+}
+
+const statementOperationsQuery = `SELECT id, wallet_id, operation_type, amount, transfer_id, created_at
+	FROM wallet_operations
+	WHERE wallet_id = $1 AND created_at > $2 AND created_at <= $3
+	ORDER BY created_at, id`
+
+// scanOperation читает операцию журнала из строки statementOperationsQuery.
+func scanOperation(row rowScanner) (models.Operation, error) {
+	var (
+		op         models.Operation
+		transferID uuid.NullUUID
+	)
+	if err := row.Scan(&op.ID, &op.WalletID, &op.Type, &op.Amount, &transferID, &op.CreatedAt); err != nil {
+		return op, err
+	}
+	if transferID.Valid {
+		op.TransferID = &transferID.UUID
+	}
+	return op, nil
+}
+
+// StreamStatement читает выписку на реплике, если она достаточно свежая.
+// Повтор на основной базе возможен только для кошелька, которого на реплике
+// ещё нет: после первой записи в sink повторять чтение уже нельзя.
+func (r *PostgresRepository) StreamStatement(ctx context.Context, walletID uuid.UUID, from, to time.Time, sink StatementSink) error {
+	if db := r.readDB(ctx); db != nil {
+		err := streamStatement(ctx, db, walletID, from, to, sink)
+		if !errors.Is(err, ErrWalletNotFound) {
+			metrics.ReplicaReads.WithLabelValues("replica").Inc()
+			return err
+		}
+		metrics.ReplicaReads.WithLabelValues("fallback").Inc()
+	} else {
+		metrics.ReplicaReads.WithLabelValues("primary").Inc()
+	}
+	return streamStatement(ctx, r.db, walletID, from, to, sink)
+}
+
+// streamStatement читает кошелёк, остаток и операции в одной транзакции
+// REPEATABLE READ, чтобы остатки сходились с операциями между ними.
+// Строки операций передаются в sink по одной, не накапливаясь в памяти.
+func streamStatement(ctx context.Context, db *sql.DB, walletID uuid.UUID, from, to time.Time, sink StatementSink) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	wallet := models.Wallet{ID: walletID}
+	err = tx.QueryRowContext(
+		ctx,
+		"SELECT "+balanceExpr+", w.status, "+versionExpr+", w.currency, w.owner_id, w.created_at FROM wallets w WHERE w.id = $1",
+		walletID,
+	).Scan(&wallet.Balance, &wallet.Status, &wallet.Version, &wallet.Currency, &wallet.OwnerID, &wallet.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrWalletNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var opening int64
+	if err := tx.QueryRowContext(ctx, balanceAsOfQuery, walletID, from).Scan(&opening); err != nil {
+		return err
+	}
+	if err := sink.Opening(&wallet, opening); err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, statementOperationsQuery, walletID, from, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		op, err := scanOperation(rows)
+		if err != nil {
+			return err
+		}
+		if err := sink.Operation(&op); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
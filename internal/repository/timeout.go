@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/metrics"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrLockTimeout означает, что транзакция не дождалась блокировки
+	// кошелька за Timeouts.Lock: кошелёк занят другими операциями.
+	ErrLockTimeout = errors.New("wallet lock wait timed out")
+
+	// ErrStatementTimeout означает, что запрос выполнялся дольше
+	// Timeouts.Statement и был прерван базой.
+	ErrStatementTimeout = errors.New("statement timed out")
+)
+
+// SQLSTATE ошибок, которыми PostgreSQL прерывает запрос по таймауту.
+const (
+	codeLockNotAvailable = "55P03"
+	codeQueryCanceled    = "57014"
+)
+
+// setTimeoutsQuery задаёт lock_timeout и statement_timeout до конца текущей
+// транзакции.
+const setTimeoutsQuery = "SELECT set_config('lock_timeout', $1, true), set_config('statement_timeout', $2, true)"
+
+// Timeouts ограничивают ожидание внутри транзакции: Lock — ожидание любой
+// блокировки, Statement — выполнение одного запроса. Ноль снимает
+// ограничение.
+type Timeouts struct {
+	Lock      time.Duration
+	Statement time.Duration
+}
+
+func (t Timeouts) enabled() bool {
+	return t.Lock > 0 || t.Statement > 0
+}
+
+// args возвращает параметры setTimeoutsQuery.
+func (t Timeouts) args() []any {
+	return []any{
+		strconv.FormatInt(t.Lock.Milliseconds(), 10),
+		strconv.FormatInt(t.Statement.Milliseconds(), 10),
+	}
+}
+
+// WithTimeouts задаёт lock_timeout и statement_timeout для каждой транзакции
+// репозитория, включая условное обновление (WithConditionalUpdate), чтобы
+// операция над занятым кошельком завершалась ErrLockTimeout, а не ждала
+// блокировку до дедлайна запроса.
+func WithTimeouts(timeouts Timeouts) Option {
+	return func(r *PostgresRepository) {
+		r.timeouts = timeouts
+	}
+}
+
+// WithPgxTimeouts — аналог WithTimeouts для PgxRepository.
+func WithPgxTimeouts(timeouts Timeouts) PgxOption {
+	return func(r *PgxRepository) {
+		r.timeouts = timeouts
+	}
+}
+
+// timeoutError переводит прерывание запроса по таймауту в ErrLockTimeout,
+// ErrStatementTimeout или ошибку контекста, если запрос отменён из-за
+// дедлайна ctx. Остальные ошибки возвращаются без изменений.
+func timeoutError(ctx context.Context, operation string, err error) error {
+	var (
+		pqErr  *pq.Error
+		pgxErr *pgconn.PgError
+		code   string
+	)
+	switch {
+	case errors.As(err, &pqErr):
+		code = string(pqErr.Code)
+	case errors.As(err, &pgxErr):
+		code = pgxErr.Code
+	default:
+		return err
+	}
+
+	switch {
+	case code == codeLockNotAvailable:
+		metrics.TxTimeouts.WithLabelValues(operation, "lock").Inc()
+		return fmt.Errorf("%w: %w", ErrLockTimeout, err)
+	case code == codeQueryCanceled && ctx.Err() != nil:
+		metrics.TxTimeouts.WithLabelValues(operation, "deadline").Inc()
+		return fmt.Errorf("%w: %w", ctx.Err(), err)
+	case code == codeQueryCanceled:
+		metrics.TxTimeouts.WithLabelValues(operation, "statement").Inc()
+		return fmt.Errorf("%w: %w", ErrStatementTimeout, err)
+	default:
+		return err
+	}
+}
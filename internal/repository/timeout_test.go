@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutError(t *testing.T) {
+	ctx := context.Background()
+
+	err := timeoutError(ctx, "test", fmt.Errorf("lock: %w", &pq.Error{Code: codeLockNotAvailable}))
+	assert.ErrorIs(t, err, ErrLockTimeout)
+
+	err = timeoutError(ctx, "test", &pgconn.PgError{Code: codeLockNotAvailable})
+	assert.ErrorIs(t, err, ErrLockTimeout)
+
+	err = timeoutError(ctx, "test", &pq.Error{Code: codeQueryCanceled})
+	assert.ErrorIs(t, err, ErrStatementTimeout)
+
+	// Отмена запроса по дедлайну контекста — не statement_timeout.
+	expired, cancel := context.WithTimeout(ctx, -time.Second)
+	defer cancel()
+	err = timeoutError(expired, "test", &pgconn.PgError{Code: codeQueryCanceled})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, ErrStatementTimeout)
+
+	other := errors.New("boom")
+	assert.Equal(t, other, timeoutError(ctx, "test", other))
+	assert.Nil(t, timeoutError(ctx, "test", nil))
+
+	serialization := &pq.Error{Code: codeSerializationFailure}
+	assert.Equal(t, error(serialization), timeoutError(ctx, "test", serialization))
+}
+
+func TestTimeouts_Args(t *testing.T) {
+	timeouts := Timeouts{Lock: 1500 * time.Millisecond}
+
+	assert.True(t, timeouts.enabled())
+	assert.Equal(t, []any{"1500", "0"}, timeouts.args())
+	assert.False(t, Timeouts{}.enabled())
+}
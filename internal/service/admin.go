@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/DisasterWoman/wallet-service/internal/repository"
+	"github.com/google/uuid"
+)
+
+type adminService struct {
+	repo repository.WalletAdmin
+}
+
+func NewAdminService(repo repository.WalletAdmin) AdminService {
+	return &adminService{repo: repo}
+}
+
+// SetWalletMetadata проверяет и заменяет метки и метаданные кошелька.
+func (s *adminService) SetWalletMetadata(ctx context.Context, walletID uuid.UUID, req *models.WalletMetadataRequest) (*models.Wallet, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return s.repo.SetWalletMetadata(ctx, walletID, req.Labels, req.Metadata)
+}
+
+// SearchWallets ищет кошельки по фильтру. Limit вне допустимых пределов
+// заменяется значением по умолчанию или максимумом.
+func (s *adminService) SearchWallets(ctx context.Context, filter models.WalletFilter) (*models.WalletPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = models.DefaultSearchLimit
+	}
+	if filter.Limit > models.MaxSearchLimit {
+		filter.Limit = models.MaxSearchLimit
+	}
+	return s.repo.SearchWallets(ctx, filter)
+}
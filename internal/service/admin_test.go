@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/DisasterWoman/wallet-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminService_SearchWallets_ClampsLimit(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewAdminService(repo)
+	ctx := context.Background()
+
+	ownerID := uuid.New()
+	_, err := repo.CreateOwner(ctx, ownerID, "")
+	require.NoError(t, err)
+	for i := 0; i < models.DefaultSearchLimit+1; i++ {
+		_, err := repo.CreateWallet(ctx, uuid.New(), ownerID)
+		require.NoError(t, err)
+	}
+
+	page, err := service.SearchWallets(ctx, models.WalletFilter{})
+	require.NoError(t, err)
+	assert.Len(t, page.Wallets, models.DefaultSearchLimit)
+	assert.NotEmpty(t, page.NextCursor)
+
+	page, err = service.SearchWallets(ctx, models.WalletFilter{Limit: models.MaxSearchLimit + 1})
+	require.NoError(t, err)
+	assert.Len(t, page.Wallets, models.DefaultSearchLimit+1)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestAdminService_SetWalletMetadata_Validates(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewAdminService(repo)
+
+	_, err := service.SetWalletMetadata(context.Background(), uuid.New(), &models.WalletMetadataRequest{
+		Metadata: json.RawMessage(`"not an object"`),
+	})
+	assert.ErrorIs(t, err, models.ErrInvalidMetadata)
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/DisasterWoman/wallet-service/internal/repository"
+)
+
+type bulkService struct {
+	repo repository.WalletBulk
+}
+
+func NewBulkService(repo repository.WalletBulk) BulkService {
+	return &bulkService{repo: repo}
+}
+
+// ImportWallets загружает кошельки source. Если в файле есть неверные
+// записи или дубликаты, вместе с отчётом возвращается
+// models.ErrImportRejected: ничего не записано.
+func (s *bulkService) ImportWallets(ctx context.Context, source repository.WalletSource, dryRun bool) (*models.ImportReport, error) {
+	report, err := s.repo.ImportWallets(ctx, source, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	if report.ErrorCount > 0 {
+		return report, models.ErrImportRejected
+	}
+	return report, nil
+}
+
+// ExportWallets передаёт sink все кошельки в порядке создания.
+func (s *bulkService) ExportWallets(ctx context.Context, sink repository.WalletSink) error {
+	return s.repo.ExportWallets(ctx, sink)
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DisasterWoman/wallet-service/internal/bulk"
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/DisasterWoman/wallet-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkService_ImportWallets_RejectsErrors(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewBulkService(repo)
+	ctx := context.Background()
+
+	walletID, ownerID := uuid.New(), uuid.New()
+	file := "wallet_id,owner_id,currency,balance\n" +
+		walletID.String() + "," + ownerID.String() + ",RUB,10.00\n" +
+		walletID.String() + "," + ownerID.String() + ",RUB,5.00\n"
+	reader, err := bulk.NewReader(strings.NewReader(file), models.BulkCSV)
+	require.NoError(t, err)
+
+	report, err := service.ImportWallets(ctx, reader, false)
+	assert.ErrorIs(t, err, models.ErrImportRejected)
+	require.NotNil(t, report)
+	assert.False(t, report.Committed)
+	require.Len(t, report.Errors, 1)
+	assert.Equal(t, models.CodeDuplicateWallet, report.Errors[0].Code)
+
+	_, err = repo.GetWallet(ctx, walletID)
+	assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+}
+
+func TestBulkService_ImportWallets_Commits(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewBulkService(repo)
+	ctx := context.Background()
+
+	walletID := uuid.New()
+	file := `{"walletId":"` + walletID.String() + `","ownerId":"` + uuid.NewString() + `","currency":"USD","balance":"7.25"}` + "\n"
+	reader, err := bulk.NewReader(strings.NewReader(file), models.BulkJSONL)
+	require.NoError(t, err)
+
+	report, err := service.ImportWallets(ctx, reader, false)
+	require.NoError(t, err)
+	assert.True(t, report.Committed)
+	assert.True(t, report.Balanced)
+
+	wallet, err := repo.GetWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(725), wallet.Balance)
+}
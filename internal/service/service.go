@@ -2,11 +2,38 @@ package service
 
 import (
 	"context"
+	"time"
+
 	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/DisasterWoman/wallet-service/internal/repository"
 	"github.com/google/uuid"
 )
 
 type WalletService interface {
-	UpdateBalance(ctx context.Context, req *models.OperationRequest) error
+	CreateOwner(ctx context.Context, ownerID uuid.UUID, name string) (*models.Owner, error)
+	CreateWallet(ctx context.Context, walletID, ownerID uuid.UUID) (*models.Wallet, error)
+	GetWallet(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error)
+	UpdateBalance(ctx context.Context, req *models.OperationRequest) (int64, error)
+	UpdateBalanceIfVersion(ctx context.Context, req *models.OperationRequest, version int64) (*models.Wallet, error)
 	GetBalance(ctx context.Context, walletID uuid.UUID) (int64, error)
-}
\ No newline at end of file
+	Transfer(ctx context.Context, req *models.TransferRequest) (uuid.UUID, error)
+	Freeze(ctx context.Context, walletID uuid.UUID) error
+	Unfreeze(ctx context.Context, walletID uuid.UUID) error
+	History(ctx context.Context, walletID uuid.UUID, limit int) ([]models.Operation, error)
+	OwnerWallets(ctx context.Context, ownerID uuid.UUID) (*models.OwnerWallets, error)
+	BalanceAsOf(ctx context.Context, walletID uuid.UUID, asOf time.Time) (*models.BalanceResponse, error)
+	Statement(ctx context.Context, req *models.StatementRequest, sink repository.StatementSink) error
+}
+
+// AdminService — административные операции: метки, метаданные и поиск
+// кошельков.
+type AdminService interface {
+	SetWalletMetadata(ctx context.Context, walletID uuid.UUID, req *models.WalletMetadataRequest) (*models.Wallet, error)
+	SearchWallets(ctx context.Context, filter models.WalletFilter) (*models.WalletPage, error)
+}
+
+// BulkService — массовые импорт и экспорт кошельков.
+type BulkService interface {
+	ImportWallets(ctx context.Context, source repository.WalletSource, dryRun bool) (*models.ImportReport, error)
+	ExportWallets(ctx context.Context, sink repository.WalletSink) error
+}
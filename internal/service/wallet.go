@@ -2,11 +2,18 @@ package service
 
 import (
 	"context"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/DisasterWoman/wallet-service/internal/models"
 	"github.com/DisasterWoman/wallet-service/internal/repository"
 )
 
+const (
+	DefaultHistoryLimit = 50
+	MaxHistoryLimit     = 1000
+)
+
 type walletService struct {
 	repo repository.Repository
 }
@@ -15,19 +22,145 @@ func NewWalletService(repo repository.Repository) WalletService {
 	return &walletService{repo: repo}
 }
 
-func (s *walletService) UpdateBalance(ctx context.Context, req *models.OperationRequest) error {
-	if err := req.Validate(); err != nil {
-		return err
+// CreateOwner создаёт владельца кошельков. Если ownerID не задан,
+// идентификатор генерируется.
+func (s *walletService) CreateOwner(ctx context.Context, ownerID uuid.UUID, name string) (*models.Owner, error) {
+	if ownerID == uuid.Nil {
+		ownerID = uuid.New()
 	}
+	return s.repo.CreateOwner(ctx, ownerID, name)
+}
 
-	amount := req.Amount
-	if req.OperationType == models.Withdraw {
-		amount = -amount
+// CreateWallet создаёт кошелёк владельца ownerID с нулевым балансом. Если
+// walletID не задан, идентификатор генерируется.
+func (s *walletService) CreateWallet(ctx context.Context, walletID, ownerID uuid.UUID) (*models.Wallet, error) {
+	if ownerID == uuid.Nil {
+		return nil, models.ErrOwnerIDRequired
+	}
+	if walletID == uuid.Nil {
+		walletID = uuid.New()
+	}
+	return s.repo.CreateWallet(ctx, walletID, ownerID)
+}
+
+func (s *walletService) GetWallet(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error) {
+	return s.repo.GetWallet(ctx, walletID)
+}
+
+// UpdateBalance выполняет операцию и возвращает баланс кошелька после неё.
+func (s *walletService) UpdateBalance(ctx context.Context, req *models.OperationRequest) (int64, error) {
+	if err := req.Validate(); err != nil {
+		return 0, err
+	}
+	if err := s.resolveAmount(ctx, req); err != nil {
+		return 0, err
 	}
 
-	return s.repo.UpdateBalance(ctx, req.WalletID, amount)
+	return s.repo.UpdateBalance(ctx, req.WalletID, signedAmount(req))
+}
+
+// UpdateBalanceIfVersion выполняет операцию, только если версия кошелька не
+// изменилась с момента, когда клиент его прочитал.
+func (s *walletService) UpdateBalanceIfVersion(ctx context.Context, req *models.OperationRequest, version int64) (*models.Wallet, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.resolveAmount(ctx, req); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateBalanceIfVersion(ctx, req.WalletID, signedAmount(req), version)
 }
 
 func (s *walletService) GetBalance(ctx context.Context, walletID uuid.UUID) (int64, error) {
 	return s.repo.GetBalance(ctx, walletID)
-}
\ No newline at end of file
+}
+
+func (s *walletService) Transfer(ctx context.Context, req *models.TransferRequest) (uuid.UUID, error) {
+	if err := req.Validate(); err != nil {
+		return uuid.Nil, err
+	}
+	return s.repo.Transfer(ctx, req.FromWalletID, req.ToWalletID, req.Amount)
+}
+
+func (s *walletService) Freeze(ctx context.Context, walletID uuid.UUID) error {
+	return s.repo.SetStatus(ctx, walletID, models.StatusFrozen)
+}
+
+func (s *walletService) Unfreeze(ctx context.Context, walletID uuid.UUID) error {
+	return s.repo.SetStatus(ctx, walletID, models.StatusActive)
+}
+
+func (s *walletService) History(ctx context.Context, walletID uuid.UUID, limit int) ([]models.Operation, error) {
+	if limit == 0 {
+		limit = DefaultHistoryLimit
+	}
+	if limit < 0 || limit > MaxHistoryLimit {
+		return nil, models.ErrInvalidLimit
+	}
+	return s.repo.History(ctx, walletID, limit)
+}
+
+// OwnerWallets возвращает кошельки владельца и их суммарные балансы по
+// валютам.
+func (s *walletService) OwnerWallets(ctx context.Context, ownerID uuid.UUID) (*models.OwnerWallets, error) {
+	wallets, err := s.repo.ListOwnerWallets(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	result := models.NewOwnerWallets(ownerID, wallets)
+	return &result, nil
+}
+
+// BalanceAsOf возвращает баланс кошелька на момент asOf. Будущий момент
+// отклоняется: его баланс ещё может измениться. Валюта в ответе — текущая
+// валюта кошелька; сменить её можно только у пустого кошелька, так что
+// ненулевой исторический баланс мог быть и в другой валюте.
+func (s *walletService) BalanceAsOf(ctx context.Context, walletID uuid.UUID, asOf time.Time) (*models.BalanceResponse, error) {
+	if asOf.IsZero() || asOf.After(time.Now()) {
+		return nil, models.ErrInvalidAsOf
+	}
+
+	wallet, err := s.repo.GetWallet(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := s.repo.BalanceAsOf(ctx, walletID, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	response := models.NewBalanceResponse(&models.Wallet{Balance: balance, Currency: wallet.Currency})
+	asOf = asOf.UTC()
+	response.AsOf = &asOf
+	return &response, nil
+}
+
+// Statement проверяет запрос и передаёт выписку по кошельку в sink по мере
+// чтения из хранилища. Если кошелька нет, sink не вызывается.
+func (s *walletService) Statement(ctx context.Context, req *models.StatementRequest, sink repository.StatementSink) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+	return s.repo.StreamStatement(ctx, req.WalletID, req.From, req.To, sink)
+}
+
+// resolveAmount переводит десятичную сумму запроса в минимальные единицы
+// по валюте кошелька. Валюта читается с основной базы: у пустого кошелька
+// её могли только что сменить, а кэш и реплика этого ещё не видят.
+func (s *walletService) resolveAmount(ctx context.Context, req *models.OperationRequest) error {
+	if !req.DecimalAmount() {
+		return nil
+	}
+	wallet, err := s.repo.GetWallet(repository.WithStrongRead(ctx), req.WalletID)
+	if err != nil {
+		return err
+	}
+	return req.ResolveAmount(wallet.Currency)
+}
+
+func signedAmount(req *models.OperationRequest) int64 {
+	if req.OperationType == models.Withdraw {
+		return -req.Amount
+	}
+	return req.Amount
+}
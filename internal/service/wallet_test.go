@@ -2,7 +2,10 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"math"
 	"testing"
+	"time"
 
 	"github.com/DisasterWoman/wallet-service/internal/models"
 	"github.com/DisasterWoman/wallet-service/internal/repository"
@@ -15,16 +18,71 @@ type MockRepository struct {
 	mock.Mock
 }
 
+func (m *MockRepository) CreateOwner(ctx context.Context, ownerID uuid.UUID, name string) (*models.Owner, error) {
+	args := m.Called(ctx, ownerID, name)
+	owner, _ := args.Get(0).(*models.Owner)
+	return owner, args.Error(1)
+}
+
+func (m *MockRepository) BalanceAsOf(ctx context.Context, walletID uuid.UUID, asOf time.Time) (int64, error) {
+	args := m.Called(ctx, walletID, asOf)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) StreamStatement(ctx context.Context, walletID uuid.UUID, from, to time.Time, sink repository.StatementSink) error {
+	return m.Called(ctx, walletID, from, to, sink).Error(0)
+}
+
+func (m *MockRepository) CreateWallet(ctx context.Context, walletID, ownerID uuid.UUID) (*models.Wallet, error) {
+	args := m.Called(ctx, walletID, ownerID)
+	wallet, _ := args.Get(0).(*models.Wallet)
+	return wallet, args.Error(1)
+}
+
+func (m *MockRepository) ListOwnerWallets(ctx context.Context, ownerID uuid.UUID) ([]models.Wallet, error) {
+	args := m.Called(ctx, ownerID)
+	wallets, _ := args.Get(0).([]models.Wallet)
+	return wallets, args.Error(1)
+}
+
+func (m *MockRepository) GetWallet(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error) {
+	args := m.Called(ctx, walletID)
+	wallet, _ := args.Get(0).(*models.Wallet)
+	return wallet, args.Error(1)
+}
+
 func (m *MockRepository) GetBalance(ctx context.Context, walletID uuid.UUID) (int64, error) {
 	args := m.Called(ctx, walletID)
 	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *MockRepository) UpdateBalance(ctx context.Context, walletID uuid.UUID, amount int64) error {
+func (m *MockRepository) UpdateBalance(ctx context.Context, walletID uuid.UUID, amount int64) (int64, error) {
 	args := m.Called(ctx, walletID, amount)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) UpdateBalanceIfVersion(ctx context.Context, walletID uuid.UUID, amount, version int64) (*models.Wallet, error) {
+	args := m.Called(ctx, walletID, amount, version)
+	wallet, _ := args.Get(0).(*models.Wallet)
+	return wallet, args.Error(1)
+}
+
+func (m *MockRepository) Transfer(ctx context.Context, fromID, toID uuid.UUID, amount int64) (uuid.UUID, error) {
+	args := m.Called(ctx, fromID, toID, amount)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockRepository) SetStatus(ctx context.Context, walletID uuid.UUID, status models.WalletStatus) error {
+	args := m.Called(ctx, walletID, status)
 	return args.Error(0)
 }
 
+func (m *MockRepository) History(ctx context.Context, walletID uuid.UUID, limit int) ([]models.Operation, error) {
+	args := m.Called(ctx, walletID, limit)
+	operations, _ := args.Get(0).([]models.Operation)
+	return operations, args.Error(1)
+}
+
 func TestWalletService_UpdateBalance_Deposit(t *testing.T) {
 	mockRepo := new(MockRepository)
 	service := NewWalletService(mockRepo)
@@ -36,11 +94,12 @@ func TestWalletService_UpdateBalance_Deposit(t *testing.T) {
 		Amount:        1000,
 	}
 
-	mockRepo.On("UpdateBalance", mock.Anything, walletID, int64(1000)).Return(nil)
+	mockRepo.On("UpdateBalance", mock.Anything, walletID, int64(1000)).Return(int64(1500), nil)
 
-	err := service.UpdateBalance(context.Background(), req)
+	balance, err := service.UpdateBalance(context.Background(), req)
 
 	assert.NoError(t, err)
+	assert.Equal(t, int64(1500), balance)
 	mockRepo.AssertExpectations(t)
 }
 
@@ -55,11 +114,71 @@ func TestWalletService_UpdateBalance_Withdraw(t *testing.T) {
 		Amount:        500,
 	}
 
-	mockRepo.On("UpdateBalance", mock.Anything, walletID, int64(-500)).Return(nil)
+	mockRepo.On("UpdateBalance", mock.Anything, walletID, int64(-500)).Return(int64(500), nil)
+
+	balance, err := service.UpdateBalance(context.Background(), req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(500), balance)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWalletService_UpdateBalance_DecimalAmount(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewWalletService(mockRepo)
+
+	walletID := uuid.New()
+	var req models.OperationRequest
+	err := json.Unmarshal([]byte(`{"walletId":"`+walletID.String()+`","operationType":"WITHDRAW","amount":"12.50"}`), &req)
+	assert.NoError(t, err)
+
+	strong := mock.MatchedBy(func(ctx context.Context) bool { return repository.IsStrongRead(ctx) })
+	mockRepo.On("GetWallet", strong, walletID).Return(&models.Wallet{ID: walletID, Currency: "USD"}, nil)
+	mockRepo.On("UpdateBalance", mock.Anything, walletID, int64(-1250)).Return(int64(750), nil)
+
+	balance, err := service.UpdateBalance(context.Background(), &req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(750), balance)
+	assert.Equal(t, models.Currency("USD"), req.Currency())
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWalletService_UpdateBalance_DecimalAmountPrecision(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewWalletService(mockRepo)
+
+	walletID := uuid.New()
+	var req models.OperationRequest
+	err := json.Unmarshal([]byte(`{"walletId":"`+walletID.String()+`","operationType":"DEPOSIT","amount":"12.50"}`), &req)
+	assert.NoError(t, err)
+
+	mockRepo.On("GetWallet", mock.Anything, walletID).Return(&models.Wallet{ID: walletID, Currency: "JPY"}, nil)
+
+	_, err = service.UpdateBalance(context.Background(), &req)
+
+	assert.ErrorIs(t, err, models.ErrAmountPrecision)
+	mockRepo.AssertNotCalled(t, "UpdateBalance")
+}
+
+func TestWalletService_UpdateBalanceIfVersion_Withdraw(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewWalletService(mockRepo)
+
+	walletID := uuid.New()
+	req := &models.OperationRequest{
+		WalletID:      walletID,
+		OperationType: models.Withdraw,
+		Amount:        500,
+	}
+	expected := &models.Wallet{ID: walletID, Balance: 500, Version: 4}
+
+	mockRepo.On("UpdateBalanceIfVersion", mock.Anything, walletID, int64(-500), int64(3)).Return(expected, nil)
 
-	err := service.UpdateBalance(context.Background(), req)
+	wallet, err := service.UpdateBalanceIfVersion(context.Background(), req, 3)
 
 	assert.NoError(t, err)
+	assert.Equal(t, expected, wallet)
 	mockRepo.AssertExpectations(t)
 }
 
@@ -74,13 +193,70 @@ func TestWalletService_UpdateBalance_InvalidAmount(t *testing.T) {
 		Amount:        -100, // Невалидная сумма
 	}
 
-	err := service.UpdateBalance(context.Background(), req)
+	_, err := service.UpdateBalance(context.Background(), req)
 
 	assert.Error(t, err)
-	assert.Equal(t, models.ErrInvalidAmount, err)
+	assert.ErrorIs(t, err, models.ErrInvalidAmount)
+	mockRepo.AssertNotCalled(t, "UpdateBalance")
+}
+
+func TestWalletService_UpdateBalance_InvalidOperationType(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewWalletService(mockRepo)
+
+	req := &models.OperationRequest{
+		WalletID:      uuid.New(),
+		OperationType: "REFUND",
+		Amount:        100,
+	}
+
+	_, err := service.UpdateBalance(context.Background(), req)
+
+	assert.ErrorIs(t, err, models.ErrInvalidOperationType)
+	mockRepo.AssertNotCalled(t, "UpdateBalance")
+}
+
+func TestWalletService_UpdateBalance_ValidatesAllFields(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewWalletService(mockRepo)
+
+	req := &models.OperationRequest{
+		OperationType: "FOO",
+		Amount:        models.MaxAmount + 1,
+	}
+
+	_, err := service.UpdateBalance(context.Background(), req)
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []models.FieldError{
+		{Field: "walletId", Code: models.CodeInvalidWalletID, Message: models.ErrWalletIDRequired.Message},
+		{Field: "operationType", Code: models.CodeInvalidOperationType, Message: models.ErrInvalidOperationType.Message},
+		{Field: "amount", Code: models.CodeAmountTooLarge, Message: models.ErrAmountTooLarge.Message},
+	}, validationErr.Fields)
+	assert.Equal(t, models.CodeValidationFailed, validationErr.Code())
+	assert.ErrorIs(t, err, models.ErrAmountTooLarge)
 	mockRepo.AssertNotCalled(t, "UpdateBalance")
 }
 
+func TestWalletService_UpdateBalance_MaxAmount(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewWalletService(mockRepo)
+
+	walletID := uuid.New()
+	req := &models.OperationRequest{
+		WalletID:      walletID,
+		OperationType: models.Deposit,
+		Amount:        models.MaxAmount,
+	}
+	mockRepo.On("UpdateBalance", mock.Anything, walletID, models.MaxAmount).Return(models.MaxAmount, nil)
+
+	_, err := service.UpdateBalance(context.Background(), req)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestWalletService_GetBalance(t *testing.T) {
 	mockRepo := new(MockRepository)
 	service := NewWalletService(mockRepo)
@@ -111,4 +287,187 @@ func TestWalletService_GetBalance_Error(t *testing.T) {
 	assert.Equal(t, repository.ErrWalletNotFound, err)
 	assert.Equal(t, int64(0), balance)
 	mockRepo.AssertExpectations(t)
-}
\ No newline at end of file
+}
+
+func TestWalletService_CreateWallet_GeneratesID(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewWalletService(mockRepo)
+
+	ownerID := uuid.New()
+	mockRepo.On("CreateWallet", mock.Anything, mock.MatchedBy(func(id uuid.UUID) bool {
+		return id != uuid.Nil
+	}), ownerID).Return(&models.Wallet{Status: models.StatusActive}, nil)
+
+	wallet, err := service.CreateWallet(context.Background(), uuid.Nil, ownerID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusActive, wallet.Status)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWalletService_CreateWallet_RequiresOwner(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewWalletService(mockRepo)
+
+	_, err := service.CreateWallet(context.Background(), uuid.New(), uuid.Nil)
+
+	assert.ErrorIs(t, err, models.ErrOwnerIDRequired)
+	mockRepo.AssertNotCalled(t, "CreateWallet")
+}
+
+func TestWalletService_OwnerWallets(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewWalletService(mockRepo)
+
+	ownerID := uuid.New()
+	mockRepo.On("ListOwnerWallets", mock.Anything, ownerID).Return([]models.Wallet{
+		{ID: uuid.New(), Balance: math.MaxInt64, Currency: "RUB"},
+		{ID: uuid.New(), Balance: 1, Currency: "RUB"},
+		{ID: uuid.New(), Balance: 1500, Currency: "JPY"},
+	}, nil)
+
+	result, err := service.OwnerWallets(context.Background(), ownerID)
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Wallets, 3)
+	if assert.Len(t, result.Totals, 2) {
+		assert.Equal(t, models.Currency("JPY"), result.Totals[0].Currency)
+		assert.Equal(t, "1500", result.Totals[0].BalanceDecimal)
+		assert.Equal(t, models.Currency("RUB"), result.Totals[1].Currency)
+		assert.Equal(t, 2, result.Totals[1].Wallets)
+		assert.Equal(t, "9223372036854775808", result.Totals[1].Balance.String())
+		assert.Equal(t, "92233720368547758.08", result.Totals[1].BalanceDecimal)
+	}
+}
+
+func TestWalletService_BalanceAsOf(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewWalletService(mockRepo)
+
+	walletID := uuid.New()
+	asOf := time.Date(2026, 9, 30, 23, 59, 59, 0, time.FixedZone("MSK", 3*3600))
+	mockRepo.On("GetWallet", mock.Anything, walletID).Return(&models.Wallet{ID: walletID, Balance: 9999, Currency: "USD"}, nil)
+	mockRepo.On("BalanceAsOf", mock.Anything, walletID, asOf).Return(int64(1250), nil)
+
+	result, err := service.BalanceAsOf(context.Background(), walletID, asOf)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1250), result.Balance)
+	assert.Equal(t, "12.50", result.BalanceDecimal)
+	assert.Equal(t, models.Currency("USD"), result.Currency)
+	if assert.NotNil(t, result.AsOf) {
+		assert.Equal(t, asOf.UTC(), *result.AsOf)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWalletService_BalanceAsOf_Future(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewWalletService(mockRepo)
+
+	_, err := service.BalanceAsOf(context.Background(), uuid.New(), time.Now().Add(time.Minute))
+
+	assert.ErrorIs(t, err, models.ErrInvalidAsOf)
+	mockRepo.AssertNotCalled(t, "BalanceAsOf", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWalletService_Statement(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewWalletService(mockRepo)
+
+	walletID := uuid.New()
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	var sink repository.StatementSink
+	mockRepo.On("StreamStatement", mock.Anything, walletID, from, to, sink).Return(nil)
+
+	err := service.Statement(context.Background(), &models.StatementRequest{WalletID: walletID, From: from, To: to, Format: models.StatementCSV}, sink)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWalletService_Statement_InvalidPeriod(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewWalletService(mockRepo)
+
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	err := service.Statement(context.Background(), &models.StatementRequest{WalletID: uuid.New(), From: from, To: from, Format: models.StatementCSV}, nil)
+
+	assert.ErrorIs(t, err, models.ErrInvalidPeriod)
+	mockRepo.AssertNotCalled(t, "StreamStatement", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWalletService_Transfer(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewWalletService(mockRepo)
+
+	fromID, toID, transferID := uuid.New(), uuid.New(), uuid.New()
+	req := &models.TransferRequest{
+		FromWalletID: fromID,
+		ToWalletID:   toID,
+		Amount:       300,
+	}
+
+	mockRepo.On("Transfer", mock.Anything, fromID, toID, int64(300)).Return(transferID, nil)
+
+	id, err := service.Transfer(context.Background(), req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, transferID, id)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWalletService_Transfer_SameWallet(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewWalletService(mockRepo)
+
+	walletID := uuid.New()
+	req := &models.TransferRequest{
+		FromWalletID: walletID,
+		ToWalletID:   walletID,
+		Amount:       300,
+	}
+
+	_, err := service.Transfer(context.Background(), req)
+
+	assert.ErrorIs(t, err, models.ErrSameWallet)
+	mockRepo.AssertNotCalled(t, "Transfer")
+}
+
+func TestWalletService_Freeze(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewWalletService(mockRepo)
+
+	walletID := uuid.New()
+	mockRepo.On("SetStatus", mock.Anything, walletID, models.StatusFrozen).Return(nil)
+
+	err := service.Freeze(context.Background(), walletID)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWalletService_History_DefaultLimit(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewWalletService(mockRepo)
+
+	walletID := uuid.New()
+	mockRepo.On("History", mock.Anything, walletID, DefaultHistoryLimit).Return([]models.Operation{}, nil)
+
+	operations, err := service.History(context.Background(), walletID, 0)
+
+	assert.NoError(t, err)
+	assert.Empty(t, operations)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWalletService_History_InvalidLimit(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewWalletService(mockRepo)
+
+	_, err := service.History(context.Background(), uuid.New(), MaxHistoryLimit+1)
+
+	assert.Equal(t, models.ErrInvalidLimit, err)
+	mockRepo.AssertNotCalled(t, "History")
+}
@@ -0,0 +1,144 @@
+// Package statement пишет выписки по кошелькам в CSV и JSON Lines.
+//
+// Writer реализует repository.StatementSink: строки пишутся по мере чтения
+// операций из хранилища, поэтому выписка за большой период не
+// накапливается в памяти.
+package statement
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+)
+
+// csvHeader — колонки CSV-выписки. Суммы и остатки записаны десятичными
+// строками в основных единицах валюты кошелька.
+var csvHeader = []string{"record", "time", "operation_id", "operation_type", "transfer_id", "amount", "balance", "currency"}
+
+// Writer пишет выписку за период req: строку OPENING, строку на каждую
+// операцию с остатком после неё и, в Close, строку CLOSING.
+type Writer struct {
+	req      *models.StatementRequest
+	encode   func(line *models.StatementLine) error
+	flush    func() error
+	currency models.Currency
+
+	balance    int64
+	operations int
+	started    bool
+}
+
+// NewWriter возвращает Writer, пишущий в out в формате req.Format.
+func NewWriter(out io.Writer, req *models.StatementRequest) (*Writer, error) {
+	w := &Writer{req: req}
+	switch req.Format {
+	case models.StatementCSV:
+		cw := csv.NewWriter(out)
+		w.encode = func(line *models.StatementLine) error {
+			if line.Record == models.StatementOpening {
+				if err := cw.Write(csvHeader); err != nil {
+					return err
+				}
+			}
+			return cw.Write(w.csvRecord(line))
+		}
+		w.flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case models.StatementJSONL:
+		enc := json.NewEncoder(out)
+		w.encode = func(line *models.StatementLine) error { return enc.Encode(line) }
+		w.flush = func() error { return nil }
+	default:
+		return nil, models.ErrInvalidFormat
+	}
+	return w, nil
+}
+
+// ContentType возвращает MIME-тип выписки в формате format.
+func ContentType(format models.StatementFormat) string {
+	if format == models.StatementJSONL {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// FileName возвращает имя файла выписки для Content-Disposition.
+func FileName(req *models.StatementRequest) string {
+	return fmt.Sprintf("statement-%s-%s-%s.%s", req.WalletID, req.From.UTC().Format("20060102"), req.To.UTC().Format("20060102"), req.Format)
+}
+
+// Started сообщает, начата ли запись: после этого ошибку уже нельзя
+// вернуть вместо выписки.
+func (w *Writer) Started() bool {
+	return w.started
+}
+
+func (w *Writer) Opening(wallet *models.Wallet, balance int64) error {
+	w.started = true
+	w.currency = wallet.Currency
+	w.balance = balance
+	return w.encode(&models.StatementLine{
+		Record:         models.StatementOpening,
+		Time:           w.req.From.UTC(),
+		WalletID:       &wallet.ID,
+		Currency:       wallet.Currency,
+		Balance:        balance,
+		BalanceDecimal: w.decimal(balance),
+	})
+}
+
+func (w *Writer) Operation(op *models.Operation) error {
+	w.balance += op.Amount
+	w.operations++
+	amount := op.Amount
+	return w.encode(&models.StatementLine{
+		Record:         models.StatementOperation,
+		Time:           op.CreatedAt.UTC(),
+		OperationID:    op.ID,
+		OperationType:  op.Type,
+		TransferID:     op.TransferID,
+		Amount:         &amount,
+		AmountDecimal:  w.decimal(amount),
+		Balance:        w.balance,
+		BalanceDecimal: w.decimal(w.balance),
+	})
+}
+
+// Close пишет строку CLOSING с остатком на конец периода и сбрасывает
+// буфер.
+func (w *Writer) Close() error {
+	operations := w.operations
+	err := w.encode(&models.StatementLine{
+		Record:         models.StatementClosing,
+		Time:           w.req.To.UTC(),
+		Balance:        w.balance,
+		BalanceDecimal: w.decimal(w.balance),
+		Operations:     &operations,
+	})
+	if err != nil {
+		return err
+	}
+	return w.flush()
+}
+
+func (w *Writer) decimal(amount int64) string {
+	return models.FormatAmount(amount, w.currency.Exponent())
+}
+
+func (w *Writer) csvRecord(line *models.StatementLine) []string {
+	record := []string{string(line.Record), line.Time.Format(time.RFC3339Nano), "", string(line.OperationType), "", line.AmountDecimal, line.BalanceDecimal, string(w.currency)}
+	if line.OperationID != 0 {
+		record[2] = strconv.FormatInt(line.OperationID, 10)
+	}
+	if line.TransferID != nil {
+		record[4] = line.TransferID.String()
+	}
+	return record
+}
@@ -0,0 +1,81 @@
+package statement
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DisasterWoman/wallet-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testFrom = time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	testTo   = time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// writeStatement пишет выписку с остатком 10.00 и двумя операциями.
+func writeStatement(t *testing.T, format models.StatementFormat) (string, uuid.UUID) {
+	t.Helper()
+	var buf bytes.Buffer
+	walletID, transferID := uuid.New(), uuid.MustParse("5f1c6f0e-9a43-4d7a-9d1e-3a0c9a8b7c6d")
+	req := &models.StatementRequest{WalletID: walletID, From: testFrom, To: testTo, Format: format}
+
+	w, err := NewWriter(&buf, req)
+	require.NoError(t, err)
+	require.NoError(t, w.Opening(&models.Wallet{ID: walletID, Currency: "USD"}, 1000))
+	require.NoError(t, w.Operation(&models.Operation{ID: 7, Type: models.Deposit, Amount: 250, CreatedAt: testFrom.Add(time.Hour)}))
+	require.NoError(t, w.Operation(&models.Operation{ID: 9, Type: models.TransferOut, Amount: -1200, TransferID: &transferID, CreatedAt: testFrom.Add(2 * time.Hour)}))
+	require.NoError(t, w.Close())
+	assert.True(t, w.Started())
+	return buf.String(), walletID
+}
+
+func TestWriter_CSV(t *testing.T) {
+	out, _ := writeStatement(t, models.StatementCSV)
+
+	assert.Equal(t, strings.Join([]string{
+		"record,time,operation_id,operation_type,transfer_id,amount,balance,currency",
+		"OPENING,2026-09-01T00:00:00Z,,,,,10.00,USD",
+		"OPERATION,2026-09-01T01:00:00Z,7,DEPOSIT,,2.50,12.50,USD",
+		"OPERATION,2026-09-01T02:00:00Z,9,TRANSFER_OUT,5f1c6f0e-9a43-4d7a-9d1e-3a0c9a8b7c6d,-12.00,0.50,USD",
+		"CLOSING,2026-10-01T00:00:00Z,,,,,0.50,USD",
+	}, "\n")+"\n", out)
+}
+
+func TestWriter_JSONL(t *testing.T) {
+	out, walletID := writeStatement(t, models.StatementJSONL)
+
+	var lines []models.StatementLine
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		var line models.StatementLine
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	require.Len(t, lines, 4)
+
+	assert.Equal(t, models.StatementOpening, lines[0].Record)
+	assert.Equal(t, &walletID, lines[0].WalletID)
+	assert.Equal(t, models.Currency("USD"), lines[0].Currency)
+	assert.Equal(t, int64(1000), lines[0].Balance)
+
+	assert.Equal(t, int64(250), *lines[1].Amount)
+	assert.Equal(t, "12.50", lines[1].BalanceDecimal)
+	assert.Equal(t, int64(50), lines[2].Balance)
+
+	assert.Equal(t, models.StatementClosing, lines[3].Record)
+	assert.True(t, testTo.Equal(lines[3].Time))
+	assert.Equal(t, int64(50), lines[3].Balance)
+	assert.Equal(t, 2, *lines[3].Operations)
+}
+
+func TestNewWriter_UnknownFormat(t *testing.T) {
+	_, err := NewWriter(&bytes.Buffer{}, &models.StatementRequest{Format: "pdf"})
+	assert.ErrorIs(t, err, models.ErrInvalidFormat)
+}
@@ -4,4 +4,4 @@ CREATE TABLE IF NOT EXISTS wallets (
 );
 
 INSERT INTO wallets (id, balance) VALUES ('123e4567-e89b-12d3-a456-426614174000', 1000)
-    ON CONFLICT (id) DO NOTHING;
\ No newline at end of file
+    ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS wallet_operations;
+
+ALTER TABLE wallets
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,20 @@
+ALTER TABLE wallets
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'FROZEN')),
+    ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE TABLE wallet_operations (
+    id BIGSERIAL PRIMARY KEY,
+    wallet_id UUID NOT NULL REFERENCES wallets (id) ON DELETE CASCADE,
+    operation_type TEXT NOT NULL,
+    amount BIGINT NOT NULL,
+    transfer_id UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX wallet_operations_wallet_id_idx ON wallet_operations (wallet_id, id);
+CREATE INDEX wallet_operations_transfer_id_idx ON wallet_operations (transfer_id) WHERE transfer_id IS NOT NULL;
+
+-- Текущие балансы переносим в журнал как входящие остатки,
+-- чтобы сумма операций кошелька совпадала с его балансом.
+INSERT INTO wallet_operations (wallet_id, operation_type, amount)
+SELECT id, 'OPENING', balance FROM wallets WHERE balance <> 0;
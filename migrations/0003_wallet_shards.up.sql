@@ -0,0 +1,12 @@
+ALTER TABLE wallets
+    ADD COLUMN shard_count INT NOT NULL DEFAULT 0 CHECK (shard_count >= 0);
+
+-- Баланс "горячего" кошелька может быть разнесён по нескольким строкам,
+-- чтобы пополнения не конкурировали за одну блокировку. Полный баланс
+-- кошелька — wallets.balance плюс сумма его шардов.
+CREATE TABLE wallet_shards (
+    wallet_id UUID NOT NULL REFERENCES wallets (id) ON DELETE CASCADE,
+    shard_no INT NOT NULL,
+    balance BIGINT NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    PRIMARY KEY (wallet_id, shard_no)
+);
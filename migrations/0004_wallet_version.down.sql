@@ -0,0 +1,3 @@
+ALTER TABLE wallet_shards DROP COLUMN IF EXISTS version;
+
+ALTER TABLE wallets DROP COLUMN IF EXISTS version;
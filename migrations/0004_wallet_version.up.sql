@@ -0,0 +1,9 @@
+-- Версия кошелька растёт при каждом изменении и отдаётся клиентам как ETag.
+-- У шардированного кошелька изменения по шардам учитываются в версии шарда,
+-- чтобы быстрый путь не блокировал строку кошелька. Полная версия —
+-- wallets.version плюс сумма версий его шардов.
+ALTER TABLE wallets
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+ALTER TABLE wallet_shards
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS wallet_shards_notify_changed ON wallet_shards;
+DROP TRIGGER IF EXISTS wallets_notify_changed ON wallets;
+DROP FUNCTION IF EXISTS notify_wallet_shard_changed();
+DROP FUNCTION IF EXISTS notify_wallet_changed();
@@ -0,0 +1,32 @@
+-- Уведомляет слушателей канала wallet_changed об изменении кошелька, чтобы
+-- инстансы сервиса сбросили его из кэша. Уведомления с одинаковым id в
+-- одной транзакции PostgreSQL доставляет один раз, после COMMIT.
+CREATE FUNCTION notify_wallet_changed() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('wallet_changed', OLD.id::text);
+    ELSE
+        PERFORM pg_notify('wallet_changed', NEW.id::text);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION notify_wallet_shard_changed() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('wallet_changed', OLD.wallet_id::text);
+    ELSE
+        PERFORM pg_notify('wallet_changed', NEW.wallet_id::text);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER wallets_notify_changed
+    AFTER UPDATE OR DELETE ON wallets
+    FOR EACH ROW EXECUTE FUNCTION notify_wallet_changed();
+
+CREATE TRIGGER wallet_shards_notify_changed
+    AFTER INSERT OR UPDATE OR DELETE ON wallet_shards
+    FOR EACH ROW EXECUTE FUNCTION notify_wallet_shard_changed();
@@ -0,0 +1,31 @@
+-- Вёдра распределённого ограничения частоты запросов. Потеря содержимого
+-- при сбое лишь сбрасывает лимиты, поэтому таблица не пишется в WAL.
+CREATE UNLOGGED TABLE rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- Пополняет ведро p_key со скоростью p_rate токенов в секунду (не больше
+-- p_burst) и забирает один токен. Возвращает 0, если токен взят, иначе число
+-- секунд до появления токена.
+CREATE FUNCTION rate_limit_take(p_key TEXT, p_burst DOUBLE PRECISION, p_rate DOUBLE PRECISION)
+RETURNS DOUBLE PRECISION AS $$
+DECLARE
+    available DOUBLE PRECISION;
+BEGIN
+    INSERT INTO rate_limit_buckets AS b (key, tokens, updated_at)
+    VALUES (p_key, p_burst, clock_timestamp())
+    ON CONFLICT (key) DO UPDATE
+        SET tokens = LEAST(p_burst, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at) * p_rate),
+            updated_at = clock_timestamp()
+    RETURNING tokens INTO available;
+
+    IF available < 1 THEN
+        RETURN (1 - available) / p_rate;
+    END IF;
+
+    UPDATE rate_limit_buckets SET tokens = tokens - 1 WHERE key = p_key;
+    RETURN 0;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,6 @@
+ALTER TABLE wallet_shards DROP CONSTRAINT IF EXISTS wallet_shards_balance_limit;
+
+ALTER TABLE wallets
+    DROP CONSTRAINT IF EXISTS wallets_balance_limit,
+    DROP CONSTRAINT IF EXISTS wallets_max_balance_range,
+    DROP COLUMN IF EXISTS max_balance;
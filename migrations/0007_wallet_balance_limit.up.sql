@@ -0,0 +1,12 @@
+-- Потолок баланса кошелька. По умолчанию и не выше 10^18: вместе с
+-- наибольшей суммой операции (10^15) это оставляет запас до предела BIGINT,
+-- поэтому balance + amount в запросах не переполняется. Полный баланс
+-- шардированного кошелька проверяет приложение, ограничения ниже — страховка
+-- для каждой строки.
+ALTER TABLE wallets
+    ADD COLUMN max_balance BIGINT NOT NULL DEFAULT 1000000000000000000,
+    ADD CONSTRAINT wallets_max_balance_range CHECK (max_balance BETWEEN 0 AND 1000000000000000000),
+    ADD CONSTRAINT wallets_balance_limit CHECK (balance <= max_balance);
+
+ALTER TABLE wallet_shards
+    ADD CONSTRAINT wallet_shards_balance_limit CHECK (balance <= 1000000000000000000);
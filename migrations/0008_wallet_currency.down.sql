@@ -0,0 +1,3 @@
+ALTER TABLE wallets
+    DROP CONSTRAINT IF EXISTS wallets_currency_format,
+    DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,6 @@
+-- Валюта кошелька. Балансы и суммы по-прежнему хранятся в минимальных
+-- единицах; валюта нужна, чтобы переводить суммы в API из основных единиц
+-- и обратно. Существующие кошельки получают RUB.
+ALTER TABLE wallets
+    ADD COLUMN currency TEXT NOT NULL DEFAULT 'RUB',
+    ADD CONSTRAINT wallets_currency_format CHECK (currency ~ '^[A-Z]{3}$');
@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS wallets_owner_required ON wallets;
+DROP FUNCTION IF EXISTS wallets_owner_required();
+
+DROP INDEX IF EXISTS wallets_owner_id_idx;
+
+ALTER TABLE wallets DROP COLUMN IF EXISTS owner_id;
+
+DROP TABLE IF EXISTS owners;
@@ -0,0 +1,27 @@
+-- Владельцы кошельков. Новые кошельки создаются только с владельцем;
+-- owner_id допускает NULL лишь для кошельков, созданных до этой миграции.
+CREATE TABLE owners (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE wallets ADD COLUMN owner_id UUID REFERENCES owners (id);
+
+CREATE INDEX wallets_owner_id_idx ON wallets (owner_id, created_at) WHERE owner_id IS NOT NULL;
+
+-- Владельца требует база, а не только сервис. CHECK ... NOT VALID здесь не
+-- подходит: его проверяет и UPDATE, то есть любое изменение баланса
+-- старого кошелька без владельца. Триггер отклоняет только новый кошелёк
+-- без владельца и снятие владельца с существующего.
+CREATE FUNCTION wallets_owner_required() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'wallet % must have an owner', NEW.id
+        USING ERRCODE = 'check_violation', CONSTRAINT = 'wallets_owner_required';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER wallets_owner_required
+    BEFORE INSERT OR UPDATE OF owner_id ON wallets
+    FOR EACH ROW WHEN (NEW.owner_id IS NULL)
+    EXECUTE FUNCTION wallets_owner_required();
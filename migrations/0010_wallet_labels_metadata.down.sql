@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS wallets_created_at_idx;
+DROP INDEX IF EXISTS wallets_labels_idx;
+
+ALTER TABLE wallets
+    DROP CONSTRAINT IF EXISTS wallets_metadata_object,
+    DROP CONSTRAINT IF EXISTS wallets_labels_object,
+    DROP COLUMN IF EXISTS metadata,
+    DROP COLUMN IF EXISTS labels;
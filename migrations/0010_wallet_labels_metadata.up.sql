@@ -0,0 +1,11 @@
+-- Метки и метаданные кошелька. Метки — плоский объект строк для поиска
+-- (labels @> '{"team": "payments"}' использует GIN-индекс), метаданные —
+-- произвольный JSON-объект, например ссылки на счета во внешних системах.
+ALTER TABLE wallets
+    ADD COLUMN labels JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}',
+    ADD CONSTRAINT wallets_labels_object CHECK (jsonb_typeof(labels) = 'object'),
+    ADD CONSTRAINT wallets_metadata_object CHECK (jsonb_typeof(metadata) = 'object');
+
+CREATE INDEX wallets_labels_idx ON wallets USING GIN (labels jsonb_path_ops);
+CREATE INDEX wallets_created_at_idx ON wallets (created_at, id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS wallet_operations_created_at_idx;
+DROP INDEX IF EXISTS wallet_operations_wallet_created_idx;
+DROP TABLE IF EXISTS wallet_balance_checkpoints;
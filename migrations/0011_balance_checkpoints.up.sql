@@ -0,0 +1,17 @@
+-- Контрольные точки баланса: остаток кошелька на момент as_of по журналу
+-- wallet_operations. Баланс на произвольный момент считается от ближайшей
+-- предыдущей точки, а не суммированием всего журнала кошелька.
+CREATE TABLE wallet_balance_checkpoints (
+    wallet_id UUID NOT NULL REFERENCES wallets (id) ON DELETE CASCADE,
+    as_of TIMESTAMPTZ NOT NULL,
+    balance BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (wallet_id, as_of)
+);
+
+CREATE INDEX wallet_balance_checkpoints_as_of_idx ON wallet_balance_checkpoints (as_of);
+
+-- Сумма операций кошелька между точкой и моментом запроса и операции всех
+-- кошельков за период между точками.
+CREATE INDEX wallet_operations_wallet_created_idx ON wallet_operations (wallet_id, created_at) INCLUDE (amount);
+CREATE INDEX wallet_operations_created_at_idx ON wallet_operations (created_at);
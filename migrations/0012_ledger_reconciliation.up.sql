@@ -0,0 +1,25 @@
+-- Отчёты сверки журнала: прогон и найденные в нём расхождения. Расхождение
+-- относится к кошельку (остаток не равен сумме журнала или вышел за
+-- пределы 0..max_balance) или к переводу (ноги не в сумме к нулю или их не
+-- две); expected и actual — ожидаемое и найденное значение.
+CREATE TABLE reconciliation_runs (
+    id BIGSERIAL PRIMARY KEY,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL,
+    wallets BIGINT NOT NULL,
+    transfers BIGINT NOT NULL,
+    discrepancies BIGINT NOT NULL
+);
+
+CREATE TABLE reconciliation_discrepancies (
+    id BIGSERIAL PRIMARY KEY,
+    run_id BIGINT NOT NULL REFERENCES reconciliation_runs (id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    wallet_id UUID,
+    transfer_id UUID,
+    expected BIGINT NOT NULL,
+    actual BIGINT NOT NULL,
+    CHECK ((wallet_id IS NULL) <> (transfer_id IS NULL))
+);
+
+CREATE INDEX reconciliation_discrepancies_run_idx ON reconciliation_discrepancies (run_id, id);
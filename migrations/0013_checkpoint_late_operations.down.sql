@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS wallet_operations_correct_checkpoints ON wallet_operations;
+DROP FUNCTION IF EXISTS wallet_operations_correct_checkpoints();
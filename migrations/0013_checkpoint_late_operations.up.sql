@@ -0,0 +1,24 @@
+-- Операция получает created_at = now(), то есть время начала своей
+-- транзакции, и может зафиксироваться уже после контрольной точки с более
+-- поздним as_of: импорт, walletctl или вставка задним числом. Такую
+-- операцию триггер добавляет ко всем точкам кошелька не раньше её
+-- created_at, иначе баланс на момент после точки её бы не учитывал.
+CREATE FUNCTION wallet_operations_correct_checkpoints() RETURNS trigger AS $$
+BEGIN
+    UPDATE wallet_balance_checkpoints c
+    SET balance = c.balance + late.amount
+    FROM (
+        SELECT c.wallet_id, c.as_of, SUM(i.amount) AS amount
+        FROM inserted i
+        JOIN wallet_balance_checkpoints c ON c.wallet_id = i.wallet_id AND c.as_of >= i.created_at
+        GROUP BY c.wallet_id, c.as_of
+    ) late
+    WHERE c.wallet_id = late.wallet_id AND c.as_of = late.as_of;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER wallet_operations_correct_checkpoints
+    AFTER INSERT ON wallet_operations
+    REFERENCING NEW TABLE AS inserted
+    FOR EACH STATEMENT EXECUTE FUNCTION wallet_operations_correct_checkpoints();
@@ -0,0 +1,9 @@
+// Package migrations содержит SQL-миграции схемы, встроенные в бинарник.
+//
+// Файлы именуются как <версия>_<название>.up.sql и <версия>_<название>.down.sql.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS